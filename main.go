@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -10,9 +12,14 @@ import (
 	"tulltaxan/pkg/handlers"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func main() {
+	rebuild := flag.Bool("rebuild", false, "rebuild the database from the newest tot files and later dif files and exit")
+	rebuildFromCache := flag.Bool("rebuild-from-cache", false, "rebuild the database from the files in FILEDIST_CACHE_DIR and exit")
+	flag.Parse()
+
 	// Fetch database connection string from environment variables
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
@@ -27,25 +34,101 @@ func main() {
 
 	// Connect to the database
 	ctx := context.Background()
-	conn, err := pgx.Connect(ctx, dbURL)
+	connConfig, err := pgx.ParseConfig(dbURL)
+	if err != nil {
+		log.Fatalf("Invalid DATABASE_URL: %v", err)
+	}
+	// Extensions are installed in their own schema, see ddl_views.sql
+	if _, ok := connConfig.RuntimeParams["search_path"]; !ok {
+		connConfig.RuntimeParams["search_path"] = `"$user", public, extensions`
+	}
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
 	if err != nil {
 		log.Fatalf("Failed to connect to the database: %v", err)
 	}
 	defer conn.Close(ctx)
 
+	// Imports hold a transaction per file, so they get a connection of their own instead of the one serving requests
+	importConn, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		log.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer importConn.Close(ctx)
+
+	// Requests are served concurrently from a pool of connections configured like the others
+	poolConfig, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		log.Fatalf("Invalid DATABASE_URL: %v", err)
+	}
+	poolConfig.ConnConfig = connConfig
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		log.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer pool.Close()
+
+	// Cache of imported files, required to rebuild the database without downloading the files again
+	var cache *filedist.Cache
+	if cacheDir := os.Getenv("FILEDIST_CACHE_DIR"); cacheDir != "" {
+		cache, err = filedist.NewCache(cacheDir)
+		if err != nil {
+			log.Fatal("NewCache: ", err)
+		}
+	}
+
 	// Create tables
 	if err := insertSQLFiles([]string{ddlFile}, ctx, conn); err != nil {
 		log.Fatal("insertSQLFiles: ", err)
 	}
 
-	// Insert filedist content
-	filedist.StartDbMaintenanceScheduler(conn)
-
-	// Construct materialized views
+	// Construct materialized views. They exist before the first import, which refreshes them.
 	if err := insertSQLFiles([]string{ddlViewsFile}, ctx, conn); err != nil {
 		log.Fatal("insertSQLFiles: ", err)
 	}
 
+	// Insert filedist content
+	src, err := newFileDistSource()
+	if err != nil {
+		log.Fatal("newFileDistSource: ", err)
+	}
+	// Local zip archives stay open while the application runs
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
+	}
+	cfg := filedist.Config{Source: src, KeyFingerprint: os.Getenv("FILEDIST_KEY_FINGERPRINT"), Cache: cache}
+	if keyFile := os.Getenv("FILEDIST_PUBKEY_FILE"); keyFile != "" {
+		pubKey, err := os.ReadFile(keyFile)
+		if err != nil {
+			log.Fatalf("unable to read public key file %s: %v", keyFile, err)
+		}
+		cfg.PublicKey = string(pubKey)
+	}
+
+	// Rebuilds create a new schema from the ddl files, import into it and replace the live schema with it
+	if *rebuild || *rebuildFromCache {
+		ddl, err := readSQLFiles([]string{ddlFile, ddlViewsFile})
+		if err != nil {
+			log.Fatal("readSQLFiles: ", err)
+		}
+
+		if *rebuildFromCache {
+			if cache == nil {
+				log.Fatal("FILEDIST_CACHE_DIR must be set to rebuild from cache")
+			}
+			err = filedist.RebuildFromCache(ctx, conn, cache, ddl)
+		} else {
+			err = filedist.Rebuild(ctx, conn, cfg, ddl)
+		}
+		if err != nil {
+			log.Fatal("rebuild: ", err)
+		}
+
+		log.Println("Database rebuilt")
+		return
+	}
+
+	filedist.StartDbMaintenanceScheduler(importConn, cfg)
+
 	// Serve UI
 	staticDir := "./static"
 	fs := http.FileServer(http.Dir(staticDir))
@@ -53,7 +136,22 @@ func main() {
 	port := "8080"
 
 	http.Handle("/search", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		handlers.SearchHandler(w, r, conn)
+		handlers.SearchHandler(w, r, pool)
+	}))
+	http.Handle("/quota", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.QuotaHandler(w, r, pool)
+	}))
+	http.Handle("/imports", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.ImportsHandler(w, r, pool)
+	}))
+	http.Handle("/changes", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.ChangesHandler(w, r, pool)
+	}))
+	http.Handle("/calculate", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.CalculateHandler(w, r, pool)
+	}))
+	http.Handle("/preferences", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.PreferencesHandler(w, r, pool)
 	}))
 	http.HandleFunc("/ip", handlers.IpHandler)
 
@@ -65,16 +163,50 @@ func main() {
 }
 
 func insertSQLFiles(paths []string, ctx context.Context, conn *pgx.Conn) error {
+	queries, err := readSQLFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	for i, query := range queries {
+		_, err = conn.Exec(ctx, query)
+		if err != nil {
+			return fmt.Errorf("unable to execute query for file %s: %w", paths[i], err)
+		}
+	}
+
+	return nil
+}
+
+// readSQLFiles returns the content of the sql files at paths.
+func readSQLFiles(paths []string) ([]string, error) {
+	queries := make([]string, 0, len(paths))
 	for _, path := range paths {
 		query, err := os.ReadFile(path)
 		if err != nil {
-			return fmt.Errorf("unable to read ddl file %s: %w", path, err)
+			return nil, fmt.Errorf("unable to read ddl file %s: %w", path, err)
 		}
-		_, err = conn.Exec(ctx, string(query))
-		if err != nil {
-			return fmt.Errorf("unable to execute query for file %s: %w", path, err)
+		queries = append(queries, string(query))
+	}
+
+	return queries, nil
+}
+
+// newFileDistSource returns the filedist source configured by environment variables.
+// FILEDIST_DIR selects a local directory or zip archive, FILEDIST_URL and FILEDIST_PUBKEY_URL a mirror of the
+// distribution server. The official Tullverket distribution is used by default.
+func newFileDistSource() (filedist.Source, error) {
+	if dir := os.Getenv("FILEDIST_DIR"); dir != "" {
+		return filedist.OpenLocalSource(dir)
+	}
+
+	if distURL := os.Getenv("FILEDIST_URL"); distURL != "" {
+		pubKeyURL := os.Getenv("FILEDIST_PUBKEY_URL")
+		if pubKeyURL == "" {
+			pubKeyURL = filedist.DefaultPubKeyURL
 		}
+		return filedist.NewHTTPSource(distURL, pubKeyURL), nil
 	}
 
-	return nil
+	return filedist.NewTullverketSource(), nil
 }
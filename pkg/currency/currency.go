@@ -0,0 +1,131 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const euro = "EUR"
+
+// ErrNoRate is returned when there is no exchange rate between two monetary units on the date of a converter.
+var ErrNoRate = errors.New("no exchange rate")
+
+// Rate is the exchange rate of a period: Unit units of From are Rate units of To. Unit is the calculation unit of
+// the rate in the distribution, a zero Unit is one unit.
+type Rate struct {
+	From string
+	To   string
+	Rate float64
+	Unit float64
+}
+
+// Converter converts amounts between monetary units, such as EUR and SEK, at the rates of a date.
+type Converter struct {
+	Date  time.Time
+	rates map[string]map[string]float64 // units of to per unit of from, by from and to
+}
+
+// New returns a converter for the given rates. Every rate can also be used in reverse.
+// If rates contains more than one rate for a pair of units, the first one is used.
+func New(date time.Time, rates []Rate) *Converter {
+	c := &Converter{Date: date, rates: map[string]map[string]float64{}}
+
+	add := func(from, to string, rate float64) {
+		if c.rates[from] == nil {
+			c.rates[from] = map[string]float64{}
+		}
+		if _, ok := c.rates[from][to]; !ok {
+			c.rates[from][to] = rate
+		}
+	}
+	for _, rate := range rates {
+		if rate.Rate <= 0 || rate.Unit < 0 || rate.From == rate.To {
+			continue
+		}
+		perUnit := rate.Rate
+		if rate.Unit > 0 {
+			perUnit /= rate.Unit
+		}
+		add(rate.From, rate.To, perUnit)
+		add(rate.To, rate.From, 1/perUnit)
+	}
+
+	return c
+}
+
+// DB is the part of *pgxpool.Pool, *pgx.Conn and pgx.Tx used to load exchange rates.
+type DB interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Load returns a converter with the exchange rates of the monetary exchange periods valid on date.
+// Quoted periods, the rates published by the EU, take precedence over unquoted ones.
+func Load(ctx context.Context, conn DB, date time.Time) (*Converter, error) {
+	rows, err := conn.Query(ctx, `
+	SELECT mep.monetary_unit_code,
+		mer.monetary_unit_code,
+		mer.monetary_conversion_rate,
+		COALESCE(mer.calculation_unit, 1)::FLOAT
+	FROM monetary_exchange_period mep
+		JOIN monetary_exchange_rate mer ON mep.sid = mer.parent_sid
+	WHERE mep.date_start <= $1::DATE
+		AND (
+			mep.date_end IS NULL
+			OR mep.date_end >= $1::DATE
+		)
+		AND mep.monetary_unit_code IS NOT NULL
+		AND mer.monetary_conversion_rate > 0
+	ORDER BY mep.is_quoted DESC NULLS LAST,
+		mep.date_start DESC`, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exchange rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := []Rate{}
+	for rows.Next() {
+		var rate Rate
+		if err := rows.Scan(&rate.From, &rate.To, &rate.Rate, &rate.Unit); err != nil {
+			return nil, fmt.Errorf("failed to scan exchange rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates: %w", err)
+	}
+
+	return New(date, rates), nil
+}
+
+// Rate returns how many units of to one unit of from is worth. Units without a direct rate are converted through
+// EUR, which the rates of the distribution are published against.
+func (c *Converter) Rate(from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if rate, ok := c.rates[from][to]; ok {
+		return rate, nil
+	}
+
+	if rate, ok := c.rates[from][euro]; ok {
+		if onward, ok := c.rates[euro][to]; ok {
+			return rate * onward, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w from %s to %s on %s", ErrNoRate, from, to, c.Date.Format(time.DateOnly))
+}
+
+// Convert converts amount from one monetary unit to another.
+func (c *Converter) Convert(amount float64, from, to string) (float64, error) {
+	rate, err := c.Rate(from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	return amount * rate, nil
+}
@@ -0,0 +1,78 @@
+package currency
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRate(t *testing.T) {
+	date := time.Date(2024, 10, 12, 0, 0, 0, 0, time.UTC)
+	converter := New(date, []Rate{
+		{From: "EUR", To: "SEK", Rate: 11.5},
+		{From: "EUR", To: "USD", Rate: 1.25},
+		{From: "EUR", To: "SEK", Rate: 99}, // a later period of the same pair is ignored
+		{From: "GBP", To: "NOK", Rate: 13},
+		{From: "NOK", To: "DKK", Rate: 0.7},
+		{From: "EUR", To: "XXX", Rate: 0},                // rates that are not positive are ignored
+		{From: "EUR", To: "JPY", Rate: 16200, Unit: 100}, // 100 EUR are 16200 JPY
+		{From: "EUR", To: "CHF", Rate: 0.95, Unit: 1},
+	})
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     float64
+		wantErr  bool
+	}{
+		{"same unit", "SEK", "SEK", 1, false},
+		{"direct", "EUR", "SEK", 11.5, false},
+		{"first rate wins", "EUR", "SEK", 11.5, false},
+		{"inverse", "SEK", "EUR", 1 / 11.5, false},
+		{"through EUR", "USD", "SEK", 11.5 / 1.25, false},
+		{"through EUR in reverse", "SEK", "USD", 1.25 / 11.5, false},
+		{"direct without EUR", "GBP", "NOK", 13, false},
+		{"only through EUR", "GBP", "DKK", 0, true},
+		{"no rate to EUR", "GBP", "SEK", 0, true},
+		{"calculation unit", "EUR", "JPY", 162, false},
+		{"calculation unit in reverse", "JPY", "EUR", 1 / 162.0, false},
+		{"calculation unit through EUR", "JPY", "SEK", 11.5 / 162, false},
+		{"calculation unit of one", "EUR", "CHF", 0.95, false},
+		{"unknown unit", "EUR", "CAD", 0, true},
+		{"rate that is not positive", "EUR", "XXX", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := converter.Rate(tt.from, tt.to)
+			if tt.wantErr {
+				if !errors.Is(err, ErrNoRate) {
+					t.Fatalf("Rate(%s, %s) = %v, %v, want %v", tt.from, tt.to, got, err, ErrNoRate)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Rate(%s, %s): %v", tt.from, tt.to, err)
+			}
+			if math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Rate(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	converter := New(time.Now(), []Rate{{From: "EUR", To: "SEK", Rate: 11.5}})
+
+	got, err := converter.Convert(176.8, "EUR", "SEK")
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	if math.Abs(got-2033.2) > 1e-9 {
+		t.Errorf("Convert = %v, want 2033.2", got)
+	}
+
+	if _, err := converter.Convert(1, "EUR", "USD"); !errors.Is(err, ErrNoRate) {
+		t.Errorf("Convert without rate = %v, want %v", err, ErrNoRate)
+	}
+}
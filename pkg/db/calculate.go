@@ -0,0 +1,314 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+	"tulltaxan/pkg/duty"
+)
+
+// Categories of the measures taken into account by a landed cost calculation.
+const (
+	CategoryThirdCountry = "third_country"
+	CategorySuspension   = "suspension"
+	CategoryPreference   = "preference"
+	CategoryTradeDefence = "trade_defence" // anti-dumping and countervailing duties
+	CategoryAdditional   = "additional"
+	CategoryVAT          = "vat"
+)
+
+// measureCategories are the categories of the measure types that make up the duty and tax of imported goods.
+var measureCategories = map[string]string{
+	"103": CategoryThirdCountry, // third country duty
+	"105": CategoryThirdCountry, // non preferential duty under end-use
+	"112": CategorySuspension,   // autonomous tariff suspension
+	"115": CategorySuspension,   // autonomous suspension under end-use
+	"117": CategorySuspension,   // suspension - goods for certain categories of ships, boats and other vessels
+	"119": CategorySuspension,   // airworthiness tariff suspension
+	"142": CategoryPreference,   // tariff preference
+	"143": CategoryPreference,   // preferential tariff quota, only while its balance lasts
+	"145": CategoryPreference,   // preferential suspension
+	"146": CategoryPreference,   // preferential tariff quota under end-use, only while its balance lasts
+	"551": CategoryTradeDefence, // provisional anti-dumping duty
+	"552": CategoryTradeDefence, // definitive anti-dumping duty
+	"553": CategoryTradeDefence, // provisional countervailing duty
+	"554": CategoryTradeDefence, // definitive countervailing duty
+	"555": CategoryTradeDefence, // anti-dumping/countervailing duty - pending collection
+	"651": CategoryAdditional,   // additional duty on sugar
+	"652": CategoryAdditional,   // additional duty on flour
+	"672": CategoryAdditional,   // additional duty based on cif price
+	"695": CategoryAdditional,   // additional duties
+	"696": CategoryAdditional,   // additional duties (safeguard)
+	"305": CategoryVAT,          // value added tax
+}
+
+// supplementaryUnitMeasureType is the measure type giving the supplementary unit of a commodity code.
+const supplementaryUnitMeasureType = "109"
+
+// DefaultVATRate is the Swedish VAT rate in percent, used when no VAT measure is found for a commodity code.
+const DefaultVATRate = 25.0
+
+// CalculationRequest are the goods and the declaration a landed cost is calculated for.
+type CalculationRequest struct {
+	Code                  string // 10 digit commodity code
+	Origin                string // country of origin, e.g. CN
+	CustomsValue          float64
+	Currency              string
+	NetMass               *float64 // kg
+	SupplementaryQuantity *float64 // in the supplementary unit of the commodity code
+	AdditionalCode        string   // e.g. C999, selects among measures with additional codes
+	Certificates          []string // presented certificates, e.g. N954, used to evaluate measure conditions
+	Date                  time.Time
+}
+
+// Calculation is the duty and tax payable on imported goods and the measures they were calculated from.
+// Amounts are in the currency of the request, rounded to two decimals.
+type Calculation struct {
+	Code         string              `json:"code"`
+	Origin       string              `json:"origin"`
+	Date         time.Time           `json:"date"`
+	CustomsValue float64             `json:"customs_value"`
+	Currency     string              `json:"currency"`
+	Measures     []CalculatedMeasure `json:"measures"`
+	Skipped      []SkippedMeasure    `json:"skipped"`
+	Notes        []string            `json:"notes"`
+	Duty         float64             `json:"duty"`     // customs, trade defence and additional duties
+	VATRate      float64             `json:"vat_rate"` // percent
+	VATBase      float64             `json:"vat_base"` // customs value and duty
+	VAT          float64             `json:"vat"`
+	Total        float64             `json:"total"` // duty and VAT
+}
+
+// CalculatedMeasure is a measure whose duty was calculated for the goods. Applied is false for a customs duty
+// that was calculated but not chosen, since a lower customs duty applies.
+type CalculatedMeasure struct {
+	MeasureDuty
+	Category string      `json:"category"`
+	Applied  bool        `json:"applied"`
+	Amount   float64     `json:"amount"`
+	Lines    []duty.Line `json:"lines"`
+}
+
+// SkippedMeasure is a measure for the goods that was left out of the calculation.
+type SkippedMeasure struct {
+	MeasureDuty
+	Reason string `json:"reason"`
+}
+
+// CalculateLandedCost calculates the duty and VAT payable when goods are imported into Sweden on the date of req.
+//
+// The customs duty is the lowest of the third country duty, a tariff suspension and a tariff preference for the
+// origin of the goods. A preference requires a proof of origin, which is noted. A preferential tariff quota only
+// applies while its balance lasts, so exhausted quotas are left out and the balance of an applied quota is noted.
+// Anti-dumping, countervailing and additional duties are added to the customs duty. Measures with additional codes
+// only apply if the code of req matches, and measures whose duty depends on conditions are evaluated with the
+// certificates of req. Measures whose duty cannot be calculated for the goods, such as agricultural components or
+// duties in a unit no quantity is given for, are left out with the error as reason. VAT is charged on the customs
+// value and the duty, at the rate of the VAT measure or DefaultVATRate. Amounts in other currencies than
+// req.Currency are converted with converter, such as a currency.Converter for the date of req.
+func CalculateLandedCost(ctx context.Context, conn DB, req CalculationRequest, converter duty.Converter) (*Calculation, error) {
+	formatter, err := GetDutyFormatter(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("GetDutyFormatter: %w", err)
+	}
+	formatter.Converter, formatter.Currency = converter, req.Currency
+
+	measures, err := GetMeasureDuties(ctx, conn, req.Code, req.Origin, Import, req.Date, formatter)
+	if err != nil {
+		return nil, fmt.Errorf("GetMeasureDuties: %w", err)
+	}
+
+	quotaStatus := func(orderNumber string) (*QuotaStatus, error) {
+		return GetQuotaStatus(ctx, conn, orderNumber, req.Date)
+	}
+
+	return calculateLandedCost(req, measures, quotaStatus, converter)
+}
+
+// calculateLandedCost calculates the landed cost of CalculateLandedCost from the measures found for the goods.
+// The status of a tariff quota on the date of req is looked up with quotaStatus, which returns nil if the quota
+// has no definition in force.
+func calculateLandedCost(req CalculationRequest, measures []MeasureDuty, quotaStatus func(orderNumber string) (*QuotaStatus, error),
+	converter duty.Converter) (*Calculation, error) {
+	goods := duty.Goods{CustomsValue: req.CustomsValue, Currency: req.Currency, Quantities: duty.Quantities{}}
+	if req.NetMass != nil {
+		goods.Quantities["KGM"] = *req.NetMass
+	}
+
+	certificates := make(map[string]bool, len(req.Certificates))
+	for _, certificate := range req.Certificates {
+		certificates[certificate] = true
+	}
+
+	calculation := &Calculation{
+		Code:         req.Code,
+		Origin:       req.Origin,
+		Date:         req.Date,
+		CustomsValue: req.CustomsValue,
+		Currency:     req.Currency,
+		Measures:     []CalculatedMeasure{},
+		Skipped:      []SkippedMeasure{},
+		Notes:        []string{},
+		VATRate:      DefaultVATRate,
+	}
+
+	// The supplementary quantity is declared in the unit of the supplementary unit measure
+	for _, measure := range measures {
+		if measure.MeasureType != supplementaryUnitMeasureType || len(measure.Components) == 0 {
+			continue
+		}
+		unit := measure.Components[0]
+		if req.SupplementaryQuantity != nil {
+			goods.Quantities[unit.MeasurementUnitCode+unit.MeasurementUnitQualifierCode] = *req.SupplementaryQuantity
+		}
+	}
+
+	var customsDuties []int          // indexes of calculated customs duties, of which the lowest applies
+	quotas := map[int]*QuotaStatus{} // by measure SID
+	vatFound := false
+	for _, measure := range measures {
+		category, ok := measureCategories[measure.MeasureType]
+		if !ok {
+			continue
+		}
+
+		if measure.AdditionalCode != "" && measure.AdditionalCode != req.AdditionalCode {
+			calculation.Skipped = append(calculation.Skipped, SkippedMeasure{measure, "applies to additional code " + measure.AdditionalCode})
+			continue
+		}
+
+		components, reason := measureComponents(measure, certificates)
+		if reason != "" {
+			calculation.Skipped = append(calculation.Skipped, SkippedMeasure{measure, reason})
+			continue
+		}
+
+		if measure.QuotaOrderNumber != "" {
+			quota, err := quotaStatus(measure.QuotaOrderNumber)
+			if err != nil {
+				return nil, fmt.Errorf("GetQuotaStatus: %w", err)
+			}
+			if quota != nil && quota.Status == QuotaExhausted {
+				calculation.Skipped = append(calculation.Skipped, SkippedMeasure{measure, "tariff quota " + quota.OrderNumber + " is exhausted"})
+				continue
+			}
+			quotas[measure.SID] = quota
+		}
+
+		if category == CategoryVAT {
+			// VAT is a percentage of the customs value and the duty, calculated once all duties are known
+			for _, component := range components {
+				if component.AdValorem() {
+					calculation.VATRate = component.Amount
+					vatFound = true
+				}
+			}
+			continue
+		}
+
+		result, err := duty.Calculate(components, goods, converter)
+		if err != nil {
+			calculation.Skipped = append(calculation.Skipped, SkippedMeasure{measure, err.Error()})
+			continue
+		}
+
+		calculated := CalculatedMeasure{MeasureDuty: measure, Category: category, Applied: true, Amount: result.Duty, Lines: result.Lines}
+		switch category {
+		case CategoryThirdCountry, CategorySuspension, CategoryPreference:
+			calculated.Applied = false
+			customsDuties = append(customsDuties, len(calculation.Measures))
+		default:
+			calculation.Duty += result.Duty
+		}
+		calculation.Measures = append(calculation.Measures, calculated)
+	}
+
+	if len(customsDuties) > 0 {
+		lowest := customsDuties[0]
+		for _, i := range customsDuties[1:] {
+			if calculation.Measures[i].Amount < calculation.Measures[lowest].Amount {
+				lowest = i
+			}
+		}
+		calculation.Measures[lowest].Applied = true
+		calculation.Duty += calculation.Measures[lowest].Amount
+
+		switch calculation.Measures[lowest].Category {
+		case CategoryPreference:
+			calculation.Notes = append(calculation.Notes, "The preferential duty requires a proof of origin for "+req.Origin)
+		case CategorySuspension:
+			calculation.Notes = append(calculation.Notes, "The tariff suspension may require end-use authorisation or certificates")
+		}
+		if measure := calculation.Measures[lowest]; measure.QuotaOrderNumber != "" {
+			calculation.Notes = append(calculation.Notes, quotaNote(measure.QuotaOrderNumber, quotas[measure.SID]))
+		}
+	}
+	if !vatFound {
+		calculation.Notes = append(calculation.Notes, fmt.Sprintf("No VAT measure found, VAT is calculated at %g %%", DefaultVATRate))
+	}
+
+	calculation.VATBase = calculation.CustomsValue + calculation.Duty
+	calculation.VAT = calculation.VATBase * calculation.VATRate / 100
+	calculation.Total = calculation.Duty + calculation.VAT
+
+	for i := range calculation.Measures {
+		calculation.Measures[i].Amount = roundAmount(calculation.Measures[i].Amount)
+	}
+	calculation.Duty = roundAmount(calculation.Duty)
+	calculation.VATBase = roundAmount(calculation.VATBase)
+	calculation.VAT = roundAmount(calculation.VAT)
+	calculation.Total = roundAmount(calculation.Total)
+
+	return calculation, nil
+}
+
+// quotaNote tells that a duty only applies within the tariff quota with orderNumber, whose status is nil if the
+// quota has no definition in force.
+func quotaNote(orderNumber string, quota *QuotaStatus) string {
+	if quota == nil {
+		return fmt.Sprintf("The duty applies within tariff quota %s while its balance lasts", orderNumber)
+	}
+	note := fmt.Sprintf("The duty applies within tariff quota %s while its balance lasts, %g %s remain", orderNumber, quota.Balance, quota.Unit)
+	if quota.Status == QuotaCritical {
+		note += " and the quota is critical"
+	}
+	return note
+}
+
+// measureComponents returns the components the duty of a measure is calculated from, given the presented
+// certificates. The conditions of the measure are evaluated first: a condition that is met may apply a duty of its
+// own, or make the measure not apply. The components of the measure are used when no condition that is met applies
+// a duty. reason tells why the measure does not apply, if so.
+func measureComponents(measure MeasureDuty, certificates map[string]bool) (components []duty.Component, reason string) {
+	for _, group := range measure.Conditions {
+		condition, decided := group.Evaluate(certificates)
+		if !decided {
+			return nil, "depends on conditions that cannot be evaluated: " + group.Summary.EN
+		}
+
+		switch condition.Action {
+		case duty.ActionApply:
+			components = append(components, condition.Components...)
+		case duty.ActionNotApplicable:
+			return nil, "not applicable: " + group.Summary.EN
+		case duty.ActionProhibited:
+			return nil, "import not allowed: " + group.Summary.EN
+		default:
+			return nil, "unknown action " + condition.ActionCode + ": " + group.Summary.EN
+		}
+	}
+	if len(components) == 0 {
+		components = dutyComponents(measure.Components)
+	}
+	if len(components) == 0 {
+		return nil, "no duty"
+	}
+
+	return components, ""
+}
+
+// roundAmount rounds an amount to two decimals.
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"tulltaxan/pkg/duty"
+)
+
+// noQuotas is the quota status of calculations without tariff quotas.
+func noQuotas(orderNumber string) (*QuotaStatus, error) {
+	return nil, nil
+}
+
+// percentDuty returns the component of an ad valorem duty.
+func percentDuty(amount float64) DutyComponent {
+	return DutyComponent{DutyExpressionID: duty.ExprDuty, DutyAmount: &amount}
+}
+
+// conditionGroups groups conditions like they are loaded with the measures.
+func conditionGroups(conditions ...duty.Condition) []duty.ConditionGroup {
+	for i := range conditions {
+		conditions[i].Action = duty.ClassifyAction(conditions[i].ActionCode)
+	}
+	return duty.GroupConditions(conditions, nil, &duty.Formatter{})
+}
+
+func TestCalculateLandedCostConditions(t *testing.T) {
+	thirdCountry := MeasureDuty{SID: 1, MeasureType: "103", GeographicalAreaID: ergaOmnes, Components: []DutyComponent{percentDuty(10)}}
+	// The preference requires a proof of origin, the Y certificate, and does not apply without one
+	preference := MeasureDuty{SID: 2, MeasureType: "142", GeographicalAreaID: "KR", Components: []DutyComponent{percentDuty(0)},
+		Conditions: conditionGroups(
+			duty.Condition{Code: "Y", SequenceNumber: 1, Certificate: "Y929", ActionCode: "27"},
+			duty.Condition{Code: "Y", SequenceNumber: 2, ActionCode: "07"},
+		)}
+	// The anti-dumping duty is lowered for goods of a company with a certificate
+	antiDumping := MeasureDuty{SID: 3, MeasureType: "552", GeographicalAreaID: "KR", Components: []DutyComponent{percentDuty(30)},
+		Conditions: conditionGroups(
+			duty.Condition{Code: "B", SequenceNumber: 1, Certificate: "D008", ActionCode: "01", Components: []duty.Component{{DutyExpressionID: duty.ExprDuty, Amount: 5}}},
+			duty.Condition{Code: "B", SequenceNumber: 2, ActionCode: "01", Components: []duty.Component{{DutyExpressionID: duty.ExprDuty, Amount: 30}}},
+		)}
+	// The goods may only be imported with a licence, the duty of the measure is kept
+	licence := MeasureDuty{SID: 4, MeasureType: "695", GeographicalAreaID: ergaOmnes, Components: []DutyComponent{percentDuty(2)},
+		Conditions: conditionGroups(
+			duty.Condition{Code: "A", SequenceNumber: 1, Certificate: "L001", ActionCode: "29"},
+			duty.Condition{Code: "A", SequenceNumber: 2, ActionCode: "09"},
+		)}
+
+	tests := []struct {
+		name         string
+		measures     []MeasureDuty
+		certificates []string
+		duty         float64
+		applied      map[int]bool   // applied calculated measures by SID
+		skipped      map[int]string // reasons of skipped measures by SID, or a prefix of them
+	}{
+		{
+			name:         "preference with proof of origin",
+			measures:     []MeasureDuty{thirdCountry, preference},
+			certificates: []string{"Y929"},
+			duty:         0,
+			applied:      map[int]bool{1: false, 2: true},
+		},
+		{
+			name:     "preference without proof of origin",
+			measures: []MeasureDuty{thirdCountry, preference},
+			duty:     100,
+			applied:  map[int]bool{1: true},
+			skipped:  map[int]string{2: "not applicable"},
+		},
+		{
+			name:         "condition duty replaces the duty of the measure",
+			measures:     []MeasureDuty{thirdCountry, antiDumping},
+			certificates: []string{"D008"},
+			duty:         150,
+			applied:      map[int]bool{1: true, 3: true},
+		},
+		{
+			name:     "condition duty of the fallback condition",
+			measures: []MeasureDuty{thirdCountry, antiDumping},
+			duty:     400,
+			applied:  map[int]bool{1: true, 3: true},
+		},
+		{
+			name:         "licence presented",
+			measures:     []MeasureDuty{thirdCountry, licence},
+			certificates: []string{"L001"},
+			duty:         120,
+			applied:      map[int]bool{1: true, 4: true},
+		},
+		{
+			name:     "prohibited without licence",
+			measures: []MeasureDuty{thirdCountry, licence},
+			duty:     100,
+			applied:  map[int]bool{1: true},
+			skipped:  map[int]string{4: "import not allowed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := CalculationRequest{Code: "8703101100", Origin: "KR", CustomsValue: 1000, Currency: "EUR", Certificates: tt.certificates}
+			calculation, err := calculateLandedCost(req, tt.measures, noQuotas, nil)
+			if err != nil {
+				t.Fatalf("calculateLandedCost: %v", err)
+			}
+
+			if calculation.Duty != tt.duty {
+				t.Errorf("duty = %v, want %v", calculation.Duty, tt.duty)
+			}
+
+			if len(calculation.Measures) != len(tt.applied) {
+				t.Errorf("calculated measures = %d, want %d", len(calculation.Measures), len(tt.applied))
+			}
+			for _, measure := range calculation.Measures {
+				if applied, ok := tt.applied[measure.SID]; !ok || measure.Applied != applied {
+					t.Errorf("measure %d applied = %v, want %v", measure.SID, measure.Applied, applied)
+				}
+			}
+
+			if len(calculation.Skipped) != len(tt.skipped) {
+				t.Errorf("skipped measures = %d, want %d", len(calculation.Skipped), len(tt.skipped))
+			}
+			for _, measure := range calculation.Skipped {
+				if reason, ok := tt.skipped[measure.SID]; !ok || !strings.HasPrefix(measure.Reason, reason) {
+					t.Errorf("measure %d skipped because %q, want %q", measure.SID, measure.Reason, reason)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+	"tulltaxan/pkg/duty"
+)
+
+// TariffChanges are the differences in the tariff of a commodity code between two dates.
+type TariffChanges struct {
+	Code                string             `json:"code"`
+	From                time.Time          `json:"from"`
+	To                  time.Time          `json:"to"`
+	Description         *DescriptionChange `json:"description"` // nil if the description did not change
+	AddedMeasures       []MeasureVersion   `json:"added_measures"`
+	RemovedMeasures     []MeasureVersion   `json:"removed_measures"`
+	ChangedDuties       []DutyChange       `json:"changed_duties"`
+	AddedCertificates   []Certificate      `json:"added_certificates"`
+	RemovedCertificates []Certificate      `json:"removed_certificates"`
+}
+
+// DescriptionChange is the Swedish description of a commodity code on two dates.
+type DescriptionChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MeasureVersion is a measure as it was recorded on a date.
+type MeasureVersion struct {
+	SID                int             `json:"sid"`
+	GoodsNomenclature  string          `json:"goods_nomenclature_code"`
+	MeasureType        string          `json:"measure_type"`
+	GeographicalAreaID string          `json:"geographical_area_id"`
+	AdditionalCode     string          `json:"additional_code"`
+	DateStart          time.Time       `json:"date_start"`
+	DateEnd            *time.Time      `json:"date_end"`
+	Components         []DutyComponent `json:"components"`
+	Duty               duty.Text       `json:"duty"`
+}
+
+// DutyComponent is a measure component, one part of the duty expression of a measure.
+type DutyComponent struct {
+	DutyExpressionID             int      `json:"duty_expression_id"`
+	DutyAmount                   *float64 `json:"duty_amount"`
+	MonetaryUnitCode             string   `json:"monetary_unit_code"`
+	MeasurementUnitCode          string   `json:"measurement_unit_code"`
+	MeasurementUnitQualifierCode string   `json:"measurement_unit_qualifier_code"`
+}
+
+// equal reports whether c and other have the same values.
+func (c DutyComponent) equal(other DutyComponent) bool {
+	sameAmount := c.DutyAmount == nil && other.DutyAmount == nil ||
+		c.DutyAmount != nil && other.DutyAmount != nil && *c.DutyAmount == *other.DutyAmount
+	c.DutyAmount, other.DutyAmount = nil, nil
+
+	return sameAmount && c == other
+}
+
+// DutyChange is a measure in force on both dates whose duty expression changed in between.
+type DutyChange struct {
+	Measure  MeasureVersion  `json:"measure"`
+	From     []DutyComponent `json:"from"`
+	To       []DutyComponent `json:"to"`
+	FromDuty duty.Text       `json:"from_duty"`
+	ToDuty   duty.Text       `json:"to_duty"`
+}
+
+// tariffState is the tariff of a commodity code on a date.
+type tariffState struct {
+	description  string
+	measures     map[int]MeasureVersion
+	certificates map[Certificate]bool
+}
+
+// GetTariffChanges compares the tariff of a 10 digit commodity code on the dates from and to. The tariff on a date
+// consists of the measures in force on that date, on the code or any of the levels above it, as they were recorded
+// in the database at the end of the day. This includes the versions of measures, duties and descriptions that were
+// later replaced by dif files, which are kept in the history tables.
+func GetTariffChanges(ctx context.Context, conn DB, code string, from, to time.Time) (*TariffChanges, error) {
+	composition, err := getTaricComposition(code)
+	if err != nil {
+		return nil, fmt.Errorf("getTaricComposition: %w", err)
+	}
+
+	before, err := getTariffState(ctx, conn, composition, from)
+	if err != nil {
+		return nil, fmt.Errorf("tariff on %s: %w", from.Format(time.DateOnly), err)
+	}
+	after, err := getTariffState(ctx, conn, composition, to)
+	if err != nil {
+		return nil, fmt.Errorf("tariff on %s: %w", to.Format(time.DateOnly), err)
+	}
+
+	formatter, err := GetDutyFormatter(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("GetDutyFormatter: %w", err)
+	}
+	for _, state := range []*tariffState{before, after} {
+		for sid, measure := range state.measures {
+			measure.Duty = formatter.Text(dutyComponents(measure.Components))
+			state.measures[sid] = measure
+		}
+	}
+
+	changes := &TariffChanges{
+		Code:                code,
+		From:                from,
+		To:                  to,
+		AddedMeasures:       []MeasureVersion{},
+		RemovedMeasures:     []MeasureVersion{},
+		ChangedDuties:       []DutyChange{},
+		AddedCertificates:   []Certificate{},
+		RemovedCertificates: []Certificate{},
+	}
+
+	if before.description != after.description {
+		changes.Description = &DescriptionChange{From: before.description, To: after.description}
+	}
+
+	for sid, measure := range after.measures {
+		previous, ok := before.measures[sid]
+		if !ok {
+			changes.AddedMeasures = append(changes.AddedMeasures, measure)
+		} else if !slices.EqualFunc(previous.Components, measure.Components, DutyComponent.equal) {
+			changes.ChangedDuties = append(changes.ChangedDuties, DutyChange{
+				Measure:  measure,
+				From:     previous.Components,
+				To:       measure.Components,
+				FromDuty: previous.Duty,
+				ToDuty:   measure.Duty,
+			})
+		}
+	}
+	for sid, measure := range before.measures {
+		if _, ok := after.measures[sid]; !ok {
+			changes.RemovedMeasures = append(changes.RemovedMeasures, measure)
+		}
+	}
+
+	for certificate := range after.certificates {
+		if !before.certificates[certificate] {
+			changes.AddedCertificates = append(changes.AddedCertificates, certificate)
+		}
+	}
+	for certificate := range before.certificates {
+		if !after.certificates[certificate] {
+			changes.RemovedCertificates = append(changes.RemovedCertificates, certificate)
+		}
+	}
+
+	// Map iteration order is random, sort for stable responses
+	sortMeasures := func(measures []MeasureVersion) {
+		sort.Slice(measures, func(i, j int) bool { return measures[i].SID < measures[j].SID })
+	}
+	sortMeasures(changes.AddedMeasures)
+	sortMeasures(changes.RemovedMeasures)
+	sort.Slice(changes.ChangedDuties, func(i, j int) bool {
+		return changes.ChangedDuties[i].Measure.SID < changes.ChangedDuties[j].Measure.SID
+	})
+	slices.Sort(changes.AddedCertificates)
+	slices.Sort(changes.RemovedCertificates)
+
+	return changes, nil
+}
+
+// getTariffState returns the tariff of a commodity code on date, as recorded at the end of that day.
+func getTariffState(ctx context.Context, conn DB, composition *TaricComposition, date time.Time) (*tariffState, error) {
+	state := &tariffState{measures: map[int]MeasureVersion{}, certificates: map[Certificate]bool{}}
+
+	// The version of a row recorded at the end of the day is the one that had not been replaced by the next day
+	err := conn.QueryRow(ctx, `
+	SELECT COALESCE(string_agg(gnd.description, ' '), '')
+	FROM goods_nomenclature gn
+		JOIN goods_nomenclature_description_period gndp ON gn.sid = gndp.parent_sid
+		JOIN goods_nomenclature_description_history gnd ON gndp.sid = gnd.parent_sid
+	WHERE gn.goods_nomenclature_code = $1
+		AND gnd.language_id = 'SV'
+		AND gn.date_start <= $2::DATE
+		AND (
+			gn.date_end IS NULL
+			OR gn.date_end >= $2::DATE
+		)
+		AND gndp.date_start <= $2::DATE
+		AND (
+			gndp.date_end IS NULL
+			OR gndp.date_end >= $2::DATE
+		)
+		AND gnd.recorded_from < $2::DATE + 1
+		AND (
+			gnd.recorded_to IS NULL
+			OR gnd.recorded_to >= $2::DATE + 1
+		)`, composition.Taric, date).Scan(&state.description)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query description: %w", err)
+	}
+
+	rows, err := conn.Query(ctx, `
+	SELECT sid,
+		goods_nomenclature_code,
+		COALESCE(measure_type, ''),
+		COALESCE(geographical_area_id, ''),
+		COALESCE(additional_code_type, '') || COALESCE(additional_code_id, ''),
+		date_start,
+		date_end
+	FROM measure_history
+	WHERE goods_nomenclature_code = ANY($1)
+		AND date_start <= $2::DATE
+		AND (
+			date_end IS NULL
+			OR date_end >= $2::DATE
+		)
+		AND recorded_from < $2::DATE + 1
+		AND (
+			recorded_to IS NULL
+			OR recorded_to >= $2::DATE + 1
+		)`, composition.Levels(), date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measures: %w", err)
+	}
+
+	sids := []int{}
+	for rows.Next() {
+		measure := MeasureVersion{Components: []DutyComponent{}}
+		err := rows.Scan(&measure.SID, &measure.GoodsNomenclature, &measure.MeasureType, &measure.GeographicalAreaID,
+			&measure.AdditionalCode, &measure.DateStart, &measure.DateEnd)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan measure: %w", err)
+		}
+		state.measures[measure.SID] = measure
+		sids = append(sids, measure.SID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read measures: %w", err)
+	}
+
+	rows, err = conn.Query(ctx, `
+	SELECT parent_sid,
+		duty_expression_id,
+		duty_amount,
+		COALESCE(monetary_unit_code, ''),
+		COALESCE(measurement_unit_code, ''),
+		COALESCE(measurement_unit_qualifier_code, '')
+	FROM measure_component_history
+	WHERE parent_sid = ANY($1)
+		AND recorded_from < $2::DATE + 1
+		AND (
+			recorded_to IS NULL
+			OR recorded_to >= $2::DATE + 1
+		)
+	ORDER BY parent_sid,
+		sequence_number,
+		duty_expression_id`, sids, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measure components: %w", err)
+	}
+
+	for rows.Next() {
+		var (
+			sid       int
+			component DutyComponent
+		)
+		err := rows.Scan(&sid, &component.DutyExpressionID, &component.DutyAmount, &component.MonetaryUnitCode,
+			&component.MeasurementUnitCode, &component.MeasurementUnitQualifierCode)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan measure component: %w", err)
+		}
+		measure := state.measures[sid]
+		measure.Components = append(measure.Components, component)
+		state.measures[sid] = measure
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read measure components: %w", err)
+	}
+
+	rows, err = conn.Query(ctx, `
+	SELECT DISTINCT certificate_type || certificate_code
+	FROM measure_condition_history
+	WHERE parent_sid = ANY($1)
+		AND certificate_type IS NOT NULL
+		AND certificate_code IS NOT NULL
+		AND recorded_from < $2::DATE + 1
+		AND (
+			recorded_to IS NULL
+			OR recorded_to >= $2::DATE + 1
+		)`, sids, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query certificates: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var certificate Certificate
+		if err := rows.Scan(&certificate); err != nil {
+			return nil, fmt.Errorf("failed to scan certificate: %w", err)
+		}
+		state.certificates[certificate] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read certificates: %w", err)
+	}
+
+	return state, nil
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"tulltaxan/pkg/duty"
+)
+
+// GetMeasureConditions returns the conditions of the measures with the given sids, grouped by condition code into
+// the alternatives that decide the action of each measure. Measures without conditions are left out.
+func GetMeasureConditions(ctx context.Context, conn DB, sids []int, formatter *duty.Formatter) (map[int][]duty.ConditionGroup, error) {
+	descriptions := map[string]string{}
+	rows, err := conn.Query(ctx, `
+	SELECT parent_condition_code,
+		COALESCE(description, '')
+	FROM measure_condition_code_description
+	WHERE language_id = 'SV'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query condition codes: %w", err)
+	}
+
+	for rows.Next() {
+		var code, description string
+		if err := rows.Scan(&code, &description); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan condition code: %w", err)
+		}
+		descriptions[code] = description
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read condition codes: %w", err)
+	}
+
+	rows, err = conn.Query(ctx, `
+	SELECT mc.sid,
+		mc.parent_sid,
+		COALESCE(mc.condition_code_id, ''),
+		COALESCE(mc.sequence_number, 0),
+		CASE
+			WHEN mc.certificate_type IS NOT NULL
+			AND mc.certificate_code IS NOT NULL THEN mc.certificate_type || mc.certificate_code
+			ELSE ''
+		END,
+		mc.duty_amount,
+		COALESCE(mc.monetary_unit_code, ''),
+		COALESCE(mc.measurement_unit_code, ''),
+		COALESCE(mc.measurement_unit_qualifier_code, ''),
+		COALESCE(mc.action_code, ''),
+		COALESCE(mad.description, '')
+	FROM measure_condition mc
+		LEFT JOIN measure_action_description mad ON mc.action_code = mad.parent_action_code
+			AND mad.language_id = 'SV'
+	WHERE mc.parent_sid = ANY($1)`, sids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measure conditions: %w", err)
+	}
+
+	conditions := map[int]duty.Condition{} // by condition sid
+	measureSIDs := map[int]int{}           // measure sid by condition sid
+	conditionSIDs := []int{}
+	for rows.Next() {
+		var (
+			sid, measureSID int
+			condition       duty.Condition
+			amount          *float64
+			threshold       duty.Component
+		)
+		err := rows.Scan(&sid, &measureSID, &condition.Code, &condition.SequenceNumber, &condition.Certificate, &amount,
+			&threshold.MonetaryUnit, &threshold.MeasurementUnit, &threshold.MeasurementUnitQualifier, &condition.ActionCode,
+			&condition.ActionDescription)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan measure condition: %w", err)
+		}
+
+		if amount != nil {
+			threshold.Amount = *amount
+			condition.Threshold = &threshold
+		}
+		condition.Action = duty.ClassifyAction(condition.ActionCode)
+		condition.Components = []duty.Component{}
+
+		conditions[sid] = condition
+		measureSIDs[sid] = measureSID
+		conditionSIDs = append(conditionSIDs, sid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read measure conditions: %w", err)
+	}
+
+	// The duties applied by the actions of the conditions
+	rows, err = conn.Query(ctx, `
+	SELECT parent_sid,
+		duty_expression_id,
+		duty_amount,
+		COALESCE(monetary_unit_code, ''),
+		COALESCE(measurement_unit_code, ''),
+		COALESCE(measurement_unit_qualifier_code, '')
+	FROM measure_condition_component
+	WHERE parent_sid = ANY($1)
+	ORDER BY parent_sid,
+		sequence_number,
+		duty_expression_id`, conditionSIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measure condition components: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			sid       int
+			component DutyComponent
+		)
+		err := rows.Scan(&sid, &component.DutyExpressionID, &component.DutyAmount, &component.MonetaryUnitCode,
+			&component.MeasurementUnitCode, &component.MeasurementUnitQualifierCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan measure condition component: %w", err)
+		}
+
+		condition := conditions[sid]
+		condition.Components = append(condition.Components, component.dutyComponent())
+		conditions[sid] = condition
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read measure condition components: %w", err)
+	}
+
+	byMeasure := map[int][]duty.Condition{}
+	for _, sid := range conditionSIDs {
+		condition := conditions[sid]
+		condition.Duty = formatter.Text(condition.Components)
+		byMeasure[measureSIDs[sid]] = append(byMeasure[measureSIDs[sid]], condition)
+	}
+
+	groups := make(map[int][]duty.ConditionGroup, len(byMeasure))
+	for measureSID, measureConditions := range byMeasure {
+		groups[measureSID] = duty.GroupConditions(measureConditions, descriptions, formatter)
+	}
+
+	return groups, nil
+}
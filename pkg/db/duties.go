@@ -0,0 +1,317 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+	"tulltaxan/pkg/duty"
+)
+
+// MeasureDuty is an import measure with its duty, such as a third country duty or a tariff preference, and the
+// conditions deciding whether and how it applies. Measures such as prohibitions have conditions but no duty.
+type MeasureDuty struct {
+	SID                int                   `json:"sid"`
+	GoodsNomenclature  string                `json:"goods_nomenclature_code"`
+	MeasureType        string                `json:"measure_type"`
+	Description        string                `json:"description"` // Swedish description of the measure type
+	GeographicalAreaID string                `json:"geographical_area_id"`
+	AdditionalCode     string                `json:"additional_code"`
+	QuotaOrderNumber   string                `json:"quota_order_number"` // six digits, empty if the measure is not a quota
+	Components         []DutyComponent       `json:"components"`
+	Duty               duty.Text             `json:"duty"`
+	Conditions         []duty.ConditionGroup `json:"conditions"`
+}
+
+// GetDutyFormatter loads the duty expressions, measurement units and qualifiers duty expressions are rendered with.
+func GetDutyFormatter(ctx context.Context, conn DB) (*duty.Formatter, error) {
+	formatter := &duty.Formatter{
+		Expressions: map[int]duty.Expression{},
+		Units:       map[string]map[string]string{},
+		Qualifiers:  map[string]map[string]string{},
+	}
+
+	rows, err := conn.Query(ctx, `
+	SELECT de.duty_expression_id,
+		COALESCE(de.duty_amount_applicability_code, 0),
+		COALESCE(de.monetary_unit_applicability_code, 0),
+		COALESCE(de.measurement_unit_applicability_code, 0),
+		COALESCE(ded.language_id, ''),
+		COALESCE(ded.description, '')
+	FROM duty_expression de
+		LEFT JOIN duty_expression_description ded ON de.duty_expression_id = ded.parent_duty_expression_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duty expressions: %w", err)
+	}
+
+	for rows.Next() {
+		var (
+			id                    string
+			expression            duty.Expression
+			language, description string
+		)
+		err := rows.Scan(&id, &expression.DutyAmount, &expression.MonetaryUnit, &expression.MeasurementUnit, &language, &description)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan duty expression: %w", err)
+		}
+
+		// Duty expression ids are zero padded, "01", while measure components refer to them as numbers
+		expressionID, err := strconv.Atoi(id)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("invalid duty expression id %q: %w", id, err)
+		}
+
+		if existing, ok := formatter.Expressions[expressionID]; ok {
+			expression.Descriptions = existing.Descriptions
+		} else {
+			expression.Descriptions = map[string]string{}
+		}
+		if language != "" {
+			expression.Descriptions[language] = description
+		}
+		formatter.Expressions[expressionID] = expression
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read duty expressions: %w", err)
+	}
+
+	descriptions := []struct {
+		name  string
+		query string
+		into  map[string]map[string]string
+	}{
+		{"measurement units", `
+		SELECT TRIM(parent_unit_code),
+			language_id,
+			description
+		FROM measurement_unit_description`, formatter.Units},
+		{"measurement unit qualifiers", `
+		SELECT parent_measurement_unit_qualifier_code,
+			language_id,
+			COALESCE(description, '')
+		FROM measurement_unit_qualifier_description`, formatter.Qualifiers},
+	}
+	for _, table := range descriptions {
+		rows, err := conn.Query(ctx, table.query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", table.name, err)
+		}
+
+		for rows.Next() {
+			var code, language, description string
+			if err := rows.Scan(&code, &language, &description); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan %s: %w", table.name, err)
+			}
+			if table.into[code] == nil {
+				table.into[code] = map[string]string{}
+			}
+			table.into[code][language] = description
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+		}
+	}
+
+	return formatter, nil
+}
+
+// GetMeasureDuties returns the measures with duties or conditions in force on date for a 10 digit commodity code, on
+// the code or any of the levels above it, that apply to goods moving in direction, with their duty expressions
+// rendered by formatter. If origin is set, only the measures applying to goods originating in or destined for that
+// country are returned: measures for every third country, for the country itself or for a group it is a member of
+// on date, unless it is excluded from the measure.
+func GetMeasureDuties(ctx context.Context, conn DB, code, origin string, direction Direction, date time.Time,
+	formatter *duty.Formatter) ([]MeasureDuty, error) {
+	duties, err := getMeasureDuties(ctx, conn, []string{code}, origin, direction, date, formatter)
+	if err != nil {
+		return nil, err
+	}
+
+	return duties[code], nil
+}
+
+// getMeasureDuties returns the measure duties of GetMeasureDuties for several commodity codes with a single query,
+// by code. A measure on a level shared by several codes, such as their heading, is returned for each of them.
+func getMeasureDuties(ctx context.Context, conn DB, codes []string, origin string, direction Direction, date time.Time,
+	formatter *duty.Formatter) (map[string][]MeasureDuty, error) {
+	levels, codesByLevel, err := codeLevels(codes)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.Query(ctx, `
+	SELECT m.sid,
+		m.goods_nomenclature_code,
+		m.measure_type,
+		COALESCE(mtd.description, ''),
+		COALESCE(m.geographical_area_id, ''),
+		COALESCE(m.additional_code_type, '') || COALESCE(m.additional_code_id, ''),
+		COALESCE(LPAD(m.quota_order_number::TEXT, 6, '0'), ''),
+		mc.duty_expression_id,
+		mc.duty_amount,
+		COALESCE(mc.monetary_unit_code, ''),
+		COALESCE(mc.measurement_unit_code, ''),
+		COALESCE(mc.measurement_unit_qualifier_code, '')
+	FROM measure m
+		JOIN measure_type mt ON m.measure_type = mt.measure_type
+		LEFT JOIN measure_type_description mtd ON mt.measure_type = mtd.parent_measure_type
+			AND mtd.language_id = 'SV'
+		LEFT JOIN measure_component mc ON m.sid = mc.parent_sid
+	WHERE m.goods_nomenclature_code = ANY($1)
+		AND mt.trade_movement_code = ANY($4)
+		AND (
+			mc.parent_sid IS NOT NULL
+			OR EXISTS (
+				SELECT 1
+				FROM measure_condition c
+				WHERE c.parent_sid = m.sid
+			)
+		)
+		AND m.date_start <= $2::DATE
+		AND (
+			m.date_end IS NULL
+			OR m.date_end >= $2::DATE
+		)
+		AND (
+			$3::TEXT = ''
+			OR m.geographical_area_id IN ($3, $5)
+			OR m.geographical_area_id IN (
+				SELECT ga2.geographical_area_id
+				FROM geographical_area ga1
+					JOIN geographical_area_membership gam ON ga1.sid = gam.parent_sid
+					JOIN geographical_area ga2 ON gam.sid_geographical_area_group = ga2.sid
+				WHERE ga1.geographical_area_id = $3
+					AND gam.date_start <= $2::DATE
+					AND (
+						gam.date_end IS NULL
+						OR gam.date_end >= $2::DATE
+					)
+			)
+		)
+		AND NOT EXISTS (
+			SELECT 1
+			FROM measure_excluded_geographical_area mega
+			WHERE mega.parent_sid = m.sid
+				AND mega.geographical_area_id = $3
+		)
+	ORDER BY m.measure_type,
+		m.geographical_area_id,
+		m.sid,
+		mc.sequence_number,
+		mc.duty_expression_id`, levels, date, origin, direction.tradeMovementCodes(), ergaOmnes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measure duties: %w", err)
+	}
+	defer rows.Close()
+
+	measures := []MeasureDuty{}
+	for rows.Next() {
+		var (
+			measure      MeasureDuty
+			component    DutyComponent
+			expressionID *int // missing for measures without components
+		)
+		err := rows.Scan(&measure.SID, &measure.GoodsNomenclature, &measure.MeasureType, &measure.Description,
+			&measure.GeographicalAreaID, &measure.AdditionalCode, &measure.QuotaOrderNumber, &expressionID, &component.DutyAmount,
+			&component.MonetaryUnitCode, &component.MeasurementUnitCode, &component.MeasurementUnitQualifierCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan measure duty: %w", err)
+		}
+
+		// Rows are ordered by measure, so the components of a measure follow each other
+		if len(measures) == 0 || measures[len(measures)-1].SID != measure.SID {
+			measure.Components = []DutyComponent{}
+			measure.Conditions = []duty.ConditionGroup{}
+			measures = append(measures, measure)
+		}
+		if expressionID != nil {
+			component.DutyExpressionID = *expressionID
+			last := &measures[len(measures)-1]
+			last.Components = append(last.Components, component)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read measure duties: %w", err)
+	}
+
+	sids := make([]int, len(measures))
+	for i, measure := range measures {
+		sids[i] = measure.SID
+	}
+	conditions, err := GetMeasureConditions(ctx, conn, sids, formatter)
+	if err != nil {
+		return nil, fmt.Errorf("GetMeasureConditions: %w", err)
+	}
+
+	duties := make(map[string][]MeasureDuty, len(codes))
+	for _, code := range codes {
+		duties[code] = []MeasureDuty{}
+	}
+	for _, measure := range measures {
+		measure.Duty = formatter.Text(dutyComponents(measure.Components))
+		if groups, ok := conditions[measure.SID]; ok {
+			measure.Conditions = groups
+		}
+		for _, code := range codesByLevel[measure.GoodsNomenclature] {
+			duties[code] = append(duties[code], measure)
+		}
+	}
+
+	return duties, nil
+}
+
+// codeLevels returns the levels 10 digit commodity codes are made up of, and the codes by each of their levels.
+// A code is given once per level, even if it is given several times or is a level of itself, such as a heading.
+func codeLevels(codes []string) (levels []string, codesByLevel map[string][]string, err error) {
+	codesByLevel = map[string][]string{}
+	for _, code := range codes {
+		composition, err := getTaricComposition(code)
+		if err != nil {
+			return nil, nil, fmt.Errorf("getTaricComposition: %w", err)
+		}
+
+		for _, level := range composition.Levels() {
+			levelCodes, ok := codesByLevel[level]
+			if !ok {
+				levels = append(levels, level)
+			}
+			if !slices.Contains(levelCodes, code) {
+				codesByLevel[level] = append(levelCodes, code)
+			}
+		}
+	}
+
+	return levels, codesByLevel, nil
+}
+
+// dutyComponent returns c as a component of the duty package. A missing amount is zero.
+func (c DutyComponent) dutyComponent() duty.Component {
+	component := duty.Component{
+		DutyExpressionID:         c.DutyExpressionID,
+		MonetaryUnit:             c.MonetaryUnitCode,
+		MeasurementUnit:          c.MeasurementUnitCode,
+		MeasurementUnitQualifier: c.MeasurementUnitQualifierCode,
+	}
+	if c.DutyAmount != nil {
+		component.Amount = *c.DutyAmount
+	}
+
+	return component
+}
+
+// dutyComponents converts components to components of the duty package.
+func dutyComponents(components []DutyComponent) []duty.Component {
+	converted := make([]duty.Component, len(components))
+	for i, component := range components {
+		converted[i] = component.dutyComponent()
+	}
+
+	return converted
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodeLevels(t *testing.T) {
+	levels, codesByLevel, err := codeLevels([]string{"0101210000", "0101291000", "0100000000", "0101210000"})
+	if err != nil {
+		t.Fatalf("codeLevels: %v", err)
+	}
+
+	wantLevels := []string{"0100000000", "0101000000", "0101210000", "0101290000", "0101291000"}
+	if !reflect.DeepEqual(levels, wantLevels) {
+		t.Errorf("levels = %v, want %v", levels, wantLevels)
+	}
+
+	wantCodes := map[string][]string{
+		"0100000000": {"0101210000", "0101291000", "0100000000"},
+		"0101000000": {"0101210000", "0101291000"},
+		"0101210000": {"0101210000"},
+		"0101290000": {"0101291000"},
+		"0101291000": {"0101291000"},
+	}
+	if !reflect.DeepEqual(codesByLevel, wantCodes) {
+		t.Errorf("codes by level = %v, want %v", codesByLevel, wantCodes)
+	}
+
+	if _, _, err := codeLevels([]string{"0101"}); err == nil {
+		t.Error("codeLevels accepted a code of four digits")
+	}
+}
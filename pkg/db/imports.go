@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ImportRun is a database maintenance run and the filedist files it tried to import.
+type ImportRun struct {
+	ID         int          `json:"id"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt *time.Time   `json:"finished_at"`
+	Status     string       `json:"status"` // running, succeeded or failed
+	Error      *string      `json:"error"`
+	Files      []ImportFile `json:"files"`
+}
+
+// ImportFile is the outcome of importing one filedist file.
+type ImportFile struct {
+	Directory  string               `json:"directory"`
+	FileName   string               `json:"file_name"`
+	StartedAt  time.Time            `json:"started_at"`
+	FinishedAt *time.Time           `json:"finished_at"`
+	Status     string               `json:"status"` // succeeded, failed, quarantined or skipped
+	Error      *string              `json:"error"`
+	FileSize   *int64               `json:"file_size"`
+	Checksum   *string              `json:"checksum"`
+	ItemCounts map[string]ItemCount `json:"item_counts"` // per element name, e.g. measure
+}
+
+// ItemCount is the number of items of one element type upserted and deleted by a file.
+type ItemCount struct {
+	Upserted int `json:"upserted"`
+	Deleted  int `json:"deleted"`
+}
+
+// GetImportRuns returns the latest maintenance runs, newest first, together with their files in import order.
+func GetImportRuns(ctx context.Context, conn DB, limit int) ([]ImportRun, error) {
+	rows, err := conn.Query(ctx, `
+	SELECT id,
+		started_at,
+		finished_at,
+		status,
+		error
+	FROM import_run
+	ORDER BY started_at DESC
+	LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query import runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []ImportRun{}
+	runIndex := map[int]int{}
+	for rows.Next() {
+		run := ImportRun{Files: []ImportFile{}}
+		if err := rows.Scan(&run.ID, &run.StartedAt, &run.FinishedAt, &run.Status, &run.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan import run: %w", err)
+		}
+		runIndex[run.ID] = len(runs)
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read import runs: %w", err)
+	}
+
+	if len(runs) == 0 {
+		return runs, nil
+	}
+
+	runIDs := make([]int, 0, len(runs))
+	for _, run := range runs {
+		runIDs = append(runIDs, run.ID)
+	}
+
+	fileRows, err := conn.Query(ctx, `
+	SELECT run_id,
+		directory,
+		file_name,
+		started_at,
+		finished_at,
+		status,
+		error,
+		file_size,
+		checksum,
+		item_counts
+	FROM import_file
+	WHERE run_id = ANY($1)
+	ORDER BY run_id, id`, runIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query import files: %w", err)
+	}
+	defer fileRows.Close()
+
+	for fileRows.Next() {
+		var (
+			runID int
+			file  ImportFile
+		)
+		err := fileRows.Scan(&runID, &file.Directory, &file.FileName, &file.StartedAt, &file.FinishedAt, &file.Status,
+			&file.Error, &file.FileSize, &file.Checksum, &file.ItemCounts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import file: %w", err)
+		}
+
+		run := &runs[runIndex[runID]]
+		run.Files = append(run.Files, file)
+	}
+	if err := fileRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read import files: %w", err)
+	}
+
+	return runs, nil
+}
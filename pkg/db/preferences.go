@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+	"tulltaxan/pkg/duty"
+)
+
+// preferenceMeasureTypes are the measure types of tariff preferences, with and without tariff quotas.
+var preferenceMeasureTypes = []string{
+	"142", // tariff preference
+	"143", // preferential tariff quota
+	"145", // preferential suspension
+	"146", // preferential tariff quota under end-use
+}
+
+// ergaOmnes is the geographical area of all third countries.
+const ergaOmnes = "1011"
+
+// Preferences are the tariff preferences available for a commodity code, compared to the third country duty.
+type Preferences struct {
+	Code         string       `json:"code"`
+	Date         time.Time    `json:"date"`
+	ThirdCountry *MeasureDuty `json:"third_country"` // erga omnes third country duty, nil if there is none
+	// ThirdCountryDuty is the third country duty of the goods, if a customs value was given
+	ThirdCountryDuty *float64     `json:"third_country_duty"`
+	Preferences      []Preference `json:"preferences"`
+}
+
+// Preference is a tariff preference for a country or group of countries.
+type Preference struct {
+	MeasureDuty
+	AreaDescription string   `json:"area_description"`
+	Members         []string `json:"members"` // countries of a group on the date, empty for a single country
+	// ProofsOfOrigin are the certificates the conditions of the preference accept, such as N954 for EUR.1
+	ProofsOfOrigin []string `json:"proofs_of_origin"`
+	// SavingsPercentagePoints is the third country duty minus the preferential duty, if both are percentages
+	SavingsPercentagePoints *float64 `json:"savings_percentage_points"`
+	// Duty and savings compared to the third country duty for the goods, if a customs value was given
+	// and both duties could be calculated
+	GoodsDuty *float64 `json:"goods_duty"`
+	Savings   *float64 `json:"savings"`
+}
+
+// GetPreferences returns the tariff preferences in force on date for a 10 digit commodity code, on the code or any
+// of the levels above it. If origin is set, only the preferences applying to that country are returned.
+// If goods is not nil, the duty of the goods is calculated with every preference and compared to the third
+// country duty, converting amounts with converter.
+func GetPreferences(ctx context.Context, conn DB, code, origin string, date time.Time, goods *duty.Goods,
+	converter duty.Converter) (*Preferences, error) {
+	formatter, err := GetDutyFormatter(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("GetDutyFormatter: %w", err)
+	}
+	if goods != nil {
+		formatter.Converter, formatter.Currency = converter, goods.Currency
+	}
+
+	measures, err := GetMeasureDuties(ctx, conn, code, origin, Import, date, formatter)
+	if err != nil {
+		return nil, fmt.Errorf("GetMeasureDuties: %w", err)
+	}
+
+	preferences := &Preferences{Code: code, Date: date, Preferences: []Preference{}}
+	areas := []string{}
+	for _, measure := range measures {
+		switch {
+		case measure.MeasureType == "103" && measure.GeographicalAreaID == ergaOmnes && measure.AdditionalCode == "":
+			thirdCountry := measure
+			preferences.ThirdCountry = &thirdCountry
+
+		case slices.Contains(preferenceMeasureTypes, measure.MeasureType):
+			preference := Preference{MeasureDuty: measure, Members: []string{}, ProofsOfOrigin: []string{}}
+			for _, group := range measure.Conditions {
+				for _, condition := range group.Conditions {
+					if condition.Certificate != "" && !slices.Contains(preference.ProofsOfOrigin, condition.Certificate) {
+						preference.ProofsOfOrigin = append(preference.ProofsOfOrigin, condition.Certificate)
+					}
+				}
+			}
+			preferences.Preferences = append(preferences.Preferences, preference)
+			areas = append(areas, measure.GeographicalAreaID)
+		}
+	}
+
+	descriptions, members, err := getGeographicalAreas(ctx, conn, areas, date)
+	if err != nil {
+		return nil, fmt.Errorf("getGeographicalAreas: %w", err)
+	}
+
+	var thirdCountryRate *float64
+	if preferences.ThirdCountry != nil {
+		thirdCountryRate = adValoremRate(preferences.ThirdCountry.Components)
+		if goods != nil {
+			if result, err := duty.Calculate(dutyComponents(preferences.ThirdCountry.Components), *goods, converter); err == nil {
+				preferences.ThirdCountryDuty = &result.Duty
+			}
+		}
+	}
+
+	for i := range preferences.Preferences {
+		preference := &preferences.Preferences[i]
+		preference.AreaDescription = descriptions[preference.GeographicalAreaID]
+		if areaMembers, ok := members[preference.GeographicalAreaID]; ok {
+			preference.Members = areaMembers
+		}
+
+		if rate := adValoremRate(preference.Components); rate != nil && thirdCountryRate != nil {
+			savings := *thirdCountryRate - *rate
+			preference.SavingsPercentagePoints = &savings
+		}
+
+		if goods != nil && len(preference.Components) > 0 {
+			if result, err := duty.Calculate(dutyComponents(preference.Components), *goods, converter); err == nil {
+				preference.GoodsDuty = &result.Duty
+				if preferences.ThirdCountryDuty != nil {
+					savings := *preferences.ThirdCountryDuty - result.Duty
+					preference.Savings = &savings
+				}
+			}
+		}
+	}
+
+	return preferences, nil
+}
+
+// adValoremRate returns the percentage of a duty that is a single percentage of the customs value, otherwise nil.
+func adValoremRate(components []DutyComponent) *float64 {
+	if len(components) != 1 {
+		return nil
+	}
+
+	component := components[0].dutyComponent()
+	if component.DutyExpressionID != duty.ExprDuty || !component.AdValorem() {
+		return nil
+	}
+
+	return &component.Amount
+}
+
+// getGeographicalAreas returns the Swedish descriptions of geographical areas and the member countries of the
+// areas that are groups, as of date.
+func getGeographicalAreas(ctx context.Context, conn DB, areas []string, date time.Time) (map[string]string, map[string][]string, error) {
+	descriptions := map[string]string{}
+	rows, err := conn.Query(ctx, `
+	SELECT DISTINCT ON (ga.geographical_area_id) ga.geographical_area_id,
+		gad.description
+	FROM geographical_area ga
+		JOIN geographical_area_description_period gadp ON ga.sid = gadp.parent_sid
+		JOIN geographical_area_description gad ON gadp.sid = gad.parent_sid
+	WHERE ga.geographical_area_id = ANY($1)
+		AND gad.language_id = 'SV'
+		AND gadp.date_start <= $2::DATE
+	ORDER BY ga.geographical_area_id,
+		gadp.date_start DESC`, areas, date)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query geographical area descriptions: %w", err)
+	}
+
+	for rows.Next() {
+		var area, description string
+		if err := rows.Scan(&area, &description); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan geographical area description: %w", err)
+		}
+		descriptions[area] = description
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read geographical area descriptions: %w", err)
+	}
+
+	members := map[string][]string{}
+	rows, err = conn.Query(ctx, `
+	SELECT ga_group.geographical_area_id,
+		ga_member.geographical_area_id
+	FROM geographical_area ga_group
+		JOIN geographical_area_membership gam ON ga_group.sid = gam.sid_geographical_area_group
+		JOIN geographical_area ga_member ON gam.parent_sid = ga_member.sid
+	WHERE ga_group.geographical_area_id = ANY($1)
+		AND gam.date_start <= $2::DATE
+		AND (
+			gam.date_end IS NULL
+			OR gam.date_end >= $2::DATE
+		)
+	ORDER BY ga_group.geographical_area_id,
+		ga_member.geographical_area_id`, areas, date)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query geographical area members: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var group, member string
+		if err := rows.Scan(&group, &member); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan geographical area member: %w", err)
+		}
+		members[group] = append(members[group], member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read geographical area members: %w", err)
+	}
+
+	return descriptions, members, nil
+}
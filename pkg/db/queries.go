@@ -5,15 +5,27 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
+	"tulltaxan/pkg/currency"
+	"tulltaxan/pkg/duty"
 
 	"github.com/jackc/pgx/v5"
 )
 
+// DB is the part of *pgxpool.Pool, *pgx.Conn and pgx.Tx used to query the tariff, so queries can be served
+// concurrently from a connection pool or run on a single connection.
+type DB interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 // HSCode represents a result from the HS code search
 type HSCode struct {
 	Code              string            `json:"code"`
 	Description       string            `json:"description"`
 	MeasureComponents MeasureComponents `json:"measure_components"`
+	Duties            []MeasureDuty     `json:"duties"`
 }
 
 type MeasureComponents struct {
@@ -24,8 +36,28 @@ type MeasureComponents struct {
 type Certificate string
 type AdditionalCode string
 
-// SearchHSCodes queries the materialized view for matching HS codes
-func SearchHSCodes(ctx context.Context, conn *pgx.Conn, query string) ([]HSCode, error) {
+// Direction is the trade movement measures apply to.
+type Direction string
+
+const (
+	Import Direction = "import"
+	Export Direction = "export"
+)
+
+// tradeMovementCodes returns the trade movement codes of the measure types that apply to goods moving in direction d.
+// Measure types with code 2 apply to both imports and exports.
+func (d Direction) tradeMovementCodes() []int {
+	if d == Export {
+		return []int{1, 2}
+	}
+	return []int{0, 2}
+}
+
+// SearchHSCodes queries the materialized view for matching HS codes.
+// Codes, descriptions and measures are those valid on date, of which only the day is used. Measures are those
+// applying to goods moving in direction to or from country, or to any country if country is empty. Duties are only
+// returned for imports.
+func SearchHSCodes(ctx context.Context, conn DB, query, country string, direction Direction, date time.Time) ([]HSCode, error) {
 	if query == "" {
 		return nil, errors.New("query string cannot be empty")
 	}
@@ -39,8 +71,9 @@ func SearchHSCodes(ctx context.Context, conn *pgx.Conn, query string) ([]HSCode,
 			mv_goods_nomenclature_search
 		WHERE
 			search_vector @@ to_tsquery('swedish', $1)
+			AND validity @> $2::DATE
 		LIMIT 20;
-	`, processedQuery)
+	`, processedQuery, date)
 
 	if err != nil {
 		return nil, err
@@ -57,41 +90,118 @@ func SearchHSCodes(ctx context.Context, conn *pgx.Conn, query string) ([]HSCode,
 
 		results = append(results, hsCode)
 	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read hs codes: %w", err)
+	}
+	if len(results) == 0 {
+		return results, nil
+	}
 
+	codes := make([]string, len(results))
 	for i, hsCode := range results {
+		codes[i] = hsCode.Code
+	}
 
-		comp, err := getTaricComposition(hsCode.Code)
+	// The duties of every result are fetched at once
+	var duties map[string][]MeasureDuty
+	if direction == Import {
+		formatter, err := searchFormatter(ctx, conn, date)
 		if err != nil {
-			return nil, fmt.Errorf("getTaricComposition: %w", err)
+			return nil, fmt.Errorf("searchFormatter: %w", err)
 		}
 
-		components, err := SearchMeasureComponents(comp.HSCode, "RU", ctx, conn)
+		duties, err = getMeasureDuties(ctx, conn, codes, country, direction, date, formatter)
 		if err != nil {
-			return nil, fmt.Errorf("SearchMeasureComponents: %w", err)
+			return nil, fmt.Errorf("getMeasureDuties: %w", err)
 		}
+	}
 
-		if components != nil {
-			results[i].MeasureComponents = *components
-		}
+	components, err := SearchMeasureComponents(ctx, conn, codes, country, direction, date)
+	if err != nil {
+		return nil, fmt.Errorf("SearchMeasureComponents: %w", err)
+	}
+
+	for i, hsCode := range results {
+		results[i].MeasureComponents = components[hsCode.Code]
 
+		results[i].Duties = []MeasureDuty{}
+		if measures, ok := duties[hsCode.Code]; ok {
+			results[i].Duties = measures
+		}
 	}
 
 	return results, nil
 }
 
-func SearchMeasureComponents(hs string, dstCtry string, ctx context.Context, conn *pgx.Conn) (*MeasureComponents, error) {
-	// Check validity of input
-	if hs == "" || dstCtry == "" || len(dstCtry) != 2 {
-		return nil, nil
+// maxSearchFormatters is the number of dates searchFormatter keeps formatters for.
+const maxSearchFormatters = 32
+
+// searchFormatters are the formatters of searchFormatter by date, loaded from the tariff as it was when the
+// materialized views were last refreshed.
+var searchFormatters = struct {
+	sync.Mutex
+	refreshedAt time.Time
+	byDate      map[string]*duty.Formatter
+}{byDate: map[string]*duty.Formatter{}}
+
+// searchFormatter returns the formatter of the duties found by searches on date, which shows specific duties in EUR
+// also in SEK at the rates of date. Searches run on every keystroke, so the formatter of a date is loaded once and
+// reused until the materialized views are refreshed, which they are after every import.
+func searchFormatter(ctx context.Context, conn DB, date time.Time) (*duty.Formatter, error) {
+	var refreshedAt *time.Time
+	if err := conn.QueryRow(ctx, `SELECT MAX(refreshed_at) FROM materialized_view_refresh`).Scan(&refreshedAt); err != nil {
+		return nil, fmt.Errorf("failed to query materialized view refresh: %w", err)
+	}
+
+	searchFormatters.Lock()
+	defer searchFormatters.Unlock()
+
+	if refreshedAt != nil && !refreshedAt.Equal(searchFormatters.refreshedAt) {
+		searchFormatters.refreshedAt = *refreshedAt
+		searchFormatters.byDate = map[string]*duty.Formatter{}
+	}
+
+	day := date.Format(time.DateOnly)
+	if formatter, ok := searchFormatters.byDate[day]; ok {
+		return formatter, nil
+	}
+
+	formatter, err := GetDutyFormatter(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("GetDutyFormatter: %w", err)
 	}
 
-	composition, err := getTaricComposition(hs)
+	// Specific duties in EUR are also shown in SEK
+	formatter.Converter, err = currency.Load(ctx, conn, date)
 	if err != nil {
-		return nil, fmt.Errorf("getTaricComposition: %w", err)
+		return nil, fmt.Errorf("currency.Load: %w", err)
+	}
+	formatter.Currency = "SEK"
+
+	if len(searchFormatters.byDate) >= maxSearchFormatters {
+		searchFormatters.byDate = map[string]*duty.Formatter{}
+	}
+	searchFormatters.byDate[day] = formatter
+
+	return formatter, nil
+}
+
+// SearchMeasureComponents returns the certificates and additional codes of the measures for 10 digit commodity codes,
+// on each code or any of the levels above it, that apply to goods moving in direction to or from country. If country
+// is empty, the measures for every country are included. The measures of all codes are queried at once, and returned
+// by code.
+// Measures, certificates, regulations and geographical area memberships are evaluated as of date, such as the
+// date a customs declaration is accepted. Only the day of date is used, end dates are inclusive.
+func SearchMeasureComponents(ctx context.Context, conn DB, codes []string, country string, direction Direction, date time.Time) (map[string]MeasureComponents, error) {
+	levels, codesByLevel, err := codeLevels(codes)
+	if err != nil {
+		return nil, err
 	}
 
 	rows, err := conn.Query(ctx, `
-	SELECT (mc.certificate_type || mc.certificate_code) AS y_code,
+	SELECT m.goods_nomenclature_code,
+		(mc.certificate_type || mc.certificate_code) AS y_code,
 		(m.additional_code_type || m.additional_code_id) AS additional_code
 	FROM measure m
 		LEFT JOIN measure_type mt ON m.measure_type = mt.measure_type
@@ -102,21 +212,31 @@ func SearchMeasureComponents(hs string, dstCtry string, ctx context.Context, con
 		LEFT JOIN modification_regulation mr ON m.regulation_id = mr.modification_regulation_id
 		LEFT JOIN certificate c ON mc.certificate_code = c.certificate_code
 		LEFT JOIN additional_code ac ON m.additional_code_id = ac.additional_code_id
-	WHERE m.goods_nomenclature_code IN (
-			$1,
-			$2,
-			$3,
-			$4
-		)
-		AND mt.trade_movement_code IN ('1', '2')
+	WHERE m.goods_nomenclature_code = ANY($1)
+		AND mt.trade_movement_code = ANY($4)
 		AND (
-			m.geographical_area_id = $5
+			$2::TEXT = ''
+			OR m.geographical_area_id = $2
 			OR m.geographical_area_id IN (
 				SELECT ga2.geographical_area_id
 				FROM geographical_area ga1
 					JOIN geographical_area_membership gam ON ga1.sid = gam.parent_sid
 					JOIN geographical_area ga2 ON gam.sid_geographical_area_group = ga2.sid
-				WHERE ga1.geographical_area_id = $5
+				WHERE ga1.geographical_area_id = $2
+					AND gam.date_start <= $3::DATE
+					AND (
+						gam.date_end IS NULL
+						OR gam.date_end >= $3::DATE
+					)
+			)
+		)
+		AND NOT (
+			$2::TEXT <> ''
+			AND EXISTS (
+				SELECT 1
+				FROM measure_excluded_geographical_area mega
+				WHERE mega.parent_sid = m.sid
+					AND mega.geographical_area_id = $2
 			)
 		)
 		AND (
@@ -125,49 +245,108 @@ func SearchMeasureComponents(hs string, dstCtry string, ctx context.Context, con
 		)
 		AND (
 			m.date_end IS NULL
-			OR m.date_end > CURRENT_TIMESTAMP
+			OR m.date_end >= $3::DATE
 		)
-		AND (m.date_start < CURRENT_TIMESTAMP)
+		AND (m.date_start <= $3::DATE)
 		AND (
-			c.date_end IS NULL
-			OR c.date_end > CURRENT_TIMESTAMP
+			c.certificate_code IS NULL
+			OR (
+				c.date_start <= $3::DATE
+				AND (
+					c.date_end IS NULL
+					OR c.date_end >= $3::DATE
+				)
+			)
+		)
+		AND (
+			br.date_start IS NULL
+			OR br.date_start <= $3::DATE
 		)
-		AND (c.date_start < CURRENT_TIMESTAMP)
 		AND (
 			br.date_end IS NULL
-			OR br.date_end > CURRENT_TIMESTAMP
+			OR br.date_end >= $3::DATE
+		)
+		AND (
+			mr.date_start IS NULL
+			OR mr.date_start <= $3::DATE
 		)
 		AND (
 			mr.date_end IS NULL
-			OR mr.date_end > CURRENT_TIMESTAMP
+			OR mr.date_end >= $3::DATE
 		)
-	GROUP BY y_code,
-		additional_code`, composition.Chapter, composition.HSCode, composition.HSUnderNumber, composition.CNCode, dstCtry)
-
+	GROUP BY m.goods_nomenclature_code,
+		y_code,
+		additional_code`, levels, country, date, direction.tradeMovementCodes())
 	if err != nil {
 		return nil, fmt.Errorf("failed to query y-codes and add codes: %w", err)
 	}
+	defer rows.Close()
 
-	components := new(MeasureComponents)
-
+	var found []levelComponents
 	for rows.Next() {
-		certificate := new(Certificate)
-		additionalCode := new(AdditionalCode)
-		if err := rows.Scan(&certificate, &additionalCode); err != nil {
-			if err != nil {
-				return nil, fmt.Errorf("failed to scan rows into variables: %w", err)
-			}
+		var row levelComponents
+		if err := rows.Scan(&row.level, &row.certificate, &row.additionalCode); err != nil {
+			return nil, fmt.Errorf("failed to scan rows into variables: %w", err)
 		}
+		found = append(found, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read y-codes and add codes: %w", err)
+	}
 
-		if certificate != nil {
-			components.Certificates = append(components.Certificates, *certificate)
-		}
-		if additionalCode != nil {
-			components.AdditionalCodes = append(components.AdditionalCodes, *additionalCode)
+	return componentsByCode(codes, codesByLevel, found), nil
+}
+
+// levelComponents is a certificate and additional code of the measures on a level of the nomenclature. Either may
+// be missing.
+type levelComponents struct {
+	level          string
+	certificate    *Certificate
+	additionalCode *AdditionalCode
+}
+
+// componentsByCode returns the measure components of every code from those found on the levels of the codes.
+// A pair of a certificate and an additional code found on several levels of a code is included once.
+func componentsByCode(codes []string, codesByLevel map[string][]string, found []levelComponents) map[string]MeasureComponents {
+	// Missing certificates and additional codes are empty, which found ones never are
+	type pair struct {
+		code           string
+		certificate    Certificate
+		additionalCode AdditionalCode
+	}
+
+	components := make(map[string]MeasureComponents, len(codes))
+	for _, code := range codes {
+		components[code] = MeasureComponents{}
+	}
+
+	seen := map[pair]bool{}
+	for _, row := range found {
+		for _, code := range codesByLevel[row.level] {
+			key := pair{code: code}
+			if row.certificate != nil {
+				key.certificate = *row.certificate
+			}
+			if row.additionalCode != nil {
+				key.additionalCode = *row.additionalCode
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			c := components[code]
+			if row.certificate != nil {
+				c.Certificates = append(c.Certificates, *row.certificate)
+			}
+			if row.additionalCode != nil {
+				c.AdditionalCodes = append(c.AdditionalCodes, *row.additionalCode)
+			}
+			components[code] = c
 		}
 	}
 
-	return components, nil
+	return components
 }
 
 type TaricComposition struct {
@@ -178,6 +357,33 @@ type TaricComposition struct {
 	Taric         string `json:"taric"`
 }
 
+// Levels returns the codes of the nomenclature levels measures on the taric code can be attached to,
+// from the chapter down to the taric code itself.
+func (c *TaricComposition) Levels() []string {
+	return []string{c.Chapter, c.HSCode, c.HSUnderNumber, c.CNCode, c.Taric}
+}
+
+// GeographicalAreaExists reports whether a geographical area, such as a country, is in force on date.
+func GeographicalAreaExists(ctx context.Context, conn DB, id string, date time.Time) (bool, error) {
+	var exists bool
+	err := conn.QueryRow(ctx, `
+	SELECT EXISTS (
+			SELECT 1
+			FROM geographical_area
+			WHERE geographical_area_id = $1
+				AND date_start <= $2::DATE
+				AND (
+					date_end IS NULL
+					OR date_end >= $2::DATE
+				)
+		)`, id, date).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to query geographical area: %w", err)
+	}
+
+	return exists, nil
+}
+
 // Requires 10 char hs code
 func getTaricComposition(taric string) (*TaricComposition, error) {
 	if len(taric) != 10 {
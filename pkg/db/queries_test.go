@@ -0,0 +1,34 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComponentsByCode(t *testing.T) {
+	codes := []string{"0101210000", "0101291000", "0201100000"}
+	_, codesByLevel, err := codeLevels(codes)
+	if err != nil {
+		t.Fatalf("codeLevels: %v", err)
+	}
+
+	certificate := func(c Certificate) *Certificate { return &c }
+	additionalCode := func(c AdditionalCode) *AdditionalCode { return &c }
+	found := []levelComponents{
+		// On the heading of the first two codes
+		{level: "0101000000", certificate: certificate("Y900")},
+		// Also on the first code itself, where it is only included once
+		{level: "0101210000", certificate: certificate("Y900")},
+		{level: "0101210000", certificate: certificate("Y900"), additionalCode: additionalCode("4100")},
+		{level: "0101291000", additionalCode: additionalCode("4200")},
+	}
+
+	want := map[string]MeasureComponents{
+		"0101210000": {Certificates: []Certificate{"Y900", "Y900"}, AdditionalCodes: []AdditionalCode{"4100"}},
+		"0101291000": {Certificates: []Certificate{"Y900"}, AdditionalCodes: []AdditionalCode{"4200"}},
+		"0201100000": {},
+	}
+	if got := componentsByCode(codes, codesByLevel, found); !reflect.DeepEqual(got, want) {
+		t.Errorf("componentsByCode = %v, want %v", got, want)
+	}
+}
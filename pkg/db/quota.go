@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Quota states derived from the quota events.
+const (
+	QuotaOpen      = "open"
+	QuotaCritical  = "critical"
+	QuotaExhausted = "exhausted"
+)
+
+// QuotaStatus is the current balance and state of a tariff quota.
+type QuotaStatus struct {
+	OrderNumber      string     `json:"order_number"`
+	DefinitionSID    int        `json:"definition_sid"`
+	DateStart        time.Time  `json:"date_start"`
+	DateEnd          *time.Time `json:"date_end"`
+	InitialVolume    float64    `json:"initial_volume"`
+	Volume           float64    `json:"volume"`
+	Balance          float64    `json:"balance"`
+	Unit             string     `json:"unit"`
+	Status           string     `json:"status"`
+	BalanceUpdatedAt *time.Time `json:"balance_updated_at"`
+}
+
+// GetQuotaStatus returns the status of the quota definition in force on date for a quota order number, as it was at
+// the end of that day. The order number may be given with or without its leading zero. It returns nil if no definition
+// is in force.
+//
+// The balance is the new balance of the latest balance event, or the definition volume if there is none.
+// A quota is exhausted if it has been exhausted and not reopened since, or if its balance is used up.
+// Otherwise it is critical if its latest critical event, or the definition itself, has the critical state "Y".
+func GetQuotaStatus(ctx context.Context, conn DB, orderNumber string, date time.Time) (*QuotaStatus, error) {
+	if orderNumber == "" {
+		return nil, errors.New("quota order number cannot be empty")
+	}
+
+	var (
+		status                  QuotaStatus
+		orderNumberInt          int
+		unit                    *string
+		definitionCriticalState *string
+		latestBalance           *float64
+		latestCriticalState     *string
+		exhaustedAt, reopenedAt *time.Time
+	)
+	err := conn.QueryRow(ctx, `
+	SELECT qd.sid,
+		qd.quota_order_number,
+		qd.date_start,
+		qd.date_end,
+		qd.initial_volume,
+		qd.volume,
+		COALESCE(qd.measurement_unit_code || COALESCE(qd.measurement_unit_qualifier_code, ''), qd.monetary_unit_code) AS unit,
+		qd.quota_critical_state_code,
+		be.new_balance,
+		be.occurrence_timestamp,
+		ce.quota_critical_state_code,
+		(
+			SELECT MAX(occurrence_timestamp)
+			FROM quota_exhaustion_event
+			WHERE sid_quota_definition = qd.sid
+				AND occurrence_timestamp < $2::DATE + 1
+		) AS exhausted_at,
+		(
+			SELECT MAX(occurrence_timestamp)
+			FROM quota_reopening_event
+			WHERE sid_quota_definition = qd.sid
+				AND occurrence_timestamp < $2::DATE + 1
+		) AS reopened_at
+	FROM quota_definition qd
+		LEFT JOIN LATERAL (
+			SELECT new_balance,
+				occurrence_timestamp
+			FROM quota_balance_event
+			WHERE sid_quota_definition = qd.sid
+				AND occurrence_timestamp < $2::DATE + 1
+			ORDER BY occurrence_timestamp DESC
+			LIMIT 1
+		) be ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT quota_critical_state_code
+			FROM quota_critical_event
+			WHERE sid_quota_definition = qd.sid
+				AND occurrence_timestamp < $2::DATE + 1
+			ORDER BY occurrence_timestamp DESC
+			LIMIT 1
+		) ce ON TRUE
+	WHERE qd.quota_order_number = $1::INT
+		AND qd.date_start <= $2::DATE
+		AND (
+			qd.date_end IS NULL
+			OR qd.date_end >= $2::DATE
+		)
+	ORDER BY qd.date_start DESC
+	LIMIT 1`, orderNumber, date).Scan(
+		&status.DefinitionSID, &orderNumberInt, &status.DateStart, &status.DateEnd, &status.InitialVolume, &status.Volume,
+		&unit, &definitionCriticalState, &latestBalance, &status.BalanceUpdatedAt, &latestCriticalState, &exhaustedAt, &reopenedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quota status: %w", err)
+	}
+
+	// Quota order numbers are six digits with a leading zero, but are stored as integers on measures and definitions
+	status.OrderNumber = fmt.Sprintf("%06d", orderNumberInt)
+	if unit != nil {
+		status.Unit = *unit
+	}
+
+	status.Balance = status.Volume
+	if latestBalance != nil {
+		status.Balance = *latestBalance
+	}
+
+	criticalState := definitionCriticalState
+	if latestCriticalState != nil {
+		criticalState = latestCriticalState
+	}
+
+	switch {
+	case exhaustedAt != nil && (reopenedAt == nil || reopenedAt.Before(*exhaustedAt)), status.Balance <= 0:
+		status.Status = QuotaExhausted
+	case criticalState != nil && *criticalState == "Y":
+		status.Status = QuotaCritical
+	default:
+		status.Status = QuotaOpen
+	}
+
+	return &status, nil
+}
+
+// GetMeasureQuotaStatus returns the status on date of the quota referenced by a measure.
+// It returns nil if the measure does not exist, is not a quota measure, or its quota has no definition in force.
+func GetMeasureQuotaStatus(ctx context.Context, conn DB, measureSID int, date time.Time) (*QuotaStatus, error) {
+	var orderNumber *int
+	err := conn.QueryRow(ctx, `SELECT quota_order_number FROM measure WHERE sid = $1`, measureSID).Scan(&orderNumber)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && orderNumber == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measure quota order number: %w", err)
+	}
+
+	return GetQuotaStatus(ctx, conn, fmt.Sprintf("%06d", *orderNumber), date)
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TaxCode is a national Swedish tax code used for VAT and excise duties.
+type TaxCode struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// GetTaxCodes returns the tax codes valid on date with their description in the given language, e.g. "SV" or "EN".
+// Codes without a description in that language are returned with an empty description.
+func GetTaxCodes(ctx context.Context, conn DB, languageID string, date time.Time) ([]TaxCode, error) {
+	rows, err := conn.Query(ctx, `
+	SELECT tc.tax_code,
+		COALESCE(tcd.description, '') AS description
+	FROM tax_code tc
+		LEFT JOIN tax_code_description tcd ON tc.sid = tcd.parent_sid
+		AND tcd.language_id = $1
+	WHERE (tc.date_start IS NULL OR tc.date_start <= $2::DATE)
+		AND (
+			tc.date_end IS NULL
+			OR tc.date_end >= $2::DATE
+		)
+	ORDER BY tc.tax_code`, languageID, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax codes: %w", err)
+	}
+	defer rows.Close()
+
+	var taxCodes []TaxCode
+	for rows.Next() {
+		var taxCode TaxCode
+		if err := rows.Scan(&taxCode.Code, &taxCode.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan tax code: %w", err)
+		}
+		taxCodes = append(taxCodes, taxCode)
+	}
+
+	return taxCodes, rows.Err()
+}
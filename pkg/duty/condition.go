@@ -0,0 +1,187 @@
+package duty
+
+import (
+	"sort"
+	"strings"
+)
+
+// Action is what a measure condition leads to when it is fulfilled, as classified from its measure action code.
+type Action string
+
+const (
+	// ActionApply applies the measure, with the duty of the condition if it has components
+	ActionApply Action = "apply"
+	// ActionNotApplicable means the measure does not apply to the goods
+	ActionNotApplicable Action = "not_applicable"
+	// ActionProhibited means the goods may not be imported or exported
+	ActionProhibited Action = "prohibited"
+	// ActionUnknown is an action code that is not classified, see the description of the action
+	ActionUnknown Action = "unknown"
+)
+
+// actions classifies the measure action codes of the distribution.
+var actions = map[string]Action{
+	"01": ActionApply,         // apply the amount of the action (see components)
+	"02": ActionApply,         // apply the difference between the amount of the action and the price at import
+	"03": ActionApply,         // apply the difference between the amount of the action and the CIF price
+	"04": ActionApply,         // the entry into free circulation is allowed
+	"05": ActionApply,         // export is allowed
+	"06": ActionProhibited,    // import/export not allowed after control
+	"07": ActionNotApplicable, // measure not applicable
+	"08": ActionProhibited,    // declared subheading not allowed
+	"09": ActionProhibited,    // import/export not allowed
+	"24": ActionApply,         // entry into free circulation allowed
+	"27": ActionApply,         // apply the mentioned duty
+	"28": ActionProhibited,    // declared subheading not allowed
+	"29": ActionApply,         // import/export allowed after control
+}
+
+// ClassifyAction returns the kind of action of a measure action code.
+func ClassifyAction(code string) Action {
+	if action, ok := actions[code]; ok {
+		return action
+	}
+	return ActionUnknown
+}
+
+// Condition is a measure condition: a requirement, such as presenting a certificate, and the action taken when it is met.
+// A condition without a certificate or threshold is always met, it is the action taken when no earlier condition is.
+type Condition struct {
+	Code              string      `json:"condition_code"`
+	SequenceNumber    int         `json:"sequence_number"`
+	Certificate       string      `json:"certificate"` // type and code, e.g. C400
+	Threshold         *Component  `json:"threshold"`   // amount, e.g. a price or quantity, the goods are compared to
+	ActionCode        string      `json:"action_code"`
+	Action            Action      `json:"action"`
+	ActionDescription string      `json:"action_description"`
+	Components        []Component `json:"components"` // duty applied by the action, if any
+	Duty              Text        `json:"duty"`
+}
+
+// ConditionGroup are the conditions of a measure with the same condition code. They are alternatives tried in
+// sequence, the first condition that is met decides the action.
+type ConditionGroup struct {
+	Code        string      `json:"condition_code"`
+	Description string      `json:"description"`
+	Conditions  []Condition `json:"conditions"`
+	Summary     Text        `json:"summary"` // e.g. "present C400 → apply; otherwise prohibited"
+}
+
+// GroupConditions groups the conditions of a measure by condition code and sorts them by sequence number.
+// The descriptions of condition codes are looked up in descriptions, by code, and thresholds are written with
+// the units of formatter.
+func GroupConditions(conditions []Condition, descriptions map[string]string, formatter *Formatter) []ConditionGroup {
+	groups := []ConditionGroup{}
+	index := map[string]int{}
+	for _, condition := range conditions {
+		i, ok := index[condition.Code]
+		if !ok {
+			i = len(groups)
+			index[condition.Code] = i
+			groups = append(groups, ConditionGroup{Code: condition.Code, Description: descriptions[condition.Code]})
+		}
+		groups[i].Conditions = append(groups[i].Conditions, condition)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Code < groups[j].Code })
+	for i := range groups {
+		sort.SliceStable(groups[i].Conditions, func(a, b int) bool {
+			return groups[i].Conditions[a].SequenceNumber < groups[i].Conditions[b].SequenceNumber
+		})
+		groups[i].Summary = Text{SV: groups[i].summary(formatter, LanguageSV), EN: groups[i].summary(formatter, LanguageEN)}
+	}
+
+	return groups
+}
+
+// Evaluate returns the condition that decides the action when the given certificates are presented: the first
+// condition in sequence whose certificate is presented, or that has no requirement. Thresholds cannot be evaluated
+// from certificates, so decided is false if a condition with a threshold is reached first and that condition is
+// returned. decided is also false if no condition is met.
+func (g ConditionGroup) Evaluate(certificates map[string]bool) (condition Condition, decided bool) {
+	for _, condition := range g.Conditions {
+		switch {
+		case condition.Threshold != nil:
+			return condition, false
+		case condition.Certificate == "" || certificates[condition.Certificate]:
+			return condition, true
+		}
+	}
+
+	return Condition{}, false
+}
+
+// summaryWords are the words conditions are summarized with, by language id.
+var summaryWords = map[string]map[string]string{
+	LanguageSV: {
+		"present":                   "uppvisa",
+		"threshold":                 "gränsvärde",
+		"otherwise":                 "annars",
+		string(ActionApply):         "tillämpas",
+		string(ActionNotApplicable): "tillämpas inte",
+		string(ActionProhibited):    "förbjuden",
+	},
+	LanguageEN: {
+		"present":                   "present",
+		"threshold":                 "threshold",
+		"otherwise":                 "otherwise",
+		string(ActionApply):         "apply",
+		string(ActionNotApplicable): "not applicable",
+		string(ActionProhibited):    "prohibited",
+	},
+}
+
+// summary writes the conditions of the group as a sentence in language.
+func (g ConditionGroup) summary(formatter *Formatter, language string) string {
+	words := summaryWords[language]
+
+	parts := make([]string, 0, len(g.Conditions))
+	for _, condition := range g.Conditions {
+		action := words[string(condition.Action)]
+		if condition.Action == ActionUnknown {
+			action = condition.ActionDescription
+			if action == "" {
+				action = condition.ActionCode
+			}
+		}
+		duty := condition.Duty.EN
+		if language == LanguageSV {
+			duty = condition.Duty.SV
+		}
+		if duty != "" && condition.Action == ActionApply {
+			action += ": " + duty
+		}
+
+		switch {
+		case condition.Certificate != "":
+			parts = append(parts, words["present"]+" "+condition.Certificate+" → "+action)
+		case condition.Threshold != nil:
+			parts = append(parts, words["threshold"]+" "+formatter.formatThreshold(*condition.Threshold, language)+" → "+action)
+		case len(parts) > 0:
+			parts = append(parts, words["otherwise"]+" "+action)
+		default:
+			parts = append(parts, action)
+		}
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// formatThreshold writes the amount of a condition, such as "1000 EUR / 100 kg" for a price or "500 kg" for a quantity.
+func (f *Formatter) formatThreshold(threshold Component, language string) string {
+	words := []string{formatAmount(threshold.Amount, language)}
+	if threshold.MonetaryUnit != "" {
+		words = append(words, threshold.MonetaryUnit)
+	}
+	if threshold.MeasurementUnit != "" {
+		if threshold.MonetaryUnit != "" {
+			words = append(words, "/")
+		}
+		words = append(words, describe(f.Units, threshold.MeasurementUnit, language))
+		if threshold.MeasurementUnitQualifier != "" {
+			words = append(words, "/", describe(f.Qualifiers, threshold.MeasurementUnitQualifier, language))
+		}
+	}
+
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,162 @@
+package duty
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyAction(t *testing.T) {
+	tests := map[string]Action{
+		"01": ActionApply,
+		"27": ActionApply,
+		"07": ActionNotApplicable,
+		"09": ActionProhibited,
+		"28": ActionProhibited,
+		"99": ActionUnknown,
+		"":   ActionUnknown,
+	}
+	for code, want := range tests {
+		if got := ClassifyAction(code); got != want {
+			t.Errorf("ClassifyAction(%q) = %s, want %s", code, got, want)
+		}
+	}
+}
+
+// condition returns a condition with the action of its action code.
+func condition(code string, sequence int, certificate, actionCode string) Condition {
+	return Condition{Code: code, SequenceNumber: sequence, Certificate: certificate, ActionCode: actionCode, Action: ClassifyAction(actionCode)}
+}
+
+func TestGroupConditions(t *testing.T) {
+	priceThreshold := condition("V", 1, "", "01")
+	priceThreshold.Threshold = &Component{Amount: 1000, MonetaryUnit: "EUR", MeasurementUnit: "DTN"}
+	priceThreshold.Duty = Text{SV: "0 %", EN: "0 %"}
+	belowThreshold := condition("V", 2, "", "01")
+	belowThreshold.Duty = Text{SV: "12,5 %", EN: "12.5 %"}
+	unknown := condition("Y", 1, "Y999", "98")
+	unknown.ActionDescription = "Särskild åtgärd"
+
+	conditions := []Condition{
+		condition("B", 2, "", "09"),
+		belowThreshold,
+		condition("Y", 2, "", "07"),
+		condition("B", 1, "C400", "01"),
+		unknown,
+		priceThreshold,
+	}
+	descriptions := map[string]string{"B": "Uppvisande av licens", "V": "Importpris"}
+
+	groups := GroupConditions(conditions, descriptions, testFormatter())
+
+	want := []struct {
+		code        string
+		description string
+		sequence    []int
+		summary     Text
+	}{
+		{
+			code:        "B",
+			description: "Uppvisande av licens",
+			sequence:    []int{1, 2},
+			summary: Text{
+				SV: "uppvisa C400 → tillämpas; annars förbjuden",
+				EN: "present C400 → apply; otherwise prohibited",
+			},
+		},
+		{
+			code:        "V",
+			description: "Importpris",
+			sequence:    []int{1, 2},
+			summary: Text{
+				SV: "gränsvärde 1000 EUR / 100 kg → tillämpas: 0 %; annars tillämpas: 12,5 %",
+				EN: "threshold 1000 EUR / 100 kg → apply: 0 %; otherwise apply: 12.5 %",
+			},
+		},
+		{
+			code:     "Y",
+			sequence: []int{1, 2},
+			summary: Text{
+				SV: "uppvisa Y999 → Särskild åtgärd; annars tillämpas inte",
+				EN: "present Y999 → Särskild åtgärd; otherwise not applicable",
+			},
+		},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("groups = %d, want %d", len(groups), len(want))
+	}
+	for i, group := range groups {
+		if group.Code != want[i].code || group.Description != want[i].description {
+			t.Errorf("group %d = %s %q, want %s %q", i, group.Code, group.Description, want[i].code, want[i].description)
+		}
+		sequence := []int{}
+		for _, condition := range group.Conditions {
+			sequence = append(sequence, condition.SequenceNumber)
+		}
+		if !reflect.DeepEqual(sequence, want[i].sequence) {
+			t.Errorf("group %s sequence = %v, want %v", group.Code, sequence, want[i].sequence)
+		}
+		if group.Summary != want[i].summary {
+			t.Errorf("group %s summary = %+v, want %+v", group.Code, group.Summary, want[i].summary)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	threshold := condition("V", 1, "", "01")
+	threshold.Threshold = &Component{Amount: 500, MeasurementUnit: "KGM"}
+
+	certificate := ConditionGroup{Code: "B", Conditions: []Condition{
+		condition("B", 1, "C400", "01"),
+		condition("B", 2, "N954", "27"),
+		condition("B", 3, "", "09"),
+	}}
+	exemption := ConditionGroup{Code: "Y", Conditions: []Condition{
+		condition("Y", 1, "Y900", "07"),
+		condition("Y", 2, "", "01"),
+	}}
+	certificateRequired := ConditionGroup{Code: "A", Conditions: []Condition{
+		condition("A", 1, "D008", "01"),
+	}}
+	priceThreshold := ConditionGroup{Code: "V", Conditions: []Condition{
+		threshold,
+		condition("V", 2, "", "01"),
+	}}
+	laterThreshold := threshold
+	laterThreshold.Code, laterThreshold.SequenceNumber = "E", 2
+	certificateBeforeThreshold := ConditionGroup{Code: "E", Conditions: []Condition{
+		condition("E", 1, "L001", "01"),
+		laterThreshold,
+	}}
+
+	tests := []struct {
+		name         string
+		group        ConditionGroup
+		certificates map[string]bool
+		wantSequence int // of the returned condition, 0 for none
+		wantAction   Action
+		wantDecided  bool
+	}{
+		{"certificate presented", certificate, map[string]bool{"C400": true}, 1, ActionApply, true},
+		{"later certificate presented", certificate, map[string]bool{"N954": true}, 2, ActionApply, true},
+		{"first certificate in sequence wins", certificate, map[string]bool{"N954": true, "C400": true}, 1, ActionApply, true},
+		{"no certificate prohibits", certificate, nil, 3, ActionProhibited, true},
+		{"unrelated certificate prohibits", certificate, map[string]bool{"Y900": true}, 3, ActionProhibited, true},
+		{"exemption makes measure not applicable", exemption, map[string]bool{"Y900": true}, 1, ActionNotApplicable, true},
+		{"without exemption the measure applies", exemption, nil, 2, ActionApply, true},
+		{"required certificate missing", certificateRequired, nil, 0, "", false},
+		{"threshold cannot be decided", priceThreshold, map[string]bool{"C400": true}, 1, ActionApply, false},
+		{"certificate before threshold", certificateBeforeThreshold, map[string]bool{"L001": true}, 1, ActionApply, true},
+		{"threshold reached without certificate", certificateBeforeThreshold, nil, 2, ActionApply, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, decided := tt.group.Evaluate(tt.certificates)
+			if decided != tt.wantDecided {
+				t.Errorf("decided = %v, want %v", decided, tt.wantDecided)
+			}
+			if got.SequenceNumber != tt.wantSequence || got.Action != tt.wantAction {
+				t.Errorf("condition = sequence %d %s, want sequence %d %s", got.SequenceNumber, got.Action, tt.wantSequence, tt.wantAction)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package duty
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Duty expression ids of measure components, see the duty_expression table.
+const (
+	ExprDuty                     = 1  // % or amount
+	ExprMinus                    = 2  // minus % or amount
+	ExprPlus                     = 4  // + % or amount
+	ExprAgriculturalComponent    = 12 // + agricultural component (EA)
+	ExprReducedAgricultural      = 14 // + reduced agricultural component (EAR)
+	ExprMinimum                  = 15 // minimum
+	ExprMaximum                  = 17 // maximum
+	ExprPlusAfterMinimum         = 19 // + % or amount
+	ExprPlusAfterMaximum         = 20 // + % or amount
+	ExprSugarDuty                = 21 // + additional duty on sugar (ADSZ)
+	ExprPlusTwoPercent           = 23 // + 2 %
+	ExprReducedSugarDuty         = 25 // + reduced additional duty on sugar (ADSZR)
+	ExprFlourDuty                = 27 // + additional duty on flour (ADFM)
+	ExprReducedFlourDuty         = 29 // + reduced additional duty on flour (ADFMR)
+	ExprMaximumAfterAgricultural = 35 // maximum
+	ExprMinusPercentCIF          = 36 // minus % CIF
+	ExprNothing                  = 37 // (nothing)
+	ExprSupplementaryUnit        = 99 // supplementary unit
+	firstExportRefundExpr        = 40 // export refunds for cereals, rice, eggs, sugar and milk products
+	lastExportRefundExpr         = 44
+)
+
+var (
+	// ErrMissingQuantity is returned when a specific duty is expressed in a unit no quantity is given for.
+	ErrMissingQuantity = errors.New("missing quantity")
+	// ErrMissingAgriculturalComponent is returned when a measure has an agricultural component or additional duty
+	// on sugar or flour that is not given. Those depend on the composition of the goods, see the Meursing table.
+	ErrMissingAgriculturalComponent = errors.New("missing agricultural component")
+	// ErrMissingExchangeRate is returned when an amount cannot be converted to the currency of the goods.
+	ErrMissingExchangeRate = errors.New("missing exchange rate")
+	// ErrUnsupportedExpression is returned for duty expressions that are not import duties, such as export refunds.
+	ErrUnsupportedExpression = errors.New("unsupported duty expression")
+)
+
+// Component is a measure component, one part of the duty expression of a measure.
+type Component struct {
+	DutyExpressionID int `json:"duty_expression_id"`
+	// Amount is a percentage of the customs value if MonetaryUnit is empty, otherwise an amount of MonetaryUnit
+	// per MeasurementUnit, or a fixed amount if MeasurementUnit is empty as well.
+	Amount                   float64 `json:"amount"`
+	MonetaryUnit             string  `json:"monetary_unit"`              // e.g. EUR
+	MeasurementUnit          string  `json:"measurement_unit"`           // e.g. DTN for 100 kg
+	MeasurementUnitQualifier string  `json:"measurement_unit_qualifier"` // e.g. E for net drained weight
+}
+
+// AdValorem reports whether the component is a percentage of the customs value.
+func (c Component) AdValorem() bool {
+	return c.MonetaryUnit == ""
+}
+
+// Converter converts amounts between monetary units, such as EUR and SEK, at the rate of a given date.
+type Converter interface {
+	Convert(amount float64, from, to string) (float64, error)
+}
+
+// Goods are the goods a duty is calculated for.
+type Goods struct {
+	CustomsValue float64
+	Currency     string // currency of CustomsValue and of the calculated duty, e.g. SEK
+	// Quantities per measurement unit, with the qualifier appended if any, e.g. KGM for the net mass in kg or DTNE
+	// for the net drained weight in 100 kg. Quantities in related units are derived, see Quantity.
+	Quantities Quantities
+	// AgriculturalComponents are the amounts, in Currency, of agricultural components and additional duties on sugar
+	// and flour by duty expression id. They are looked up in the Meursing table by the caller.
+	AgriculturalComponents map[int]float64
+}
+
+// Line is the outcome of one component in a calculation.
+type Line struct {
+	Component Component `json:"component"`
+	// Amount is what the component adds to the duty, negative for deductions and for a maximum that was applied.
+	// The components adding to a minimum or maximum add nothing themselves, the limit adds the difference.
+	Amount float64 `json:"amount"`
+	// Duty is the duty after the component
+	Duty float64 `json:"duty"`
+	// Applied is false for a minimum or maximum that did not change the duty, and the components adding to it,
+	// and for components without a duty
+	Applied bool `json:"applied"`
+}
+
+// Result is a calculated duty and how it was arrived at.
+type Result struct {
+	Duty     float64 `json:"duty"` // in the currency of the goods, not rounded
+	Currency string  `json:"currency"`
+	Lines    []Line  `json:"lines"`
+}
+
+// Calculate evaluates the components of a measure for goods.
+//
+// Components are applied in the order they are written in: percentages and specific amounts are added to the duty
+// and deductions subtracted. A minimum or maximum, together with the components written after it up to the next
+// minimum or maximum, make up a limit on the duty of the components before it, e.g. "20 % MAX 50 EUR + 5 EUR" limits
+// the duty to 55 EUR and "MAX 18,7 % + ADSZ" to 18,7 % and the additional duty on sugar. Amounts in other currencies
+// than the goods are converted with converter, which may be nil if all amounts are in the currency of the goods.
+func Calculate(components []Component, goods Goods, converter Converter) (*Result, error) {
+	result := &Result{Currency: goods.Currency, Lines: make([]Line, 0, len(components))}
+	for i := 0; i < len(components); i++ {
+		if !isLimit(components[i].DutyExpressionID) {
+			amount, err := addedAmount(components[i], goods, converter)
+			if err != nil {
+				return nil, err
+			}
+			result.Duty += amount
+			result.Lines = append(result.Lines, Line{Component: components[i], Amount: amount, Duty: result.Duty, Applied: isDuty(components[i].DutyExpressionID)})
+			continue
+		}
+
+		// The limit and the components adding to it
+		limit, err := componentAmount(components[i], goods, converter)
+		if err != nil {
+			return nil, err
+		}
+		end := i + 1
+		for ; end < len(components) && !isLimit(components[end].DutyExpressionID); end++ {
+			amount, err := addedAmount(components[end], goods, converter)
+			if err != nil {
+				return nil, err
+			}
+			limit += amount
+		}
+
+		id := components[i].DutyExpressionID
+		line := Line{Component: components[i]}
+		if (id == ExprMinimum && result.Duty < limit) || (id != ExprMinimum && result.Duty > limit) {
+			line.Amount = limit - result.Duty
+			line.Applied = true
+		}
+		result.Duty += line.Amount
+		line.Duty = result.Duty
+		result.Lines = append(result.Lines, line)
+
+		for _, component := range components[i+1 : end] {
+			result.Lines = append(result.Lines, Line{Component: component, Duty: result.Duty, Applied: line.Applied})
+		}
+		i = end - 1
+	}
+
+	return result, nil
+}
+
+// isLimit reports whether a duty expression is a minimum or maximum.
+func isLimit(id int) bool {
+	return id == ExprMinimum || id == ExprMaximum || id == ExprMaximumAfterAgricultural
+}
+
+// isDuty reports whether a duty expression adds to the duty, as opposed to (nothing) and supplementary units.
+func isDuty(id int) bool {
+	return id != ExprNothing && id != ExprSupplementaryUnit
+}
+
+// addedAmount returns what a component other than a minimum or maximum adds to the duty of goods.
+func addedAmount(component Component, goods Goods, converter Converter) (float64, error) {
+	switch id := component.DutyExpressionID; {
+	case isAgricultural(id):
+		amount, ok := goods.AgriculturalComponents[id]
+		if !ok {
+			return 0, fmt.Errorf("%w: duty expression %02d", ErrMissingAgriculturalComponent, id)
+		}
+		return amount, nil
+
+	case id == ExprMinus || id == ExprMinusPercentCIF:
+		amount, err := componentAmount(component, goods, converter)
+		return -amount, err
+
+	case !isDuty(id):
+		// The measure has no duty, or only requires the quantity to be declared in a supplementary unit
+		return 0, nil
+
+	case id >= firstExportRefundExpr && id <= lastExportRefundExpr:
+		return 0, fmt.Errorf("%w: duty expression %02d is an export refund", ErrUnsupportedExpression, id)
+
+	default:
+		return componentAmount(component, goods, converter)
+	}
+}
+
+// isAgricultural reports whether a duty expression is an amount looked up in the Meursing table.
+func isAgricultural(id int) bool {
+	switch id {
+	case ExprAgriculturalComponent, ExprReducedAgricultural, ExprSugarDuty, ExprReducedSugarDuty, ExprFlourDuty, ExprReducedFlourDuty:
+		return true
+	}
+	return false
+}
+
+// componentAmount returns the amount of a component for goods, in the currency of the goods.
+func componentAmount(component Component, goods Goods, converter Converter) (float64, error) {
+	if component.AdValorem() {
+		return goods.CustomsValue * component.Amount / 100, nil
+	}
+
+	amount := component.Amount
+	if component.MeasurementUnit != "" {
+		quantity, ok := goods.Quantities.Quantity(component.MeasurementUnit, component.MeasurementUnitQualifier)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s%s", ErrMissingQuantity, component.MeasurementUnit, component.MeasurementUnitQualifier)
+		}
+		amount *= quantity
+	}
+
+	if component.MonetaryUnit == goods.Currency {
+		return amount, nil
+	}
+	if converter == nil {
+		return 0, fmt.Errorf("%w: unable to convert %s to %s", ErrMissingExchangeRate, component.MonetaryUnit, goods.Currency)
+	}
+
+	converted, err := converter.Convert(amount, component.MonetaryUnit, goods.Currency)
+	if err != nil {
+		return 0, fmt.Errorf("unable to convert %s to %s: %w", component.MonetaryUnit, goods.Currency, err)
+	}
+
+	return converted, nil
+}
@@ -0,0 +1,215 @@
+package duty
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// rates converts between monetary units at fixed rates, by the units joined like EURSEK.
+type rates map[string]float64
+
+func (r rates) Convert(amount float64, from, to string) (float64, error) {
+	rate, ok := r[from+to]
+	if !ok {
+		return 0, fmt.Errorf("no rate from %s to %s", from, to)
+	}
+	return amount * rate, nil
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCalculate(t *testing.T) {
+	percent := func(id int, amount float64) Component {
+		return Component{DutyExpressionID: id, Amount: amount}
+	}
+	perUnit := func(id int, amount float64, monetaryUnit, unit string) Component {
+		return Component{DutyExpressionID: id, Amount: amount, MonetaryUnit: monetaryUnit, MeasurementUnit: unit}
+	}
+
+	tests := []struct {
+		name       string
+		components []Component
+		goods      Goods
+		converter  Converter
+		want       float64
+		// applied tells for each line, in the order of the components, whether it was applied
+		applied []bool
+		wantErr error
+	}{
+		{
+			name:       "ad valorem",
+			components: []Component{percent(ExprDuty, 12.8)},
+			goods:      Goods{CustomsValue: 1000, Currency: "EUR"},
+			want:       128,
+			applied:    []bool{true},
+		},
+		{
+			name:       "specific duty in a derived unit",
+			components: []Component{perUnit(ExprDuty, 176.8, "EUR", "DTN")},
+			goods:      Goods{Currency: "EUR", Quantities: Quantities{"KGM": 250}},
+			want:       442,
+			applied:    []bool{true},
+		},
+		{
+			name:       "minimum applied",
+			components: []Component{percent(ExprDuty, 5), perUnit(ExprMinimum, 22, "EUR", "DTN")},
+			goods:      Goods{CustomsValue: 100, Currency: "EUR", Quantities: Quantities{"KGM": 100}},
+			want:       22,
+			applied:    []bool{true, true},
+		},
+		{
+			name:       "minimum not applied",
+			components: []Component{percent(ExprDuty, 5), perUnit(ExprMinimum, 22, "EUR", "DTN")},
+			goods:      Goods{CustomsValue: 1000, Currency: "EUR", Quantities: Quantities{"KGM": 100}},
+			want:       50,
+			applied:    []bool{true, false},
+		},
+		{
+			// The plus is part of the maximum, which is 55 EUR. Adding it to the duty after a maximum of 50 EUR would give 55.
+			name:       "maximum includes the plus after it",
+			components: []Component{percent(ExprDuty, 20), perUnit(ExprMaximum, 50, "EUR", ""), perUnit(ExprPlusAfterMaximum, 5, "EUR", "")},
+			goods:      Goods{CustomsValue: 260, Currency: "EUR"},
+			want:       52,
+			applied:    []bool{true, false, false},
+		},
+		{
+			name:       "maximum with plus applied",
+			components: []Component{percent(ExprDuty, 20), perUnit(ExprMaximum, 50, "EUR", ""), perUnit(ExprPlusAfterMaximum, 5, "EUR", "")},
+			goods:      Goods{CustomsValue: 1000, Currency: "EUR"},
+			want:       55,
+			applied:    []bool{true, true, true},
+		},
+		{
+			// The minimum is 12 EUR. Adding the plus to the duty after a minimum of 10 EUR would give 13.
+			name:       "minimum includes the plus after it",
+			components: []Component{percent(ExprDuty, 20), perUnit(ExprMinimum, 10, "EUR", ""), perUnit(ExprPlusAfterMinimum, 2, "EUR", "")},
+			goods:      Goods{CustomsValue: 55, Currency: "EUR"},
+			want:       12,
+			applied:    []bool{true, true, true},
+		},
+		{
+			// The maximum is 18,7 % and the additional duty on sugar, 217 EUR. Adding the sugar duty after a
+			// maximum of 187 EUR would give 210.
+			name: "maximum includes the additional duty on sugar",
+			components: []Component{percent(ExprDuty, 8), {DutyExpressionID: ExprAgriculturalComponent},
+				percent(ExprMaximum, 18.7), {DutyExpressionID: ExprSugarDuty}},
+			goods: Goods{CustomsValue: 1000, Currency: "EUR",
+				AgriculturalComponents: map[int]float64{ExprAgriculturalComponent: 100, ExprSugarDuty: 30}},
+			want:    180,
+			applied: []bool{true, true, false, false},
+		},
+		{
+			name:       "minimum and maximum",
+			components: []Component{percent(ExprDuty, 10), perUnit(ExprMinimum, 5, "EUR", ""), perUnit(ExprMaximum, 20, "EUR", "")},
+			goods:      Goods{CustomsValue: 300, Currency: "EUR"},
+			want:       20,
+			applied:    []bool{true, false, true},
+		},
+		{
+			name:       "agricultural component of a maximum missing",
+			components: []Component{percent(ExprDuty, 8), percent(ExprMaximum, 18.7), {DutyExpressionID: ExprSugarDuty}},
+			goods:      Goods{CustomsValue: 1000, Currency: "EUR"},
+			wantErr:    ErrMissingAgriculturalComponent,
+		},
+		{
+			name:       "maximum not applied",
+			components: []Component{percent(ExprDuty, 2), perUnit(ExprMaximum, 50, "EUR", "")},
+			goods:      Goods{CustomsValue: 1000, Currency: "EUR"},
+			want:       20,
+			applied:    []bool{true, false},
+		},
+		{
+			name:       "deduction",
+			components: []Component{percent(ExprDuty, 10), perUnit(ExprMinus, 30, "EUR", "")},
+			goods:      Goods{CustomsValue: 1000, Currency: "EUR"},
+			want:       70,
+			applied:    []bool{true, true},
+		},
+		{
+			name:       "agricultural component given",
+			components: []Component{percent(ExprDuty, 9), {DutyExpressionID: ExprAgriculturalComponent}, perUnit(ExprMaximumAfterAgricultural, 24.2, "EUR", "DTN")},
+			goods: Goods{CustomsValue: 1000, Currency: "EUR", Quantities: Quantities{"KGM": 1000},
+				AgriculturalComponents: map[int]float64{ExprAgriculturalComponent: 40}},
+			want:    130,
+			applied: []bool{true, true, false},
+		},
+		{
+			name:       "agricultural component missing",
+			components: []Component{percent(ExprDuty, 9), {DutyExpressionID: ExprSugarDuty}},
+			goods:      Goods{CustomsValue: 1000, Currency: "EUR"},
+			wantErr:    ErrMissingAgriculturalComponent,
+		},
+		{
+			name:       "quantity missing",
+			components: []Component{perUnit(ExprDuty, 176.8, "EUR", "DTN")},
+			goods:      Goods{Currency: "EUR", Quantities: Quantities{"LTR": 10}},
+			wantErr:    ErrMissingQuantity,
+		},
+		{
+			name:       "converted to the currency of the goods",
+			components: []Component{percent(ExprDuty, 10), perUnit(ExprPlus, 2, "EUR", "DTN")},
+			goods:      Goods{CustomsValue: 1000, Currency: "SEK", Quantities: Quantities{"KGM": 500}},
+			converter:  rates{"EURSEK": 11},
+			want:       210,
+			applied:    []bool{true, true},
+		},
+		{
+			name:       "no converter",
+			components: []Component{perUnit(ExprDuty, 2, "EUR", "")},
+			goods:      Goods{Currency: "SEK"},
+			wantErr:    ErrMissingExchangeRate,
+		},
+		{
+			name:       "no duty",
+			components: []Component{{DutyExpressionID: ExprNothing}},
+			goods:      Goods{CustomsValue: 1000, Currency: "EUR"},
+			want:       0,
+			applied:    []bool{false},
+		},
+		{
+			name:       "export refund",
+			components: []Component{perUnit(firstExportRefundExpr, 10, "EUR", "TNE")},
+			goods:      Goods{Currency: "EUR", Quantities: Quantities{"TNE": 1}},
+			wantErr:    ErrUnsupportedExpression,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Calculate(tt.components, tt.goods, tt.converter)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Calculate: %v", err)
+			}
+
+			if !approxEqual(result.Duty, tt.want) {
+				t.Errorf("duty = %v, want %v", result.Duty, tt.want)
+			}
+			if result.Currency != tt.goods.Currency {
+				t.Errorf("currency = %s, want %s", result.Currency, tt.goods.Currency)
+			}
+			if len(result.Lines) != len(tt.applied) {
+				t.Fatalf("lines = %d, want %d", len(result.Lines), len(tt.applied))
+			}
+			for i, line := range result.Lines {
+				if line.Applied != tt.applied[i] {
+					t.Errorf("line %d with duty expression %02d applied = %v, want %v", i, line.Component.DutyExpressionID, line.Applied, tt.applied[i])
+				}
+				if line.Component != tt.components[i] {
+					t.Errorf("line %d has component %+v, want the components in the order they are written", i, line.Component)
+				}
+			}
+			if last := result.Lines[len(result.Lines)-1]; !approxEqual(last.Duty, result.Duty) {
+				t.Errorf("duty of the last line = %v, want the duty %v", last.Duty, result.Duty)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package duty
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Languages duty expressions are rendered in, as the language ids of the distribution.
+const (
+	LanguageSV = "SV"
+	LanguageEN = "EN"
+)
+
+// Applicability tells whether a part of a measure component, such as the duty amount, is used by a duty expression.
+type Applicability int
+
+const (
+	Permitted    Applicability = 0
+	Mandatory    Applicability = 1
+	NotPermitted Applicability = 2
+)
+
+// Expression is a duty expression as described by the duty_expression tables.
+type Expression struct {
+	DutyAmount      Applicability
+	MonetaryUnit    Applicability
+	MeasurementUnit Applicability
+	Descriptions    map[string]string // by language id
+}
+
+// Formatter renders measure components as duty expressions, e.g. "12,8 % + 176,8 EUR / 100 kg MIN 22 EUR / 100 kg".
+type Formatter struct {
+	Expressions map[int]Expression           // by duty expression id
+	Units       map[string]map[string]string // descriptions by measurement unit code and language id
+	Qualifiers  map[string]map[string]string // descriptions by measurement unit qualifier code and language id
+	// If Converter is set, amounts in other monetary units than Currency are followed by their value in Currency,
+	// e.g. "176,8 EUR (1950,45 SEK) / 100 kg". Amounts that cannot be converted are written as they are.
+	Converter Converter
+	Currency  string
+}
+
+// Text is a duty expression in Swedish and English.
+type Text struct {
+	SV string `json:"sv"`
+	EN string `json:"en"`
+}
+
+// operators are the symbols duty expressions are written with, by duty expression id. They are the same in every
+// language. Expressions without an operator are written with their description.
+var operators = map[int]string{
+	ExprDuty:                     "",
+	ExprMinus:                    "-",
+	ExprPlus:                     "+",
+	ExprAgriculturalComponent:    "+ EA",
+	ExprReducedAgricultural:      "+ EAR",
+	ExprMinimum:                  "MIN",
+	ExprMaximum:                  "MAX",
+	ExprPlusAfterMinimum:         "+",
+	ExprPlusAfterMaximum:         "+",
+	ExprSugarDuty:                "+ ADSZ",
+	ExprPlusTwoPercent:           "+ 2 %",
+	ExprReducedSugarDuty:         "+ ADSZR",
+	ExprFlourDuty:                "+ ADFM",
+	ExprReducedFlourDuty:         "+ ADFMR",
+	ExprMaximumAfterAgricultural: "MAX",
+	ExprMinusPercentCIF:          "-",
+	ExprNothing:                  "NIHIL",
+	ExprSupplementaryUnit:        "",
+}
+
+// Text renders components in Swedish and English.
+func (f *Formatter) Text(components []Component) Text {
+	return Text{SV: f.Format(components, LanguageSV), EN: f.Format(components, LanguageEN)}
+}
+
+// Format renders components in the language with the given id, in the order they are given, which should be the
+// order they are written in. The amount, monetary unit and measurement unit of a component are left out where its
+// duty expression does not permit them.
+func (f *Formatter) Format(components []Component, language string) string {
+	parts := make([]string, 0, len(components))
+	for _, component := range components {
+		if part := f.formatComponent(component, language); part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// formatComponent renders a single component.
+func (f *Formatter) formatComponent(component Component, language string) string {
+	// Expressions missing from the database are rendered with every part the component has
+	expression, known := f.Expressions[component.DutyExpressionID]
+
+	operator, ok := operators[component.DutyExpressionID]
+	if !ok {
+		operator = expression.Descriptions[language]
+	}
+
+	words := []string{}
+	if operator != "" {
+		words = append(words, operator)
+	}
+
+	withAmount := !known || expression.DutyAmount != NotPermitted
+	if withAmount {
+		words = append(words, formatAmount(component.Amount, language))
+		if component.AdValorem() {
+			words = append(words, "%")
+		}
+	}
+	if component.MonetaryUnit != "" && (!known || expression.MonetaryUnit != NotPermitted) {
+		words = append(words, component.MonetaryUnit)
+		if withAmount && f.Converter != nil && f.Currency != "" && component.MonetaryUnit != f.Currency {
+			if converted, err := f.Converter.Convert(component.Amount, component.MonetaryUnit, f.Currency); err == nil {
+				words = append(words, "("+formatAmount(math.Round(converted*100)/100, language), f.Currency+")")
+			}
+		}
+	}
+	if component.MeasurementUnit != "" && (!known || expression.MeasurementUnit != NotPermitted) {
+		// A unit without an amount, such as a supplementary unit, is written on its own
+		if withAmount {
+			words = append(words, "/")
+		}
+		words = append(words, describe(f.Units, component.MeasurementUnit, language))
+		if component.MeasurementUnitQualifier != "" {
+			words = append(words, "/", describe(f.Qualifiers, component.MeasurementUnitQualifier, language))
+		}
+	}
+	if component.DutyExpressionID == ExprMinusPercentCIF {
+		words = append(words, "CIF")
+	}
+
+	return strings.Join(words, " ")
+}
+
+// describe returns the description of code in language, falling back to English and then to the code.
+func describe(descriptions map[string]map[string]string, code, language string) string {
+	if description := descriptions[code][language]; description != "" {
+		return description
+	}
+	if description := descriptions[code][LanguageEN]; description != "" {
+		return description
+	}
+	return code
+}
+
+// formatAmount writes amount with as many decimals as needed, with a decimal comma in Swedish.
+func formatAmount(amount float64, language string) string {
+	formatted := strconv.FormatFloat(amount, 'f', -1, 64)
+	if language == LanguageSV {
+		formatted = strings.Replace(formatted, ".", ",", 1)
+	}
+	return formatted
+}
@@ -0,0 +1,130 @@
+package duty
+
+import "testing"
+
+// testFormatter describes the duty expressions and units used in the tests like the database does.
+func testFormatter() *Formatter {
+	return &Formatter{
+		Expressions: map[int]Expression{
+			ExprDuty:              {DutyAmount: Mandatory, MonetaryUnit: Permitted, MeasurementUnit: Permitted},
+			ExprPlus:              {DutyAmount: Mandatory, MonetaryUnit: Permitted, MeasurementUnit: Permitted},
+			ExprMinimum:           {DutyAmount: Mandatory, MonetaryUnit: Permitted, MeasurementUnit: Permitted},
+			ExprMinusPercentCIF:   {DutyAmount: Mandatory, MonetaryUnit: NotPermitted, MeasurementUnit: NotPermitted},
+			ExprNothing:           {DutyAmount: NotPermitted, MonetaryUnit: NotPermitted, MeasurementUnit: NotPermitted},
+			ExprSupplementaryUnit: {DutyAmount: NotPermitted, MonetaryUnit: NotPermitted, MeasurementUnit: Mandatory},
+		},
+		Units: map[string]map[string]string{
+			"DTN": {LanguageSV: "100 kg", LanguageEN: "100 kg"},
+			"NAR": {LanguageSV: "st", LanguageEN: "p/st"},
+			"HLT": {LanguageEN: "hl"},
+		},
+		Qualifiers: map[string]map[string]string{
+			"E": {LanguageSV: "avrunnen nettovikt", LanguageEN: "net drained weight"},
+		},
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []Component
+		converter  Converter
+		want       Text
+	}{
+		{
+			name:       "ad valorem with decimals",
+			components: []Component{{DutyExpressionID: ExprDuty, Amount: 12.8}},
+			want:       Text{SV: "12,8 %", EN: "12.8 %"},
+		},
+		{
+			name:       "whole percentage",
+			components: []Component{{DutyExpressionID: ExprDuty, Amount: 5}},
+			want:       Text{SV: "5 %", EN: "5 %"},
+		},
+		{
+			name: "compound with minimum",
+			components: []Component{
+				{DutyExpressionID: ExprDuty, Amount: 12.8},
+				{DutyExpressionID: ExprPlus, Amount: 176.8, MonetaryUnit: "EUR", MeasurementUnit: "DTN"},
+				{DutyExpressionID: ExprMinimum, Amount: 22, MonetaryUnit: "EUR", MeasurementUnit: "DTN"},
+			},
+			want: Text{
+				SV: "12,8 % + 176,8 EUR / 100 kg MIN 22 EUR / 100 kg",
+				EN: "12.8 % + 176.8 EUR / 100 kg MIN 22 EUR / 100 kg",
+			},
+		},
+		{
+			name:       "unit with qualifier",
+			components: []Component{{DutyExpressionID: ExprDuty, Amount: 7.5, MonetaryUnit: "EUR", MeasurementUnit: "DTN", MeasurementUnitQualifier: "E"}},
+			want:       Text{SV: "7,5 EUR / 100 kg / avrunnen nettovikt", EN: "7.5 EUR / 100 kg / net drained weight"},
+		},
+		{
+			name:       "unit described in English only",
+			components: []Component{{DutyExpressionID: ExprDuty, Amount: 0.5, MonetaryUnit: "EUR", MeasurementUnit: "HLT"}},
+			want:       Text{SV: "0,5 EUR / hl", EN: "0.5 EUR / hl"},
+		},
+		{
+			name:       "unit without description",
+			components: []Component{{DutyExpressionID: ExprDuty, Amount: 1, MonetaryUnit: "EUR", MeasurementUnit: "KPP"}},
+			want:       Text{SV: "1 EUR / KPP", EN: "1 EUR / KPP"},
+		},
+		{
+			name:       "no duty",
+			components: []Component{{DutyExpressionID: ExprNothing}},
+			want:       Text{SV: "NIHIL", EN: "NIHIL"},
+		},
+		{
+			name:       "supplementary unit",
+			components: []Component{{DutyExpressionID: ExprSupplementaryUnit, MeasurementUnit: "NAR"}},
+			want:       Text{SV: "st", EN: "p/st"},
+		},
+		{
+			name:       "percentage of CIF",
+			components: []Component{{DutyExpressionID: ExprMinusPercentCIF, Amount: 3}},
+			want:       Text{SV: "- 3 % CIF", EN: "- 3 % CIF"},
+		},
+		{
+			name:       "converted amount",
+			components: []Component{{DutyExpressionID: ExprDuty, Amount: 176.8, MonetaryUnit: "EUR", MeasurementUnit: "DTN"}},
+			converter:  rates{"EURSEK": 11.0339},
+			want:       Text{SV: "176,8 EUR (1950,79 SEK) / 100 kg", EN: "176.8 EUR (1950.79 SEK) / 100 kg"},
+		},
+		{
+			name:       "amount without rate",
+			components: []Component{{DutyExpressionID: ExprDuty, Amount: 2, MonetaryUnit: "USD"}},
+			converter:  rates{"EURSEK": 11.0339},
+			want:       Text{SV: "2 USD", EN: "2 USD"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := testFormatter()
+			if tt.converter != nil {
+				formatter.Converter, formatter.Currency = tt.converter, "SEK"
+			}
+
+			if got := formatter.Text(tt.components); got != tt.want {
+				t.Errorf("Text = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		language string
+		want     string
+	}{
+		{1950.79, LanguageSV, "1950,79"},
+		{1950.79, LanguageEN, "1950.79"},
+		{0.125, LanguageSV, "0,125"},
+		{100, LanguageSV, "100"},
+		{-3.5, LanguageSV, "-3,5"},
+	}
+	for _, tt := range tests {
+		if got := formatAmount(tt.amount, tt.language); got != tt.want {
+			t.Errorf("formatAmount(%v, %s) = %q, want %q", tt.amount, tt.language, got, tt.want)
+		}
+	}
+}
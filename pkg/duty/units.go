@@ -0,0 +1,66 @@
+package duty
+
+import "sort"
+
+// Quantities are quantities of goods by measurement unit code with the qualifier code appended, e.g. KGM, DTNE or HLT.
+type Quantities map[string]float64
+
+// derivedUnits are the measurement units a quantity can be converted to from a base unit, by the factor base
+// quantities per unit. A duty per 100 kg (DTN) can thus be calculated from the net mass in kg (KGM).
+var derivedUnits = map[string]struct {
+	base   string
+	factor float64
+}{
+	"DTN": {"KGM", 100},
+	"TNE": {"KGM", 1000},
+	"GRM": {"KGM", 0.001},
+	"HLT": {"LTR", 100},
+	"MIL": {"NAR", 1000},
+}
+
+// derivedUnitCodes are the codes of derivedUnits in sorted order.
+var derivedUnitCodes = func() []string {
+	codes := make([]string, 0, len(derivedUnits))
+	for code := range derivedUnits {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}()
+
+// Quantity returns the quantity in unit with qualifier, deriving it from a related unit with the same qualifier
+// if it was not given. ok is false if neither the unit nor a related unit was given. If several related units are
+// given, the first in order of unit code is used.
+func (q Quantities) Quantity(unit, qualifier string) (quantity float64, ok bool) {
+	if quantity, ok := q[unit+qualifier]; ok {
+		return quantity, true
+	}
+
+	// Try the base unit of unit, and then every unit derived from the same base
+	base := unit
+	if derived, ok := derivedUnits[unit]; ok {
+		base = derived.base
+	}
+
+	baseQuantity, ok := q[base+qualifier]
+	if !ok {
+		for _, other := range derivedUnitCodes {
+			derived := derivedUnits[other]
+			if other == unit || derived.base != base {
+				continue
+			}
+			if quantity, found := q[other+qualifier]; found {
+				baseQuantity, ok = quantity*derived.factor, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+
+	if derived, isDerived := derivedUnits[unit]; isDerived {
+		return baseQuantity / derived.factor, true
+	}
+	return baseQuantity, true
+}
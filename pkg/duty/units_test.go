@@ -0,0 +1,38 @@
+package duty
+
+import "testing"
+
+func TestQuantity(t *testing.T) {
+	tests := []struct {
+		name       string
+		quantities Quantities
+		unit       string
+		qualifier  string
+		want       float64
+		wantOK     bool
+	}{
+		{"given", Quantities{"DTN": 2}, "DTN", "", 2, true},
+		{"derived from base", Quantities{"KGM": 250}, "DTN", "", 2.5, true},
+		{"base from derived", Quantities{"TNE": 1.5}, "KGM", "", 1500, true},
+		{"derived from related", Quantities{"TNE": 1}, "DTN", "", 10, true},
+		{"litres", Quantities{"LTR": 50}, "HLT", "", 0.5, true},
+		{"pieces", Quantities{"NAR": 2500}, "MIL", "", 2.5, true},
+		{"with qualifier", Quantities{"KGME": 300}, "DTN", "E", 3, true},
+		{"qualifier not given", Quantities{"KGM": 300}, "DTN", "E", 0, false},
+		{"unrelated unit", Quantities{"LTR": 1}, "KGM", "", 0, false},
+		{"unit without derivations", Quantities{"KGM": 1}, "KPP", "", 0, false},
+		{"nothing given", Quantities{}, "DTN", "", 0, false},
+		// Inconsistent quantities give the same result every time, from the first related unit by code
+		{"first related unit", Quantities{"TNE": 1, "GRM": 1000}, "DTN", "", 0.01, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				got, ok := tt.quantities.Quantity(tt.unit, tt.qualifier)
+				if ok != tt.wantOK || !approxEqual(got, tt.want) {
+					t.Fatalf("Quantity(%q, %q) = %v, %v, want %v, %v", tt.unit, tt.qualifier, got, ok, tt.want, tt.wantOK)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package filedist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Cache is an on-disk cache of imported distribution files, so the database can be rebuilt without downloading them again.
+// Contents are stored by their sha256 checksum in blobs/, and every cached file has an entry in files/<dir>/ naming
+// the checksums of its raw and decrypted content.
+type Cache struct {
+	Dir string
+}
+
+// cacheEntry describes a cached distribution file.
+type cacheEntry struct {
+	Size        int64  `json:"size"`
+	Checksum    string `json:"checksum"`     // sha256 of the file as downloaded
+	XMLChecksum string `json:"xml_checksum"` // sha256 of the decrypted and decompressed xml
+}
+
+// NewCache returns a cache stored in dir, creating the directory if needed.
+func NewCache(dir string) (*Cache, error) {
+	for _, sub := range []string{"blobs", "tmp", filepath.Join("files", TotDir), filepath.Join("files", DifDir)} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("unable to create cache directory: %w", err)
+		}
+	}
+
+	return &Cache{Dir: dir}, nil
+}
+
+// Files returns the names of the files cached from the distribution directory dir.
+func (c *Cache) Files(dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(c.Dir, "files", dir))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list cached %s files: %w", dir, err)
+	}
+
+	fileList := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			fileList = append(fileList, name)
+		}
+	}
+
+	return fileList, nil
+}
+
+// Has reports whether the file name from dir is cached.
+func (c *Cache) Has(dir, name string) bool {
+	_, err := os.Stat(c.entryPath(dir, name))
+	return err == nil
+}
+
+// entry returns the cache entry of the file name from dir.
+func (c *Cache) entry(dir, name string) (cacheEntry, error) {
+	var entry cacheEntry
+	content, err := os.ReadFile(c.entryPath(dir, name))
+	if err != nil {
+		return entry, err
+	}
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return entry, fmt.Errorf("invalid cache entry for %s: %w", name, err)
+	}
+
+	return entry, nil
+}
+
+// store commits the raw and xml content of the file name from dir to the cache and records its entry.
+func (c *Cache) store(dir, name string, entry cacheEntry, raw, xml *blobWriter) error {
+	if err := raw.commit(entry.Checksum); err != nil {
+		return fmt.Errorf("unable to cache raw content of %s: %w", name, err)
+	}
+	if err := xml.commit(entry.XMLChecksum); err != nil {
+		return fmt.Errorf("unable to cache xml content of %s: %w", name, err)
+	}
+
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// Written through a temporary file so a crash never leaves a partial entry
+	tmp, err := os.CreateTemp(filepath.Join(c.Dir, "tmp"), "entry-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.entryPath(dir, name))
+}
+
+// openBlob opens the cached content with the given checksum.
+func (c *Cache) openBlob(checksum string) (*os.File, error) {
+	return os.Open(c.blobPath(checksum))
+}
+
+func (c *Cache) entryPath(dir, name string) string {
+	return filepath.Join(c.Dir, "files", dir, filepath.Base(name)+".json")
+}
+
+// blobPath spreads the blobs over subdirectories by the first two characters of their checksum.
+func (c *Cache) blobPath(checksum string) string {
+	return filepath.Join(c.Dir, "blobs", checksum[:2], checksum)
+}
+
+// blobWriter writes content to a temporary file until it is committed to the cache under its checksum.
+// Write never fails, so the writer can be fed by an io.TeeReader without affecting the import. The first
+// error is returned by commit instead. All methods are no-ops on a nil writer.
+type blobWriter struct {
+	cache *Cache
+	file  *os.File
+	err   error
+}
+
+// newBlob returns a writer for content to be committed to the cache.
+func (c *Cache) newBlob() *blobWriter {
+	file, err := os.CreateTemp(filepath.Join(c.Dir, "tmp"), "blob-*")
+	return &blobWriter{cache: c, file: file, err: err}
+}
+
+func (w *blobWriter) Write(p []byte) (int, error) {
+	if w != nil && w.err == nil {
+		_, w.err = w.file.Write(p)
+	}
+	return len(p), nil
+}
+
+// commit moves the written content to the blob with the given checksum.
+// Content already in the cache is kept and the written copy is discarded.
+func (w *blobWriter) commit(checksum string) error {
+	if w == nil {
+		return nil
+	}
+	if w.err != nil {
+		w.abort()
+		return w.err
+	}
+	if err := w.file.Close(); err != nil {
+		w.abort()
+		return err
+	}
+
+	path := w.cache.blobPath(checksum)
+	if _, err := os.Stat(path); err == nil {
+		return os.Remove(w.file.Name())
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		w.abort()
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		w.abort()
+		return err
+	}
+
+	return os.Rename(w.file.Name(), path)
+}
+
+// abort discards the written content. It is a no-op once the blob is committed.
+func (w *blobWriter) abort() {
+	if w == nil || w.file == nil {
+		return
+	}
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
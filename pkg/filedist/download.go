@@ -0,0 +1,231 @@
+package filedist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how failed requests to a distribution server are retried, with exponential backoff.
+type RetryPolicy struct {
+	Attempts     int           // attempts per request, including the first
+	InitialDelay time.Duration // delay before the first retry, doubled for every following retry
+	MaxDelay     time.Duration // upper bound of the delay between retries
+}
+
+// DefaultRetry is the retry policy of sources created by NewHTTPSource.
+var DefaultRetry = RetryPolicy{Attempts: 5, InitialDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// defaultReadTimeout is how long a download may go without receiving any data before it is resumed.
+const defaultReadTimeout = time.Minute
+
+// delay returns the backoff before retry number retry, starting at 1.
+func (p RetryPolicy) delay(retry int) time.Duration {
+	delay := p.InitialDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// newHTTPClient returns a client with timeouts for connecting and waiting for a response.
+// The client has no timeout for whole requests since large files take long to download, stalled downloads are
+// detected by the read timeout of HTTPSource instead.
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = 30 * time.Second
+	transport.ResponseHeaderTimeout = time.Minute
+
+	return &http.Client{Transport: transport}
+}
+
+// statusError is returned when a server responds with an unexpected status code.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("received unexpected response code: %d", e.code)
+}
+
+// errFileChanged is returned when a download cannot be resumed because the file changed on the server.
+var errFileChanged = errors.New("file changed on the server during download")
+
+// retryable reports whether a request that failed with err may succeed when retried.
+// Network errors and server errors are retried, client errors are not.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, errFileChanged) {
+		return false
+	}
+
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= http.StatusInternalServerError || statusErr.code == http.StatusTooManyRequests
+	}
+
+	return true
+}
+
+// download is a GET request whose body is read through Read. If reading the body fails or stalls, the download
+// is resumed from the last byte read with a Range request. Every request is retried according to the retry policy.
+type download struct {
+	ctx         context.Context
+	client      *http.Client
+	url         string
+	retry       RetryPolicy
+	readTimeout time.Duration
+
+	// validator is the ETag or Last-Modified header of the first response, so a resumed download fails if the file changed
+	validator string
+	offset    int64
+
+	body     io.ReadCloser
+	cancel   context.CancelFunc
+	watchdog *time.Timer
+}
+
+// startDownload requests url and returns the download once the server has responded successfully.
+func startDownload(ctx context.Context, client *http.Client, url string, retry RetryPolicy, readTimeout time.Duration) (*download, error) {
+	d := &download{ctx: ctx, client: client, url: url, retry: retry, readTimeout: readTimeout}
+	if err := d.request(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// request (re)opens the body at the current offset, retrying failed attempts with backoff.
+func (d *download) request() error {
+	attempts := max(d.retry.Attempts, 1)
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			delay := d.retry.delay(attempt - 1)
+			slog.Warn("Request failed, retrying", "url", d.url, "offset", d.offset, "attempt", attempt, "delay", delay, "error", err)
+
+			select {
+			case <-d.ctx.Done():
+				return d.ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		err = d.attempt()
+		if err == nil || !retryable(d.ctx, err) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+}
+
+// attempt sends a single request for the body from the current offset.
+func (d *download) attempt() error {
+	ctx, cancel := context.WithCancel(d.ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url, nil)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if d.offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(d.offset, 10)+"-")
+		if d.validator != "" {
+			req.Header.Set("If-Range", d.validator)
+		}
+	}
+
+	// The request is cancelled if no data arrives in time, failing the pending read
+	watchdog := time.AfterFunc(d.readTimeout, cancel)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		watchdog.Stop()
+		cancel()
+		return err
+	}
+
+	fail := func(err error) error {
+		watchdog.Stop()
+		resp.Body.Close()
+		cancel()
+		return err
+	}
+
+	validator := resp.Header.Get("ETag")
+	if validator == "" {
+		validator = resp.Header.Get("Last-Modified")
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK && d.offset == 0:
+		d.validator = validator
+
+	case resp.StatusCode == http.StatusPartialContent && d.offset > 0:
+
+	case resp.StatusCode == http.StatusOK:
+		// The server ignored the range, either because the file changed or because it does not support ranges
+		if d.validator != "" && validator != d.validator {
+			return fail(errFileChanged)
+		}
+		if _, err := io.CopyN(io.Discard, resp.Body, d.offset); err != nil {
+			return fail(fmt.Errorf("unable to skip to offset %d: %w", d.offset, err))
+		}
+
+	default:
+		return fail(&statusError{code: resp.StatusCode})
+	}
+
+	d.body, d.cancel, d.watchdog = resp.Body, cancel, watchdog
+	return nil
+}
+
+func (d *download) Read(p []byte) (int, error) {
+	for {
+		if d.body == nil {
+			if err := d.request(); err != nil {
+				return 0, fmt.Errorf("unable to resume download at offset %d: %w", d.offset, err)
+			}
+		}
+
+		d.watchdog.Reset(d.readTimeout)
+		n, err := d.body.Read(p)
+		d.offset += int64(n)
+
+		if err == nil || err == io.EOF || d.ctx.Err() != nil {
+			return n, err
+		}
+
+		// The connection failed or stalled, resume from the current offset on the next read
+		slog.Warn("Download interrupted, resuming", "url", d.url, "offset", d.offset, "error", err)
+		d.closeBody()
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (d *download) Close() error {
+	d.closeBody()
+	return nil
+}
+
+// closeBody releases the body of the current request, if any.
+func (d *download) closeBody() {
+	if d.body == nil {
+		return
+	}
+
+	d.watchdog.Stop()
+	d.body.Close()
+	d.cancel()
+	d.body = nil
+}
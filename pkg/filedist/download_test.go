@@ -0,0 +1,205 @@
+package filedist
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRetry retries without waiting noticeably.
+var testRetry = RetryPolicy{Attempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Attempts: 10, InitialDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range want {
+		if got := policy.delay(i + 1); got != delay {
+			t.Errorf("delay(%d) = %v, want %v", i+1, got, delay)
+		}
+	}
+}
+
+// fileServer serves content, answering each request with the next handler in responses.
+// Requests after the last handler are answered by the last one. The requests it received are recorded.
+type fileServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []http.HandlerFunc
+	requests  []*http.Request
+}
+
+func newFileServer(t *testing.T, responses ...http.HandlerFunc) *fileServer {
+	s := &fileServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		handler := s.responses[min(len(s.requests), len(s.responses))-1]
+		s.mu.Unlock()
+
+		handler(w, r)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// serve answers with content from the offset of the Range header, if any, tagged with etag.
+// If cutAt is positive, the connection is dropped after that many bytes of the file.
+func serve(content, etag string, cutAt int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+
+		offset := 0
+		status := http.StatusOK
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && r.Header.Get("If-Range") == etag {
+			offset, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-"))
+			status = http.StatusPartialContent
+		}
+
+		end := len(content)
+		if cutAt > 0 {
+			end = cutAt
+		}
+
+		// A body shorter than its Content-Length makes the server close the connection
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)-offset))
+		w.WriteHeader(status)
+		io.WriteString(w, content[offset:end])
+	}
+}
+
+// status answers with an empty response of the given status code.
+func status(code int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	content := strings.Repeat("<measure/>", 1000)
+	half := len(content) / 2
+
+	tests := []struct {
+		name      string
+		responses []http.HandlerFunc
+		want      error
+		requests  int
+	}{
+		{
+			name:      "complete",
+			responses: []http.HandlerFunc{serve(content, `"v1"`, 0)},
+			requests:  1,
+		},
+		{
+			name:      "server errors are retried",
+			responses: []http.HandlerFunc{status(http.StatusServiceUnavailable), status(http.StatusBadGateway), serve(content, `"v1"`, 0)},
+			requests:  3,
+		},
+		{
+			name:      "client errors are not retried",
+			responses: []http.HandlerFunc{status(http.StatusNotFound)},
+			want:      &statusError{code: http.StatusNotFound},
+			requests:  1,
+		},
+		{
+			name:      "gives up after the attempts of the policy",
+			responses: []http.HandlerFunc{status(http.StatusInternalServerError)},
+			want:      &statusError{code: http.StatusInternalServerError},
+			requests:  3,
+		},
+		{
+			name:      "dropped connection is resumed with a range",
+			responses: []http.HandlerFunc{serve(content, `"v1"`, half), serve(content, `"v1"`, 0)},
+			requests:  2,
+		},
+		{
+			name:      "resume skips to the offset when the range is ignored",
+			responses: []http.HandlerFunc{serve(content, `"v1"`, half), ignoreRange(serve(content, `"v1"`, 0))},
+			requests:  2,
+		},
+		{
+			name:      "file changed before resuming",
+			responses: []http.HandlerFunc{serve(content, `"v1"`, half), serve(strings.ToUpper(content), `"v2"`, 0)},
+			want:      errFileChanged,
+			requests:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFileServer(t, tt.responses...)
+			ctx := context.Background()
+
+			var got []byte
+			d, err := startDownload(ctx, server.Client(), server.URL+"/tot/Measure_tot_241012.xml.pgp", testRetry, time.Minute)
+			if err == nil {
+				got, err = io.ReadAll(d)
+				d.Close()
+			}
+
+			switch want := tt.want.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("download failed: %v", err)
+				}
+				if string(got) != content {
+					t.Errorf("downloaded %d bytes that differ from the %d bytes of the file", len(got), len(content))
+				}
+			case *statusError:
+				var statusErr *statusError
+				if !errors.As(err, &statusErr) || statusErr.code != want.code {
+					t.Fatalf("error = %v, want status %d", err, want.code)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("error = %v, want %v", err, want)
+				}
+			}
+
+			if len(server.requests) != tt.requests {
+				t.Errorf("requests = %d, want %d", len(server.requests), tt.requests)
+			}
+		})
+	}
+}
+
+// ignoreRange serves a request as if it had no Range header, like a server without range support.
+func ignoreRange(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("Range")
+		handler(w, r)
+	}
+}
+
+func TestDownloadResumeHeaders(t *testing.T) {
+	content := strings.Repeat("<measure/>", 1000)
+	half := len(content) / 2
+	server := newFileServer(t, serve(content, `"v1"`, half), serve(content, `"v1"`, 0))
+
+	d, err := startDownload(context.Background(), server.Client(), server.URL, testRetry, time.Minute)
+	if err != nil {
+		t.Fatalf("startDownload: %v", err)
+	}
+	defer d.Close()
+	if _, err := io.ReadAll(d); err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+
+	if len(server.requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(server.requests))
+	}
+	resumed := server.requests[1]
+	if got, want := resumed.Header.Get("Range"), "bytes="+strconv.Itoa(half)+"-"; got != want {
+		t.Errorf("Range = %q, want %q", got, want)
+	}
+	if got := resumed.Header.Get("If-Range"); got != `"v1"` {
+		t.Errorf("If-Range = %q, want the ETag of the first response", got)
+	}
+}
@@ -1,40 +1,46 @@
 package filedist
 
 import (
-	"bufio"
-	"bytes"
-	"compress/gzip"
 	"context"
 	"database/sql"
 	_ "embed"
-	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"net/http"
-	"net/url"
 	"path/filepath"
-	"reflect"
 	"sort"
 	"strings"
 	"time"
-	"tulltaxan/pkg/xmltypes"
 
 	"github.com/jackc/pgx/v5"
 	"golang.org/x/crypto/openpgp"
-	"golang.org/x/crypto/openpgp/armor"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 )
 
-func StartDbMaintenanceScheduler(conn *pgx.Conn) {
-	pubKey, err := downloadPublicKey(`https://distr.tullverket.se/tulltaxan/Tulltaxan_Fildistribution.asc`)
-	if err != nil {
-		slog.Error("downloadPublicKey", "error", err)
+// Config configures where filedist files are read from and how they are verified.
+type Config struct {
+	Source Source
+	// PublicKey is the armored signing key. If empty, the key is fetched from Source on each run.
+	PublicKey string
+	// KeyFingerprint pins the signing key by the hex fingerprint of its primary key.
+	// Files signed by any other key are rejected and quarantined.
+	KeyFingerprint string
+	// Cache stores the content of imported files if set, see RebuildFromCache.
+	Cache *Cache
+}
+
+// StartDbMaintenanceScheduler imports new files from the configured source immediately and then every night at 23:30.
+// Every file is imported in a transaction on conn, so conn must not be shared with anything else, such as the
+// HTTP handlers, which would fail with a busy connection or read uncommitted changes.
+func StartDbMaintenanceScheduler(conn *pgx.Conn, cfg Config) {
+	if cfg.KeyFingerprint == "" {
+		slog.Warn("Signing key is not pinned, set a key fingerprint to only accept files signed by Tullverket")
 	}
 
 	// Perform initial database maintenance immediately
-	err = performDbMaintenance(pubKey, conn)
+	err := performDbMaintenance(cfg, conn)
 	if err != nil {
 		slog.Error("Error during initial database maintenance", "error", err)
 	}
@@ -58,7 +64,7 @@ func StartDbMaintenanceScheduler(conn *pgx.Conn) {
 			time.Sleep(timeUntilNextRun)
 
 			// Perform scheduled database maintenance at 11:30 PM
-			err := performDbMaintenance(pubKey, conn)
+			err := performDbMaintenance(cfg, conn)
 			if err != nil {
 				slog.Error("Error during scheduled database maintenance", "error", err)
 			}
@@ -66,62 +72,87 @@ func StartDbMaintenanceScheduler(conn *pgx.Conn) {
 	}()
 }
 
-// downloadPublicKey fetches the public key from the given URL.
-func downloadPublicKey(url string) (string, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", fmt.Errorf("failed to download public key: %w", err)
-	}
-	defer resp.Body.Close()
+// performDbMaintenance performs the necessary maintenance tasks on the database.
+// It downloads new files from the distribution and processes them into the database. If any file was imported,
+// the materialized views are refreshed, also when a later file failed.
+// The run and the outcome of every file are recorded in import_run and import_file.
+func performDbMaintenance(cfg Config, conn *pgx.Conn) (err error) {
+	ctx := context.Background()
+	slog.Info("Starting database maintenance..")
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
+	run, err := startImportRun(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("startImportRun: %w", err)
 	}
+	defer func() {
+		if finishErr := run.finish(ctx, err); finishErr != nil {
+			slog.Error("Unable to record end of import run", "error", finishErr)
+		}
+	}()
 
-	body, err := io.ReadAll(resp.Body)
+	// A missing key only prevents pgp files from being imported, decrypted xml files can still be read
+	keyRing, err := loadSigningKey(cfg)
 	if err != nil {
-		return "", fmt.Errorf("failed to read public key response: %w", err)
+		slog.Error("loadSigningKey", "error", err)
 	}
 
-	return string(body), nil
-}
-
-// performDbMaintenance performs the necessary maintenance tasks on the database.
-// It downloads new files from the distribution, processes them into the database, and sets the active codes.
-func performDbMaintenance(pubKey string, conn *pgx.Conn) error {
-	slog.Info("Starting database maintenance..")
-
 	// Download new files from distribution
-	totFiles, err := downloadAndPrepareNewFiles("https://distr.tullverket.se/tulltaxan/xml/tot/", pubKey, conn)
+	totFiles, err := importNewFiles(cfg.Source, TotDir, keyRing, conn, cfg.Cache, run)
 	if err != nil {
-		return fmt.Errorf("error fetching tot files: %w", err)
+		err = fmt.Errorf("error fetching tot files: %w", err)
 	}
 
-	difFiles, err := downloadAndPrepareNewFiles("https://distr.tullverket.se/tulltaxan/xml/dif/", pubKey, conn)
-	if err != nil {
-		return fmt.Errorf("error fetching dif files: %w", err)
+	// Dif files are changes to the tot files, they are only applied on top of a complete tot import.
+	// A failed tot file is retried on the next run, and would overwrite the changes of difs applied before it
+	var difFiles []string
+	if err == nil {
+		difFiles, err = importNewFiles(cfg.Source, DifDir, keyRing, conn, cfg.Cache, run)
+		if err != nil {
+			err = fmt.Errorf("error fetching dif files: %w", err)
+		}
 	}
 
-	slog.Info("Files downloaded successfully", "totFiles", len(totFiles), "difFiles", len(difFiles))
+	slog.Info("Files imported", "totFiles", len(totFiles), "difFiles", len(difFiles))
 
-	return nil
-}
+	if len(totFiles)+len(difFiles) > 0 {
+		if refreshErr := refreshMaterializedViews(ctx, conn); refreshErr != nil {
+			return errors.Join(err, fmt.Errorf("refreshMaterializedViews: %w", refreshErr))
+		}
+	}
 
-// downloadAndPrepareNewFiles retrieves and prepares a list of files from a specified distribution URL.
-// This function downloads, decrypts, and decompresses files that are available in the distribution.
-// If a file already exists in the output directory, it is skipped. It returns a list of the resulting files.
-func downloadAndPrepareNewFiles(distUrl, pubKey string, conn *pgx.Conn) ([]string, error) {
-	slog.Info("Download and preparation process started", "URL", distUrl)
+	return err
+}
 
-	response, err := http.Get(distUrl)
-	if err != nil {
-		return nil, fmt.Errorf("unable to get file list, url: [%v] err: %w", distUrl, err)
+// loadSigningKey returns the configured signing key, fetching it from the source if none is configured.
+func loadSigningKey(cfg Config) (openpgp.EntityList, error) {
+	pubKey := cfg.PublicKey
+	if pubKey == "" {
+		var err error
+		pubKey, err = cfg.Source.PublicKey(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("PublicKey: %w", err)
+		}
 	}
+	if pubKey == "" {
+		return nil, fmt.Errorf("no public key available")
+	}
+
+	return loadKeyRing(pubKey, cfg.KeyFingerprint)
+}
 
-	// Parse the HTML content for pgp files
-	fileList, err := parseHtmlForPgpAnchors(html.NewTokenizer(response.Body))
+// importNewFiles retrieves and imports the files in a distribution directory.
+// Files are downloaded, decrypted and decompressed as needed and inserted into the database in date order.
+// Files already listed in inserted_files or quarantined_files are skipped. A file that fails does not stop the tot
+// files after it from being imported, but stops the dif files after it. It returns a list of the imported files
+// along with an error joining the failures, if any. The outcome of every file is recorded in run.
+// Imported files are stored in cache, unless it is nil.
+func importNewFiles(src Source, dir string, keyRing openpgp.EntityList, conn *pgx.Conn, cache *Cache, run *importRun) ([]string, error) {
+	ctx := context.Background()
+	slog.Info("Download and preparation process started", "dir", dir)
+
+	fileList, err := src.List(ctx, dir)
 	if err != nil {
-		return nil, fmt.Errorf("parseHtmlForPgpAnchors: %w", err)
+		return nil, fmt.Errorf("List: %w", err)
 	}
 
 	fileList, err = sortFilesByDate(fileList)
@@ -135,68 +166,274 @@ func downloadAndPrepareNewFiles(distUrl, pubKey string, conn *pgx.Conn) ([]strin
 		return nil, fmt.Errorf("getInsertedFileNames: %w", err)
 	}
 
+	// The first tot import into an empty database is loaded with COPY, later imports are upserted
+	bulk := false
+	if dir == TotDir {
+		bulk, err = isEmptyDatabase(ctx, conn)
+		if err != nil {
+			return nil, fmt.Errorf("isEmptyDatabase: %w", err)
+		}
+	}
+
 	// filter out inserted files to only download new ones.
 	fileList = filterOutInsertedFiles(fileList, insertedFileNames)
 
-	// Iterate through file list and download all files for the current month
-	for _, v := range fileList {
-		// Construct the url for the file
-		fileUrl, err := url.JoinPath(distUrl, filepath.ToSlash(filepath.Base(v)))
-		if err != nil {
-			return nil, fmt.Errorf("unable to construct valid URL for tot file dist, url: [%v] err: %w", fileUrl, err)
+	imported := make([]string, 0, len(fileList))
+	var failures []error
+
+	for i, v := range fileList {
+		file := importFile(ctx, src, dir, v, keyRing, conn, cache, bulk)
+		if err := run.recordFile(ctx, file); err != nil {
+			slog.Error("Unable to record imported file", "filename", v, "error", err)
+		}
+		if file.err == nil {
+			imported = append(imported, v)
+			continue
 		}
 
-		distLogger := slog.With("filename", filepath.Base(fileUrl))
+		slog.Error("Unable to import file, continuing with the remaining files", "filename", v, "error", file.err)
+		failures = append(failures, file.err)
 
-		// Download and prepare the file to a usable format
-		distLogger.Info("Downloading file")
-		gzReader, err := fetchDecryptedReader(fileUrl, pubKey)
-		if err != nil {
-			return nil, fmt.Errorf("downloadAndPrepareFile: %w", err)
+		// Dif files are changes on top of each other and must be applied in order, the files after a failed
+		// one are left for a later run
+		if dir == DifDir {
+			skipFiles(ctx, run, dir, fileList[i+1:], v)
+			break
 		}
+	}
 
-		distLogger.Info("Download finished, decoding")
-		export := new(xmltypes.Export)
-		err = xml.NewDecoder(gzReader).Decode(export)
-		if err != nil {
-			return nil, err
+	if len(failures) > 0 {
+		return imported, fmt.Errorf("%d of %d files failed: %w", len(failures), len(fileList), errors.Join(failures...))
+	}
+
+	return imported, nil
+}
+
+// skipFiles records files that were not attempted because the earlier file failedFile failed.
+func skipFiles(ctx context.Context, run *importRun, dir string, fileNames []string, failedFile string) {
+	for _, name := range fileNames {
+		file := importedFile{
+			dir:       dir,
+			fileName:  name,
+			startedAt: time.Now(),
+			err:       fmt.Errorf("%w: %s failed", errSkipped, failedFile),
 		}
+		if err := run.recordFile(ctx, file); err != nil {
+			slog.Error("Unable to record skipped file", "filename", name, "error", err)
+		}
+	}
 
-		distLogger.Info("Decoding finished, inserting to DB")
+	if len(fileNames) > 0 {
+		slog.Warn("Skipped files after failed file", "dir", dir, "failed", failedFile, "skipped", len(fileNames))
+	}
+}
 
-		// Reflect over struct values to insert into the database
-		val := reflect.ValueOf(export.Items)
-		for i := 0; i < val.NumField(); i++ {
-			field := val.Field(i)
-			fieldType := field.Type()
+// importFile downloads, decrypts and imports a distribution file. Files failing signature verification are quarantined.
+// The raw and decrypted content of an imported file is stored in cache, unless it is nil.
+// The returned importedFile holds the error if the file was not imported.
+func importFile(ctx context.Context, src Source, dir, name string, keyRing openpgp.EntityList, conn *pgx.Conn, cache *Cache, bulk bool) importedFile {
+	file := importedFile{dir: dir, fileName: name, startedAt: time.Now()}
+	distLogger := slog.With("filename", name)
 
-			// Check if the field implements the FileDistItem interface
-			if fieldType.Implements(reflect.TypeOf((*xmltypes.FileDistItem)(nil)).Elem()) {
-				if field.Len() < 1 {
-					continue
-				}
-				slog.Debug("Inserting struct values", "type", field.Type().Name())
-				// Execute the BatchInsert method on the field
-				callvalues := field.MethodByName("BatchInsert").Call([]reflect.Value{reflect.ValueOf(context.Background()), reflect.ValueOf(conn), reflect.ValueOf(10000)})
-				for _, callvalue := range callvalues {
-					if !callvalue.IsNil() {
-						return nil, fmt.Errorf("BatchInsert: %w", callvalue.Interface().(error))
-					}
-				}
-				fileNameStmt := `INSERT INTO inserted_files (file_name) VALUES ($1) ON CONFLICT DO NOTHING;`
-				_, err = conn.Exec(context.Background(), fileNameStmt, v)
-				if err != nil {
-					return nil, fmt.Errorf("unable to insert filename to db: %w", err)
+	// Download and prepare the file to a usable format
+	distLogger.Info("Downloading file")
+	raw, err := src.Open(ctx, dir, name)
+	if err != nil {
+		file.err = fmt.Errorf("Open %s: %w", name, err)
+		return file
+	}
+	defer raw.Close()
+
+	// The content is written to the cache while it is imported, and only kept if the import succeeds
+	var rawBlob, xmlBlob *blobWriter
+	if cache != nil && !cache.Has(dir, name) {
+		rawBlob, xmlBlob = cache.newBlob(), cache.newBlob()
+		defer rawBlob.abort()
+		defer xmlBlob.abort()
+	}
+
+	checksummed := newChecksumReader(io.TeeReader(raw, rawBlob))
+
+	var sums fileSums
+	reader, err := decryptedReader(name, checksummed, keyRing)
+	if err == nil {
+		distLogger.Info("Download finished, decoding")
+		xmlReader := newChecksumReader(io.TeeReader(reader, xmlBlob))
+		file.counts, err = importExport(ctx, xmlReader, name, conn, bulk, func() (fileSums, error) {
+			// Read what is left after the signature, so the checksum covers the whole file
+			if _, err := io.Copy(io.Discard, checksummed); err != nil {
+				return sums, fmt.Errorf("unable to read remainder of file: %w", err)
+			}
+			sums = fileSums{size: checksummed.size, checksum: checksummed.Sum(), xmlChecksum: xmlReader.Sum()}
+			return sums, nil
+		})
+		if err != nil && !errors.Is(err, ErrSignature) {
+			// Decoding errors may be caused by tampered content, check the signature to tell them apart
+			if verifier, ok := reader.(interface{ Verify() error }); ok {
+				if sigErr := verifier.Verify(); errors.Is(sigErr, ErrSignature) {
+					err = sigErr
 				}
 			}
 		}
-		// Save the resulting file name in the list
-		distLogger.Info("Dist file processed into DB")
+		reader.Close()
+	}
+
+	if errors.Is(err, ErrSignature) {
+		distLogger.Error("Signature verification failed, quarantining file", "error", err)
+		if qErr := quarantineFile(ctx, conn, name, err.Error()); qErr != nil {
+			file.err = fmt.Errorf("quarantineFile %s: %w", name, qErr)
+			return file
+		}
+	}
+	if err != nil {
+		// Nothing from the file was committed, it is retried on the next run unless quarantined
+		file.err = fmt.Errorf("importing %s: %w", name, err)
+		return file
+	}
+
+	file.size, file.checksum = sums.size, sums.checksum
+
+	if rawBlob != nil {
+		entry := cacheEntry{Size: sums.size, Checksum: sums.checksum, XMLChecksum: sums.xmlChecksum}
+		if err := cache.store(dir, name, entry, rawBlob, xmlBlob); err != nil {
+			distLogger.Error("Unable to cache file", "error", err)
+		}
+	}
+
+	distLogger.Info("Dist file processed into DB")
+	return file
+}
+
+// decryptedReader returns the decrypted and decompressed content of a distribution file.
+// PGP-armored files are verified against keyRing, other files are assumed to be decrypted xml already.
+func decryptedReader(name string, raw io.Reader, keyRing openpgp.EntityList) (io.ReadCloser, error) {
+	if filepath.Ext(name) != ".pgp" {
+		return io.NopCloser(raw), nil
+	}
+
+	if len(keyRing) == 0 {
+		return nil, fmt.Errorf("a public key is required to read pgp file %s", name)
+	}
+
+	// Decrypt and decompress
+	gzReader, err := decryptAndExtractGzippedFile(keyRing, raw)
+	if err != nil {
+		return nil, fmt.Errorf("decryptAndExtractGzippedFile: %w", err)
+	}
+
+	return gzReader, nil
+}
+
+// quarantineFile records a file that failed signature verification so it is never imported.
+func quarantineFile(ctx context.Context, conn *pgx.Conn, fileName, reason string) error {
+	_, err := conn.Exec(ctx, `
+	INSERT INTO quarantined_files (file_name, reason, date_quarantined)
+	VALUES ($1, $2, CURRENT_TIMESTAMP)
+	ON CONFLICT (file_name) DO UPDATE
+	SET reason = EXCLUDED.reason,
+		date_quarantined = EXCLUDED.date_quarantined;`, fileName, reason)
+
+	return err
+}
+
+// fileSums are the size and checksums of an imported file, recorded in inserted_files.
+type fileSums struct {
+	size        int64
+	checksum    string // sha256 of the file as downloaded
+	xmlChecksum string // sha256 of the decrypted xml
+}
+
+// importExport decodes a decrypted filedist xml export from r and inserts its items into the database.
+// All items and the inserted_files row for the file are written in a single transaction, so a file
+// that fails halfway leaves the database untouched and is retried on the next run.
+// If bulk is set, the large item types are loaded with COPY, see streamItems. The history triggers are skipped
+// during a bulk load, and the loaded rows are recorded in the history tables at once afterwards.
+// sums is called once r is read completely and returns the checksums to record for the file.
+// It returns the number of items imported per element name.
+func importExport(ctx context.Context, r io.Reader, fileName string, conn *pgx.Conn, bulk bool, sums func() (fileSums, error)) (map[string]itemCount, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	// Rollback is a no-op once the transaction is committed
+	defer tx.Rollback(ctx)
+
+	if bulk {
+		if _, err := tx.Exec(ctx, "SELECT set_config('tulltaxan.initial_load', 'on', true);"); err != nil {
+			return nil, fmt.Errorf("unable to skip history triggers: %w", err)
+		}
+	}
 
-		gzReader.Close()
+	counts, err := streamItems(ctx, r, tx, filepath.Base(fileName), bulk)
+	if err != nil {
+		return nil, fmt.Errorf("streamItems: %w", err)
+	}
+
+	if bulk {
+		if err := backfillHistory(ctx, tx); err != nil {
+			return nil, fmt.Errorf("backfillHistory: %w", err)
+		}
 	}
 
-	return fileList, nil
+	slog.Info("Decoding finished, all items inserted", "filename", filepath.Base(fileName))
+
+	// Read the rest of the file so the signature of pgp files is verified before committing
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, fmt.Errorf("unable to read remainder of file: %w", err)
+	}
+
+	fileSums, err := sums()
+	if err != nil {
+		return nil, err
+	}
+
+	fileNameStmt := `
+	INSERT INTO inserted_files (file_name, date_inserted, file_size, checksum, xml_checksum)
+	VALUES ($1, CURRENT_DATE, $2, $3, $4)
+	ON CONFLICT DO NOTHING;`
+	_, err = tx.Exec(ctx, fileNameStmt, fileName, fileSums.size, fileSums.checksum, fileSums.xmlChecksum)
+	if err != nil {
+		return nil, fmt.Errorf("unable to insert filename to db: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return counts, nil
+}
+
+// historyTables are the tables whose versions are recorded by the record_history triggers, with their key columns.
+var historyTables = []struct {
+	table string
+	keys  []string
+}{
+	{"measure", []string{"sid"}},
+	{"measure_component", []string{"parent_sid", "duty_expression_id"}},
+	{"measure_condition", []string{"sid"}},
+	{"goods_nomenclature_description", []string{"parent_sid", "language_id"}},
+}
+
+// backfillHistory records the rows loaded without history triggers in the history tables.
+func backfillHistory(ctx context.Context, tx pgx.Tx) error {
+	for _, history := range historyTables {
+		if _, err := tx.Exec(ctx, "SELECT backfill_history($1, VARIADIC $2::TEXT[]);", history.table, history.keys); err != nil {
+			return fmt.Errorf("unable to backfill history of %s: %w", history.table, err)
+		}
+	}
+
+	return nil
+}
+
+// isEmptyDatabase reports whether no filedist file has been imported yet.
+func isEmptyDatabase(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	var empty bool
+	err := conn.QueryRow(ctx, "SELECT NOT EXISTS (SELECT 1 FROM inserted_files);").Scan(&empty)
+	if err != nil {
+		return false, err
+	}
+
+	return empty, nil
 }
 
 // parseHtmlForPgpAnchors scans HTML content from the provided tokenizer for anchor tags linking to .pgp files.
@@ -231,10 +468,10 @@ func parseHtmlForPgpAnchors(tokenizer *html.Tokenizer) (anchorRefs []string, err
 	}
 }
 
-// getInsertedFileNames sends a select query to the inserted_files table in DB
+// getInsertedFileNames sends a select query to the inserted_files and quarantined_files tables in DB
 // and returns a slice of all filenames it retrieved.
 func getInsertedFileNames(conn *pgx.Conn) ([]string, error) {
-	rows, err := conn.Query(context.Background(), "SELECT file_name FROM inserted_files;")
+	rows, err := conn.Query(context.Background(), "SELECT file_name FROM inserted_files UNION SELECT file_name FROM quarantined_files;")
 	if err != nil {
 		return nil, err
 	}
@@ -276,6 +513,7 @@ func filterOutInsertedFiles(distributionFiles []string, alreadyImportedFileNames
 }
 
 // sortFilesByDate sorts a list of filenames by their embedded dates in ascending order.
+// Tot files of the same date are sorted so that items are imported after the items they depend on.
 func sortFilesByDate(files []string) ([]string, error) {
 	// Create a slice of file-date pairs
 	type fileDate struct {
@@ -294,9 +532,12 @@ func sortFilesByDate(files []string) ([]string, error) {
 		fileDates = append(fileDates, fileDate{filename: file, date: date})
 	}
 
-	// Sort by date
-	sort.Slice(fileDates, func(i, j int) bool {
-		return fileDates[i].date.Before(fileDates[j].date)
+	// Sort by date, files of the same date are ordered by the dependency order of the items they contain
+	sort.SliceStable(fileDates, func(i, j int) bool {
+		if !fileDates[i].date.Equal(fileDates[j].date) {
+			return fileDates[i].date.Before(fileDates[j].date)
+		}
+		return fileItemRank(fileDates[i].filename) < fileItemRank(fileDates[j].filename)
 	})
 
 	// Extract sorted filenames
@@ -323,107 +564,3 @@ func extractDate(filename string) (time.Time, error) {
 	// Parse the date in YYMMDD format
 	return time.Parse("060102", dateStr)
 }
-
-// fetchDecryptedReader downloads, decrypts, and decompresses a PGP-encrypted, GZIP-compressed file from a given URL.
-// It returns an io.Reader for the decompressed content.
-func fetchDecryptedReader(url, pubKey string) (io.ReadCloser, error) {
-
-	// Download the file
-	response, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("unable to get PGP file: %w", err)
-	}
-
-	// Decrypt and decompress
-	gzReader, err := decryptAndExtractGzippedFile(pubKey, response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("decryptAndExtractGzippedFile: %w", err)
-	}
-
-	return gzReader, nil
-}
-
-// VerifyAndDecryptGzippedPgpFile verifies the PGP signature of a gzipped file,
-// then decrypts and decompresses its contents.
-func decryptAndExtractGzippedFile(pubKey string, signedFile io.Reader) (io.ReadCloser, error) {
-	// Import the public key
-	entityList, err := importPublicKey(pubKey)
-	if err != nil {
-		return nil, fmt.Errorf("importPublicKey: %w", err)
-	}
-
-	// Decode the armored signature
-	block, err := armor.Decode(signedFile)
-	if err != nil {
-		return nil, fmt.Errorf("unable to decode signed file: %w", err)
-	}
-
-	// Initialize pgp reader
-	md, err := openpgp.ReadMessage(block.Body, entityList, nil, nil)
-	if err != nil {
-		return nil, fmt.Errorf("unable to initialize pgp reader: %w", err)
-	}
-
-	// Wrap with gzip reader
-	reader, err := gzip.NewReader(md.UnverifiedBody)
-	if err != nil {
-		return nil, fmt.Errorf("unable to initalize gzip reader: %w", err)
-	}
-
-	return reader, nil
-}
-
-// Function to import a public key
-func importPublicKey(pubKey string) (openpgp.EntityList, error) {
-	block, err := armor.Decode(bytes.NewReader([]byte(pubKey)))
-	if err != nil {
-		return nil, fmt.Errorf("unable to decode public key: %w", err)
-	}
-
-	if block.Type != "PGP PUBLIC KEY BLOCK" {
-		return nil, fmt.Errorf("block type is not [PGP PUBLIC KEY BLOCK]")
-	}
-
-	entityList, err := openpgp.ReadKeyRing(block.Body)
-	if err != nil {
-		return nil, fmt.Errorf("unable to read keyring: %w", err)
-	}
-
-	return entityList, nil
-}
-
-// cleanDifFile removes all lines containing <record> tags from a dif file.
-// It expects line-feeds to be used as row delimiters in the xml string.
-func cleanDifFile(f io.Reader) (*bufio.Reader, error) {
-
-	content, err := io.ReadAll(f)
-	if err != nil {
-		return nil, fmt.Errorf("unable to read filedist file content: %w", err)
-	}
-
-	strContentWithoutRecordTags, err := removeRecordTags(string(content))
-	if err != nil {
-		return nil, fmt.Errorf("unable to remove record tags from string: %w", err)
-	}
-
-	return bufio.NewReader(strings.NewReader(strContentWithoutRecordTags)), nil
-}
-
-// removeRecordTags removes all lines containing <record> tags from an xml string.
-// It expects line-feeds to be used as row delimiters in the xml string.
-func removeRecordTags(xmlStr string) (xmlWithoutRecords string, err error) {
-	result := strings.Builder{}
-	rows := strings.Split(xmlStr, "\n")
-	if len(rows) == 0 {
-		return "", fmt.Errorf("no rows found in xml string")
-	}
-
-	for _, line := range rows {
-		if strings.Contains(line, "<record") || strings.Contains(line, "</record>") {
-			continue
-		}
-		result.WriteString(line)
-	}
-
-	return result.String(), nil
-}
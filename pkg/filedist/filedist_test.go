@@ -0,0 +1,204 @@
+package filedist
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// certificateTot is a decrypted tot file with two certificates.
+const certificateTot = `<?xml version="1.0" encoding="UTF-8"?>
+<export>
+	<id>1</id>
+	<exportType>tot</exportType>
+	<items>
+		<certificate certificateCode="954" certificateType="N" changeType="U" dateStart="2000-01-01" national="0">
+			<certificateDescriptionPeriod SID="1" dateStart="2000-01-01" national="0">
+				<certificateDescription description="EUR.1" languageId="SV" national="0"/>
+			</certificateDescriptionPeriod>
+		</certificate>
+		<certificate certificateCode="400" certificateType="C" changeType="U" dateStart="2000-01-01" national="0">
+			<certificateDescriptionPeriod SID="2" dateStart="2000-01-01" national="0">
+				<certificateDescription description="Importlicens" languageId="SV" national="0"/>
+			</certificateDescriptionPeriod>
+		</certificate>
+	</items>
+</export>`
+
+// certificateDif is a decrypted dif file ending one certificate and deleting the other.
+const certificateDif = `<?xml version="1.0" encoding="UTF-8"?>
+<export>
+	<id>2</id>
+	<exportType>dif</exportType>
+	<items>
+		<record recordType="certificate">
+			<certificate certificateCode="954" certificateType="N" changeType="U" dateStart="2000-01-01" dateEnd="2024-12-31" national="0">
+				<certificateDescriptionPeriod SID="1" dateStart="2000-01-01" national="0">
+					<certificateDescription description="Varucertifikat EUR.1" languageId="SV" national="0"/>
+				</certificateDescriptionPeriod>
+			</certificate>
+		</record>
+		<record recordType="certificate">
+			<certificate certificateCode="400" certificateType="C" changeType="D" national="0"/>
+		</record>
+	</items>
+</export>`
+
+// fixtureSource returns a distribution with the certificate tot and dif files.
+func fixtureSource() *MemorySource {
+	src := NewMemorySource("")
+	src.Add(TotDir, "Certificate_tot_241012.xml", []byte(certificateTot))
+	src.Add(DifDir, "IncrementalObjectTraderExport_241013.xml", []byte(certificateDif))
+	return src
+}
+
+func TestPerformDbMaintenanceImportsFixtures(t *testing.T) {
+	conn := testConn(t)
+	ctx := context.Background()
+	cfg := Config{Source: fixtureSource()}
+
+	if err := performDbMaintenance(cfg, conn); err != nil {
+		t.Fatalf("performDbMaintenance: %v", err)
+	}
+
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM certificate;"); n != 1 {
+		t.Errorf("certificates = %d, want 1 after the dif deleted C400", n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM certificate WHERE certificate_type = 'N' AND certificate_code = '954' AND date_end = '2024-12-31';"); n != 1 {
+		t.Errorf("N954 was not updated by the dif file")
+	}
+
+	var description string
+	err := conn.QueryRow(ctx, "SELECT description FROM certificate_description WHERE parent_sid = 1 AND language_id = 'SV';").Scan(&description)
+	if err != nil {
+		t.Fatalf("query description: %v", err)
+	}
+	if description != "Varucertifikat EUR.1" {
+		t.Errorf("description = %q, want the description of the dif file", description)
+	}
+
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM dif_record WHERE file_name = 'IncrementalObjectTraderExport_241013.xml';"); n != 2 {
+		t.Errorf("dif records = %d, want 2", n)
+	}
+
+	var statuses []string
+	rows, err := conn.Query(ctx, "SELECT status FROM import_file ORDER BY id;")
+	if err != nil {
+		t.Fatalf("query import files: %v", err)
+	}
+	for rows.Next() {
+		var status string
+		if err := rows.Scan(&status); err != nil {
+			t.Fatalf("scan import file: %v", err)
+		}
+		statuses = append(statuses, status)
+	}
+	if want := []string{importSucceeded, importSucceeded}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("import file statuses = %v, want %v", statuses, want)
+	}
+
+	// Imported files are skipped by the next run
+	if err := performDbMaintenance(cfg, conn); err != nil {
+		t.Fatalf("second performDbMaintenance: %v", err)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM inserted_files;"); n != 2 {
+		t.Errorf("inserted files = %d, want 2", n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM import_file;"); n != 2 {
+		t.Errorf("import files = %d after a run without new files, want 2", n)
+	}
+}
+
+func TestPerformDbMaintenanceRollsBackFailedFile(t *testing.T) {
+	conn := testConn(t)
+
+	src := NewMemorySource("")
+	src.Add(TotDir, "Certificate_tot_241012.xml", []byte(certificateTot[:len(certificateTot)/2]))
+	if err := performDbMaintenance(Config{Source: src}, conn); err == nil {
+		t.Fatal("performDbMaintenance succeeded with a truncated file")
+	}
+
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM certificate;"); n != 0 {
+		t.Errorf("certificates = %d, want nothing from the truncated file", n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM inserted_files;"); n != 0 {
+		t.Errorf("inserted files = %d, want the truncated file to be retried", n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM import_file WHERE status = $1;", importFailed); n != 1 {
+		t.Errorf("failed import files = %d, want 1", n)
+	}
+}
+
+func TestInitialLoadBackfillsHistory(t *testing.T) {
+	conn := testConn(t)
+
+	var tot strings.Builder
+	if err := writeMeasureTot(&tot, 10); err != nil {
+		t.Fatal(err)
+	}
+	src := NewMemorySource("")
+	src.Add(TotDir, "Measure_tot_241012.xml", []byte(tot.String()))
+
+	if err := performDbMaintenance(Config{Source: src}, conn); err != nil {
+		t.Fatalf("performDbMaintenance: %v", err)
+	}
+
+	// The history triggers are skipped during the initial load, every loaded row is backfilled once
+	for _, history := range []struct {
+		table string
+		rows  int
+	}{{"measure", 10}, {"measure_component", 20}, {"measure_condition", 20}} {
+		query := "SELECT COUNT(*) FROM " + history.table + "_history WHERE recorded_from = '-infinity' AND recorded_to IS NULL;"
+		if n := queryInt(t, conn, query); n != history.rows {
+			t.Errorf("%s history rows from -infinity = %d, want %d", history.table, n, history.rows)
+		}
+		if n := queryInt(t, conn, "SELECT COUNT(*) FROM "+history.table+"_history;"); n != history.rows {
+			t.Errorf("%s history rows = %d, want one per row", history.table, n)
+		}
+	}
+}
+
+// measureDif is a decrypted dif file ending measure 1 and adding measure 5, which is dated back to 2020.
+const measureDif = `<?xml version="1.0" encoding="UTF-8"?>
+<export>
+	<id>2</id>
+	<exportType>dif</exportType>
+	<items>
+		<record recordType="measure">
+			<measure SID="1" SIDGeographicalArea="1" SIDGoodsNomenclature="1" changeType="U" dateStart="2020-01-01" dateEnd="2024-12-31" geographicalAreaId="1011" goodsNomenclatureCode="0100000001" measureType="103" national="0" regulationId="R8726580" regulationRoleType="1" stoppedFlag="0"/>
+		</record>
+		<record recordType="measure">
+			<measure SID="5" SIDGeographicalArea="1" SIDGoodsNomenclature="1" changeType="U" dateStart="2020-01-01" geographicalAreaId="1011" goodsNomenclatureCode="0100000001" measureType="142" national="0" regulationId="R8726580" regulationRoleType="1" stoppedFlag="0"/>
+		</record>
+	</items>
+</export>`
+
+func TestDifChangesAreRecordedWhenImported(t *testing.T) {
+	conn := testConn(t)
+
+	var tot strings.Builder
+	if err := writeMeasureTot(&tot, 1); err != nil {
+		t.Fatal(err)
+	}
+	src := NewMemorySource("")
+	src.Add(TotDir, "Measure_tot_241012.xml", []byte(tot.String()))
+	src.Add(DifDir, "IncrementalObjectTraderExport_241013.xml", []byte(measureDif))
+
+	if err := performDbMaintenance(Config{Source: src}, conn); err != nil {
+		t.Fatalf("performDbMaintenance: %v", err)
+	}
+
+	// The version of the initial load is recorded from -infinity and replaced when the dif file is imported
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM measure_history WHERE sid = 1 AND recorded_from = '-infinity' AND recorded_to IS NOT NULL;"); n != 1 {
+		t.Errorf("replaced versions of measure 1 = %d, want the version of the tot file", n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM measure_history WHERE sid = 1 AND recorded_from > '-infinity' AND recorded_to IS NULL AND date_end = '2024-12-31';"); n != 1 {
+		t.Errorf("current versions of measure 1 = %d, want the version of the dif file", n)
+	}
+
+	// A measure added by a dif file is recorded from when it was imported, even though it is dated back
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM measure_history WHERE sid = 5 AND recorded_from > now() - INTERVAL '1 hour' AND recorded_to IS NULL;"); n != 1 {
+		t.Errorf("versions of measure 5 recorded when imported = %d, want 1", n)
+	}
+}
@@ -0,0 +1,154 @@
+package filedist
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Statuses of import runs and files, as stored in import_run and import_file.
+const (
+	importRunning     = "running"
+	importSucceeded   = "succeeded"
+	importFailed      = "failed"
+	importQuarantined = "quarantined"
+	importSkipped     = "skipped"
+)
+
+// errSkipped marks files that were not imported because an earlier file failed.
+var errSkipped = errors.New("skipped")
+
+// importRun records a database maintenance run and the files it imported in import_run and import_file.
+type importRun struct {
+	id   int
+	conn *pgx.Conn
+	// schema of the tables the run is recorded in, the current schema if empty
+	schema string
+}
+
+// startImportRun records the start of a maintenance run.
+func startImportRun(ctx context.Context, conn *pgx.Conn) (*importRun, error) {
+	run := &importRun{conn: conn}
+	err := conn.QueryRow(ctx, `
+	INSERT INTO import_run (started_at, status)
+	VALUES (CURRENT_TIMESTAMP, $1)
+	RETURNING id;`, importRunning).Scan(&run.id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to insert import run: %w", err)
+	}
+
+	return run, nil
+}
+
+// inSchema returns the run recorded in the tables of schema instead, which must already hold the run.
+func (run *importRun) inSchema(schema string) *importRun {
+	return &importRun{id: run.id, conn: run.conn, schema: schema}
+}
+
+// table returns the sanitized name of a history table in the schema of the run.
+func (run *importRun) table(name string) string {
+	if run.schema == "" {
+		return pgx.Identifier{name}.Sanitize()
+	}
+	return pgx.Identifier{run.schema, name}.Sanitize()
+}
+
+// finish records the end of the run, which failed if runErr is not nil.
+func (run *importRun) finish(ctx context.Context, runErr error) error {
+	status := importSucceeded
+	var message *string
+	if runErr != nil {
+		status = importFailed
+		msg := runErr.Error()
+		message = &msg
+	}
+
+	_, err := run.conn.Exec(ctx, fmt.Sprintf(`
+	UPDATE %s
+	SET finished_at = CURRENT_TIMESTAMP,
+		status = $2,
+		error = $3
+	WHERE id = $1;`, run.table("import_run")), run.id, status, message)
+	if err != nil {
+		return fmt.Errorf("unable to update import run %d: %w", run.id, err)
+	}
+
+	return nil
+}
+
+// importedFile is the outcome of importing one distribution file.
+type importedFile struct {
+	dir       string
+	fileName  string
+	startedAt time.Time
+	// size and checksum of the file as downloaded, only set if the file was read completely
+	size     int64
+	checksum string
+	counts   map[string]itemCount
+	err      error
+}
+
+// recordFile records the outcome of importing a file during the run.
+func (run *importRun) recordFile(ctx context.Context, file importedFile) error {
+	status := importSucceeded
+	var message *string
+	if file.err != nil {
+		status = importFailed
+		if errors.Is(file.err, ErrSignature) {
+			status = importQuarantined
+		} else if errors.Is(file.err, errSkipped) {
+			status = importSkipped
+		}
+		msg := file.err.Error()
+		message = &msg
+	}
+
+	var size *int64
+	var checksum *string
+	if file.checksum != "" {
+		size = &file.size
+		checksum = &file.checksum
+	}
+
+	_, err := run.conn.Exec(ctx, fmt.Sprintf(`
+	INSERT INTO %s (
+		run_id, directory, file_name, started_at, finished_at, status, error, file_size, checksum, item_counts
+	)
+	VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, $5, $6, $7, $8, $9);`, run.table("import_file")),
+		run.id, file.dir, file.fileName, file.startedAt, status, message, size, checksum, file.counts)
+	if err != nil {
+		return fmt.Errorf("unable to insert import file %s: %w", file.fileName, err)
+	}
+
+	return nil
+}
+
+// checksumReader computes the size and sha256 checksum of everything read through it.
+type checksumReader struct {
+	r    io.Reader
+	hash hash.Hash
+	size int64
+}
+
+func newChecksumReader(r io.Reader) *checksumReader {
+	return &checksumReader{r: r, hash: sha256.New()}
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.hash.Write(p[:n])
+	c.size += int64(n)
+	return n, err
+}
+
+// Sum returns the hex encoded checksum of the data read so far.
+func (c *checksumReader) Sum() string {
+	return hex.EncodeToString(c.hash.Sum(nil))
+}
@@ -0,0 +1,99 @@
+package filedist
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestImportRunRecordsFiles(t *testing.T) {
+	conn := testConn(t)
+	ctx := context.Background()
+
+	// The first dif file is truncated, so the dif file after it is skipped
+	src := NewMemorySource("")
+	src.Add(TotDir, "Certificate_tot_241012.xml", []byte(certificateTot))
+	src.Add(DifDir, "IncrementalObjectTraderExport_241013.xml", []byte(certificateDif[:len(certificateDif)/2]))
+	src.Add(DifDir, "IncrementalObjectTraderExport_241014.xml", []byte(certificateDif))
+
+	if err := performDbMaintenance(Config{Source: src}, conn); err == nil {
+		t.Fatal("performDbMaintenance succeeded with a truncated dif file")
+	}
+
+	var runStatus string
+	var runError *string
+	var finished bool
+	err := conn.QueryRow(ctx, "SELECT status, error, finished_at IS NOT NULL FROM import_run;").Scan(&runStatus, &runError, &finished)
+	if err != nil {
+		t.Fatalf("query import run: %v", err)
+	}
+	if runStatus != importFailed || runError == nil || !finished {
+		t.Errorf("import run = %s, error %v, finished %v, want a finished failed run with its error", runStatus, runError, finished)
+	}
+
+	type fileRow struct {
+		Directory  string
+		FileName   string
+		Status     string
+		Error      *string
+		Checksum   *string
+		ItemCounts map[string]itemCount
+	}
+	want := []fileRow{
+		{Directory: TotDir, FileName: "Certificate_tot_241012.xml", Status: importSucceeded},
+		{Directory: DifDir, FileName: "IncrementalObjectTraderExport_241013.xml", Status: importFailed},
+		{Directory: DifDir, FileName: "IncrementalObjectTraderExport_241014.xml", Status: importSkipped},
+	}
+
+	rows, err := conn.Query(ctx, "SELECT directory, file_name, status, error, checksum, item_counts FROM import_file ORDER BY id;")
+	if err != nil {
+		t.Fatalf("query import files: %v", err)
+	}
+	defer rows.Close()
+
+	var files []fileRow
+	for rows.Next() {
+		var file fileRow
+		var counts []byte
+		if err := rows.Scan(&file.Directory, &file.FileName, &file.Status, &file.Error, &file.Checksum, &counts); err != nil {
+			t.Fatalf("scan import file: %v", err)
+		}
+		if counts != nil {
+			if err := json.Unmarshal(counts, &file.ItemCounts); err != nil {
+				t.Fatalf("item counts %s: %v", counts, err)
+			}
+		}
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("import files: %v", err)
+	}
+
+	if len(files) != len(want) {
+		t.Fatalf("import files = %d, want %d", len(files), len(want))
+	}
+	for i, file := range files {
+		if file.Directory != want[i].Directory || file.FileName != want[i].FileName || file.Status != want[i].Status {
+			t.Errorf("import file %d = %s/%s %s, want %s/%s %s", i, file.Directory, file.FileName, file.Status, want[i].Directory, want[i].FileName, want[i].Status)
+		}
+		if (file.Status == importSucceeded) != (file.Error == nil) {
+			t.Errorf("%s has status %s and error %v", file.FileName, file.Status, file.Error)
+		}
+	}
+
+	tot := files[0]
+	if tot.Checksum == nil || len(*tot.Checksum) != 64 {
+		t.Errorf("checksum of the tot file = %v, want a sha256 checksum", tot.Checksum)
+	}
+	if got := tot.ItemCounts["certificate"]; got != (itemCount{Upserted: 2}) {
+		t.Errorf("certificate counts of the tot file = %+v, want 2 upserted", got)
+	}
+	if skipped := files[2]; skipped.Checksum != nil {
+		t.Errorf("checksum of the skipped file = %v, want none since it was not read", *skipped.Checksum)
+	}
+
+	// The skipped dif file is imported by a later run
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM inserted_files WHERE file_name = 'IncrementalObjectTraderExport_241014.xml';"); n != 0 {
+		t.Error("the skipped dif file is listed as inserted")
+	}
+}
@@ -0,0 +1,315 @@
+package filedist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// rebuildSchema is the schema a rebuilt database is imported into before it replaces the live schema.
+const rebuildSchema = "tulltaxan_rebuild"
+
+// Tables copied from the live schema into a rebuilt one, since they describe the history of the database and not the tariff.
+var keptTables = []struct {
+	table   string
+	columns string
+}{
+	{"import_run", "id, started_at, finished_at, status, error"},
+	{"import_file", "id, run_id, directory, file_name, started_at, finished_at, status, error, file_size, checksum, item_counts"},
+	{"quarantined_files", "file_name, reason, date_quarantined"},
+}
+
+// Rebuild builds the database from scratch from the distribution: the newest tot files and the dif files dated after
+// them are imported in date order into a new schema created by the ddl scripts. Once every file is imported and the
+// materialized views are refreshed, the new schema atomically replaces the live one, so the API never serves a
+// partially built tariff. If the rebuild fails, the live schema is left untouched.
+// The import history is kept, but the history of tariff changes starts over from the rebuilt tariff.
+func Rebuild(ctx context.Context, conn *pgx.Conn, cfg Config, ddl []string) error {
+	// A missing key only prevents pgp files from being imported, decrypted xml files can still be read
+	keyRing, err := loadSigningKey(cfg)
+	if err != nil {
+		slog.Error("loadSigningKey", "error", err)
+	}
+
+	return rebuild(ctx, conn, ddl, cfg.Source.List, func(dir, name string, bulk bool) importedFile {
+		return importFile(ctx, cfg.Source, dir, name, keyRing, conn, cfg.Cache, bulk)
+	})
+}
+
+// RebuildFromCache rebuilds the database like Rebuild, reading the files from cache instead of the distribution.
+// The cached xml is imported as is, its checksum is verified against the cache entry instead of its signature.
+func RebuildFromCache(ctx context.Context, conn *pgx.Conn, cache *Cache, ddl []string) error {
+	list := func(ctx context.Context, dir string) ([]string, error) {
+		return cache.Files(dir)
+	}
+
+	return rebuild(ctx, conn, ddl, list, func(dir, name string, bulk bool) importedFile {
+		return importCachedFile(ctx, cache, dir, name, conn, bulk)
+	})
+}
+
+// rebuild imports the baseline files returned by list into a new schema and swaps it in for the live schema.
+func rebuild(ctx context.Context, conn *pgx.Conn, ddl []string, list func(ctx context.Context, dir string) ([]string, error),
+	importOne func(dir, name string, bulk bool) importedFile) error {
+	totFiles, err := list(ctx, TotDir)
+	if err != nil {
+		return fmt.Errorf("List: %w", err)
+	}
+	difFiles, err := list(ctx, DifDir)
+	if err != nil {
+		return fmt.Errorf("List: %w", err)
+	}
+
+	totFiles, difFiles, err = baselineFiles(totFiles, difFiles)
+	if err != nil {
+		return fmt.Errorf("baselineFiles: %w", err)
+	}
+	slog.Info("Rebuilding database", "totFiles", len(totFiles), "difFiles", len(difFiles))
+
+	var liveSchema, searchPath string
+	if err := conn.QueryRow(ctx, "SELECT current_schema(), current_setting('search_path');").Scan(&liveSchema, &searchPath); err != nil {
+		return fmt.Errorf("unable to read current schema: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(ctx, "SELECT set_config('search_path', $1, false);", searchPath); err != nil {
+			slog.Error("Unable to restore search_path", "error", err)
+		}
+	}()
+
+	// The run is recorded in the live schema before the rebuild schema copies the import history, so a failed
+	// rebuild is not lost with the rebuild schema
+	run, err := startImportRun(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("startImportRun: %w", err)
+	}
+
+	if err := buildAndSwap(ctx, conn, run, liveSchema, ddl, totFiles, difFiles, importOne); err != nil {
+		if recordErr := recordFailedRebuild(ctx, run.inSchema(liveSchema), err); recordErr != nil {
+			slog.Error("Unable to record failed rebuild", "error", recordErr)
+		}
+		return err
+	}
+
+	slog.Info("Database rebuilt", "schema", liveSchema)
+	return nil
+}
+
+// buildAndSwap imports the files into a new rebuildSchema and swaps it in for liveSchema.
+// The run is recorded in the rebuild schema, so its outcome replaces the live import history along with the tariff.
+func buildAndSwap(ctx context.Context, conn *pgx.Conn, run *importRun, liveSchema string, ddl []string,
+	totFiles, difFiles []string, importOne func(dir, name string, bulk bool) importedFile) error {
+	if err := createRebuildSchema(ctx, conn, liveSchema, ddl); err != nil {
+		return fmt.Errorf("createRebuildSchema: %w", err)
+	}
+
+	err := importBaseline(ctx, conn, run, totFiles, difFiles, importOne)
+	if finishErr := run.finish(ctx, err); finishErr != nil {
+		slog.Error("Unable to record end of import run", "error", finishErr)
+	}
+	if err != nil {
+		return fmt.Errorf("rebuild failed, schema %s is kept for inspection: %w", rebuildSchema, err)
+	}
+
+	if err := swapSchemas(ctx, conn, liveSchema); err != nil {
+		return fmt.Errorf("swapSchemas: %w", err)
+	}
+
+	return nil
+}
+
+// recordFailedRebuild records the failure of a rebuild in the live schema of run, copying the files the rebuild
+// recorded in rebuildSchema, if it got that far.
+func recordFailedRebuild(ctx context.Context, run *importRun, runErr error) error {
+	if err := run.finish(ctx, runErr); err != nil {
+		return err
+	}
+
+	var created bool
+	err := run.conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL;", pgx.Identifier{rebuildSchema, "import_file"}.Sanitize()).Scan(&created)
+	if err != nil {
+		return fmt.Errorf("unable to look up import files of %s: %w", rebuildSchema, err)
+	}
+	if !created {
+		return nil
+	}
+
+	// The ids of the rebuild schema may already be taken by files imported meanwhile into the live schema
+	const columns = "run_id, directory, file_name, started_at, finished_at, status, error, file_size, checksum, item_counts"
+	_, err = run.conn.Exec(ctx, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s WHERE run_id = $1 ORDER BY id;",
+		run.table("import_file"), columns, columns, pgx.Identifier{rebuildSchema, "import_file"}.Sanitize()), run.id)
+	if err != nil {
+		return fmt.Errorf("unable to copy import files of run %d: %w", run.id, err)
+	}
+
+	return nil
+}
+
+// baselineFiles returns the files a database is built from: the tot files of the newest date and the dif files
+// dated after them, each sorted by date.
+func baselineFiles(totFiles, difFiles []string) ([]string, []string, error) {
+	totFiles, err := sortFilesByDate(totFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(totFiles) == 0 {
+		return nil, nil, errors.New("no tot files available")
+	}
+
+	difFiles, err = sortFilesByDate(difFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Sorted files have valid dates
+	baseline, _ := extractDate(totFiles[len(totFiles)-1])
+
+	first := len(totFiles) - 1
+	for first > 0 {
+		date, _ := extractDate(totFiles[first-1])
+		if !date.Equal(baseline) {
+			break
+		}
+		first--
+	}
+
+	laterDifs := make([]string, 0, len(difFiles))
+	for _, file := range difFiles {
+		if date, _ := extractDate(file); date.After(baseline) {
+			laterDifs = append(laterDifs, file)
+		}
+	}
+
+	return totFiles[first:], laterDifs, nil
+}
+
+// createRebuildSchema creates an empty rebuildSchema from the ddl scripts and makes it the current schema.
+// The import history of the live schema is copied into it.
+func createRebuildSchema(ctx context.Context, conn *pgx.Conn, liveSchema string, ddl []string) error {
+	schema := pgx.Identifier{rebuildSchema}.Sanitize()
+
+	// A schema left by a failed rebuild is replaced
+	if _, err := conn.Exec(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE;"); err != nil {
+		return fmt.Errorf("unable to drop schema %s: %w", rebuildSchema, err)
+	}
+	if _, err := conn.Exec(ctx, "CREATE SCHEMA "+schema+";"); err != nil {
+		return fmt.Errorf("unable to create schema %s: %w", rebuildSchema, err)
+	}
+	if _, err := conn.Exec(ctx, "SET search_path TO "+schema+", extensions;"); err != nil {
+		return fmt.Errorf("unable to set search_path: %w", err)
+	}
+
+	for _, script := range ddl {
+		if _, err := conn.Exec(ctx, script); err != nil {
+			return fmt.Errorf("unable to execute ddl: %w", err)
+		}
+	}
+
+	for _, kept := range keptTables {
+		_, err := conn.Exec(ctx, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s;",
+			pgx.Identifier{kept.table}.Sanitize(), kept.columns, kept.columns, pgx.Identifier{liveSchema, kept.table}.Sanitize()))
+		if err != nil {
+			return fmt.Errorf("unable to copy %s: %w", kept.table, err)
+		}
+	}
+
+	// Continue numbering runs after the copied ones
+	for _, table := range []string{"import_run", "import_file"} {
+		_, err := conn.Exec(ctx, fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %s;",
+			table, pgx.Identifier{table}.Sanitize()))
+		if err != nil {
+			return fmt.Errorf("unable to update sequence of %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// importBaseline imports the tot and dif files into the current schema and refreshes the materialized views.
+// It stops at the first failing file, since a rebuild must be complete before it replaces the live schema.
+func importBaseline(ctx context.Context, conn *pgx.Conn, run *importRun, totFiles, difFiles []string,
+	importOne func(dir, name string, bulk bool) importedFile) error {
+	for _, files := range []struct {
+		dir   string
+		names []string
+	}{{TotDir, totFiles}, {DifDir, difFiles}} {
+		for _, name := range files.names {
+			// The tot files are loaded into the empty schema with COPY
+			file := importOne(files.dir, name, files.dir == TotDir)
+			if err := run.recordFile(ctx, file); err != nil {
+				slog.Error("Unable to record imported file", "filename", name, "error", err)
+			}
+			if file.err != nil {
+				return file.err
+			}
+		}
+	}
+
+	if err := refreshMaterializedViews(ctx, conn); err != nil {
+		return fmt.Errorf("refreshMaterializedViews: %w", err)
+	}
+
+	return nil
+}
+
+// swapSchemas replaces liveSchema with rebuildSchema in a single transaction and drops the replaced schema.
+func swapSchemas(ctx context.Context, conn *pgx.Conn, liveSchema string) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	// Rollback is a no-op once the transaction is committed
+	defer tx.Rollback(ctx)
+
+	live := pgx.Identifier{liveSchema}.Sanitize()
+	replaced := pgx.Identifier{rebuildSchema + "_replaced"}.Sanitize()
+	statements := []string{
+		"ALTER SCHEMA " + live + " RENAME TO " + replaced + ";",
+		"ALTER SCHEMA " + pgx.Identifier{rebuildSchema}.Sanitize() + " RENAME TO " + live + ";",
+		"DROP SCHEMA " + replaced + " CASCADE;",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("unable to swap schemas: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// importCachedFile imports the cached xml of a distribution file.
+func importCachedFile(ctx context.Context, cache *Cache, dir, name string, conn *pgx.Conn, bulk bool) importedFile {
+	file := importedFile{dir: dir, fileName: name, startedAt: time.Now()}
+
+	entry, err := cache.entry(dir, name)
+	if err != nil {
+		file.err = fmt.Errorf("reading cache entry of %s: %w", name, err)
+		return file
+	}
+
+	blob, err := cache.openBlob(entry.XMLChecksum)
+	if err != nil {
+		file.err = fmt.Errorf("opening cached xml of %s: %w", name, err)
+		return file
+	}
+	defer blob.Close()
+
+	xmlReader := newChecksumReader(blob)
+	file.counts, err = importExport(ctx, xmlReader, name, conn, bulk, func() (fileSums, error) {
+		if sum := xmlReader.Sum(); sum != entry.XMLChecksum {
+			return fileSums{}, fmt.Errorf("cached xml is corrupt, checksum %s does not match %s", sum, entry.XMLChecksum)
+		}
+		return fileSums{size: entry.Size, checksum: entry.Checksum, xmlChecksum: entry.XMLChecksum}, nil
+	})
+	if err != nil {
+		file.err = fmt.Errorf("importing cached %s: %w", name, err)
+		return file
+	}
+
+	file.size, file.checksum = entry.Size, entry.Checksum
+	slog.Info("Cached file processed into DB", "filename", name)
+	return file
+}
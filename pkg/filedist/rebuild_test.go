@@ -0,0 +1,59 @@
+package filedist
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// rebuildConn returns a test connection, dropping a rebuild schema left by the test when it ends.
+func rebuildConn(t *testing.T) *pgx.Conn {
+	conn := testConn(t)
+	t.Cleanup(func() {
+		if _, err := conn.Exec(context.Background(), "DROP SCHEMA IF EXISTS "+pgx.Identifier{rebuildSchema}.Sanitize()+" CASCADE;"); err != nil {
+			t.Errorf("unable to drop schema %s: %v", rebuildSchema, err)
+		}
+	})
+
+	return conn
+}
+
+func TestRebuildReplacesLiveSchema(t *testing.T) {
+	conn := rebuildConn(t)
+
+	if err := Rebuild(context.Background(), conn, Config{Source: fixtureSource()}, testDDL(t)); err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM certificate;"); n != 1 {
+		t.Errorf("certificates = %d, want 1 after the dif deleted C400", n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM import_run WHERE status = $1;", importSucceeded); n != 1 {
+		t.Errorf("succeeded import runs = %d, want 1", n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM import_file WHERE status = $1;", importSucceeded); n != 2 {
+		t.Errorf("succeeded import files = %d, want 2", n)
+	}
+}
+
+func TestRebuildRecordsFailedRunInLiveSchema(t *testing.T) {
+	conn := rebuildConn(t)
+
+	src := NewMemorySource("")
+	src.Add(TotDir, "Certificate_tot_241012.xml", []byte(certificateTot[:len(certificateTot)/2]))
+	if err := Rebuild(context.Background(), conn, Config{Source: src}, testDDL(t)); err == nil {
+		t.Fatal("Rebuild succeeded with a truncated file")
+	}
+
+	// The live schema is left untouched except for the record of the failed run
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM certificate;"); n != 0 {
+		t.Errorf("certificates = %d, want the live schema untouched", n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM import_run WHERE status = $1 AND error IS NOT NULL;", importFailed); n != 1 {
+		t.Errorf("failed import runs = %d, want 1", n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM import_file f JOIN import_run r ON r.id = f.run_id WHERE f.status = $1;", importFailed); n != 1 {
+		t.Errorf("failed import files = %d, want the file of the failed run", n)
+	}
+}
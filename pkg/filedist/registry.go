@@ -0,0 +1,191 @@
+package filedist
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"tulltaxan/pkg/xmltypes"
+	"unicode"
+	"unicode/utf8"
+)
+
+// itemHandler decodes and inserts one type of element found in the <items> of a filedist export.
+type itemHandler struct {
+	element string
+	// dependsOn lists the elements that must be inserted before this one,
+	// typically the items referred to by its rows.
+	dependsOn []string
+	newBuffer func(capacity int) itemBuffer
+}
+
+// itemBuffer holds decoded elements of one type until they are inserted.
+type itemBuffer interface {
+	Decode(d *xml.Decoder, start *xml.StartElement) error
+	Len() int
+	// Flush inserts the buffered elements and empties the buffer.
+	// If bulk is set and the elements support it, they are loaded with COPY.
+	Flush(ctx context.Context, db xmltypes.DB, bulk bool) error
+}
+
+// sliceBuffer is the itemBuffer of an xmltypes collection S of elements T.
+type sliceBuffer[T any, S interface {
+	~[]T
+	xmltypes.FileDistItem
+}] struct {
+	items S
+}
+
+func (b *sliceBuffer[T, S]) Decode(d *xml.Decoder, start *xml.StartElement) error {
+	var item T
+	if err := d.DecodeElement(&item, start); err != nil {
+		return err
+	}
+	b.items = append(b.items, item)
+
+	return nil
+}
+
+func (b *sliceBuffer[T, S]) Len() int {
+	return len(b.items)
+}
+
+func (b *sliceBuffer[T, S]) Flush(ctx context.Context, db xmltypes.DB, bulk bool) error {
+	var item xmltypes.FileDistItem = b.items
+	if bulkItem, ok := item.(xmltypes.BulkFileDistItem); ok && bulk {
+		if err := bulkItem.BulkInsert(ctx, db); err != nil {
+			return fmt.Errorf("BulkInsert: %w", err)
+		}
+	} else if err := item.BatchInsert(ctx, db, insertBatchSize); err != nil {
+		return fmt.Errorf("BatchInsert: %w", err)
+	}
+
+	b.items = b.items[:0]
+	return nil
+}
+
+// handle returns the handler of element, decoding it into T and inserting it as a collection S.
+func handle[T any, S interface {
+	~[]T
+	xmltypes.FileDistItem
+}](element string, dependsOn ...string) itemHandler {
+	return itemHandler{
+		element:   element,
+		dependsOn: dependsOn,
+		newBuffer: func(capacity int) itemBuffer {
+			return &sliceBuffer[T, S]{items: make(S, 0, capacity)}
+		},
+	}
+}
+
+// registeredItems are the filedist elements that are imported. Elements not listed here are skipped.
+var registeredItems = []itemHandler{
+	handle[xmltypes.CodeType, xmltypes.CodeTypes]("codeType"),
+	handle[xmltypes.DutyExpression, xmltypes.DutyExpressions]("dutyExpression"),
+	handle[xmltypes.MeasurementUnit, xmltypes.MeasurementUnits]("measurementUnit"),
+	handle[xmltypes.MeasurementUnitQualifier, xmltypes.MeasurementUnitQualifiers]("measurementUnitQualifier"),
+	handle[xmltypes.Measurement, xmltypes.Measurements]("measurement", "measurementUnit", "measurementUnitQualifier"),
+	handle[xmltypes.MonetaryExchangePeriod, xmltypes.MonetaryExchangePeriods]("monetaryExchangePeriod"),
+	handle[xmltypes.UnquotedMonetaryExchangePeriod, xmltypes.UnquotedMonetaryExchangePeriods]("unquotedMonetaryExchangePeriod"),
+	handle[xmltypes.MeasureAction, xmltypes.MeasureActions]("measureAction"),
+	handle[xmltypes.MeasureConditionCode, xmltypes.MeasureConditionCodes]("measureConditionCode"),
+	handle[xmltypes.MeasureType, xmltypes.MeasureTypes]("measureType"),
+	handle[xmltypes.PreferenceCode, xmltypes.PreferenceCodes]("preferenceCode"),
+	handle[xmltypes.LookupTable, xmltypes.LookupTables]("lookupTable"),
+	handle[xmltypes.Footnote, xmltypes.Footnotes]("footnote"),
+	handle[xmltypes.Certificate, xmltypes.Certificates]("certificate"),
+	handle[xmltypes.GeographicalArea, xmltypes.GeographicalAreas]("geographicalArea"),
+	handle[xmltypes.BaseRegulation, xmltypes.BaseRegulations]("baseRegulation"),
+	handle[xmltypes.ModificationRegulation, xmltypes.ModificationRegulations]("modificationRegulation", "baseRegulation"),
+	handle[xmltypes.FullTemporaryStopRegulation, xmltypes.FullTemporaryStopRegulations]("fullTemporaryStopRegulation", "baseRegulation"),
+	handle[xmltypes.AdditionalCode, xmltypes.AdditionalCodes]("additionalCode", "footnote"),
+	handle[xmltypes.GoodsNomenclatureGroup, xmltypes.GoodsNomenclatureGroups]("goodsNomenclatureGroup"),
+	handle[xmltypes.GoodsNomenclature, xmltypes.GoodsNomenclatures]("goodsNomenclature", "footnote", "goodsNomenclatureGroup"),
+	handle[xmltypes.DeclarableGoodsNomenclature, xmltypes.DeclarableGoodsNomenclatures]("declarableGoodsNomenclature", "goodsNomenclature"),
+	handle[xmltypes.ExportRefundNomenclature, xmltypes.ExportRefundNomenclatures]("exportRefundNomenclature", "goodsNomenclature", "footnote"),
+	handle[xmltypes.MeursingTablePlan, xmltypes.MeursingTablePlans]("meursingTablePlan"),
+	handle[xmltypes.MeursingAdditionalCode, xmltypes.MeursingAdditionalCodes]("meursingAdditionalCode", "meursingTablePlan", "dutyExpression", "measurementUnit"),
+	handle[xmltypes.MeursingHeading, xmltypes.MeursingHeadings]("meursingHeading", "meursingTablePlan", "footnote"),
+	handle[xmltypes.MeursingSubheading, xmltypes.MeursingSubheadings]("meursingSubheading", "meursingHeading"),
+	handle[xmltypes.TaxCode, xmltypes.TaxCodes]("taxCode"),
+	handle[xmltypes.QuotaOrderNumber, xmltypes.QuotaOrderNumbers]("quotaOrderNumber", "geographicalArea"),
+	handle[xmltypes.QuotaDefinition, xmltypes.QuotaDefinitions]("quotaDefinition", "quotaOrderNumber", "measurementUnit", "measurementUnitQualifier"),
+	handle[xmltypes.QuotaBalanceEvent, xmltypes.QuotaBalanceEvents]("quotaBalanceEvent", "quotaDefinition"),
+	handle[xmltypes.QuotaCriticalEvent, xmltypes.QuotaCriticalEvents]("quotaCriticalEvent", "quotaDefinition"),
+	handle[xmltypes.QuotaExhaustionEvent, xmltypes.QuotaExhaustionEvents]("quotaExhaustionEvent", "quotaDefinition"),
+	handle[xmltypes.QuotaReopeningEvent, xmltypes.QuotaReopeningEvents]("quotaReopeningEvent", "quotaDefinition"),
+	handle[xmltypes.QuotaUnblockingEvent, xmltypes.QuotaUnblockingEvents]("quotaUnblockingEvent", "quotaDefinition"),
+	handle[xmltypes.QuotaUnsuspensionEvent, xmltypes.QuotaUnsuspensionEvents]("quotaUnsuspensionEvent", "quotaDefinition"),
+	handle[xmltypes.Measure, xmltypes.Measures]("measure",
+		"geographicalArea", "goodsNomenclature", "additionalCode", "exportRefundNomenclature", "measureType",
+		"baseRegulation", "modificationRegulation", "dutyExpression", "measurementUnit", "measurementUnitQualifier",
+		"measureAction", "measureConditionCode", "certificate", "footnote", "quotaOrderNumber", "quotaDefinition"),
+}
+
+// itemHandlers are the registered handlers by element name.
+var itemHandlers = func() map[string]itemHandler {
+	handlers := make(map[string]itemHandler, len(registeredItems))
+	for _, h := range registeredItems {
+		if _, ok := handlers[h.element]; ok {
+			panic(fmt.Sprintf("filedist: element %s registered twice", h.element))
+		}
+		handlers[h.element] = h
+	}
+	return handlers
+}()
+
+// itemOrder is the order elements are inserted in: every element comes after the elements it depends on.
+// Elements without dependencies between them keep their registration order.
+var itemOrder = func() []string {
+	order := make([]string, 0, len(registeredItems))
+	state := map[string]int{} // 1 = visiting, 2 = done
+
+	var visit func(element string, path []string)
+	visit = func(element string, path []string) {
+		h, ok := itemHandlers[element]
+		if !ok {
+			panic(fmt.Sprintf("filedist: %s depends on unregistered element %s", strings.Join(path, " -> "), element))
+		}
+		switch state[element] {
+		case 1:
+			panic(fmt.Sprintf("filedist: dependency cycle %s -> %s", strings.Join(path, " -> "), element))
+		case 2:
+			return
+		}
+
+		state[element] = 1
+		for _, dep := range h.dependsOn {
+			visit(dep, append(path, element))
+		}
+		state[element] = 2
+		order = append(order, element)
+	}
+
+	for _, h := range registeredItems {
+		visit(h.element, nil)
+	}
+	return order
+}()
+
+// itemRank maps element names to their position in itemOrder.
+var itemRank = func() map[string]int {
+	rank := make(map[string]int, len(itemOrder))
+	for i, element := range itemOrder {
+		rank[element] = i
+	}
+	return rank
+}()
+
+// fileItemRank returns the position in itemOrder of the element a tot file contains,
+// based on its name prefix, e.g. GeographicalArea_xxx_241012.xml.pgp contains geographicalArea elements.
+// Files of unknown types rank last.
+func fileItemRank(fileName string) int {
+	prefix, _, _ := strings.Cut(fileName, "_")
+	r, size := utf8.DecodeRuneInString(prefix)
+	element := string(unicode.ToLower(r)) + prefix[size:]
+
+	if rank, ok := itemRank[element]; ok {
+		return rank
+	}
+	return len(itemOrder)
+}
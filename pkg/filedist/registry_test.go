@@ -0,0 +1,46 @@
+package filedist
+
+import "testing"
+
+func TestItemOrderPutsDependenciesFirst(t *testing.T) {
+	if len(itemOrder) != len(registeredItems) {
+		t.Fatalf("itemOrder has %d elements, want the %d registered ones", len(itemOrder), len(registeredItems))
+	}
+
+	for _, h := range registeredItems {
+		for _, dep := range h.dependsOn {
+			if itemRank[dep] >= itemRank[h.element] {
+				t.Errorf("%s is inserted at %d, after %s at %d which depends on it", dep, itemRank[dep], h.element, itemRank[h.element])
+			}
+		}
+	}
+}
+
+func TestFileItemRank(t *testing.T) {
+	tests := []struct {
+		fileName string
+		element  string
+	}{
+		{"GeographicalArea_tot_241012.xml.pgp", "geographicalArea"},
+		{"Measure_tot_241012.xml.pgp", "measure"},
+		{"MeasurementUnitQualifier_tot_241012.xml.pgp", "measurementUnitQualifier"},
+		{"QuotaBalanceEvent_tot_241012.xml.pgp", "quotaBalanceEvent"},
+		{"codeType_tot_241012.xml.pgp", "codeType"},
+	}
+	for _, tt := range tests {
+		if got, want := fileItemRank(tt.fileName), itemRank[tt.element]; got != want {
+			t.Errorf("fileItemRank(%q) = %d, want %d, the rank of %s", tt.fileName, got, want, tt.element)
+		}
+	}
+
+	for _, fileName := range []string{"Unknown_tot_241012.xml.pgp", "", "_tot_241012.xml.pgp"} {
+		if got := fileItemRank(fileName); got != len(itemOrder) {
+			t.Errorf("fileItemRank(%q) = %d, want %d so unknown files rank last", fileName, got, len(itemOrder))
+		}
+	}
+
+	// Files of parents sort before the files of their dependents
+	if fileItemRank("Measure_tot_241012.xml.pgp") <= fileItemRank("GeographicalArea_tot_241012.xml.pgp") {
+		t.Error("Measure files rank before GeographicalArea files")
+	}
+}
@@ -0,0 +1,142 @@
+package filedist
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// ErrSignature is returned when a distribution file is unsigned or its signature does not verify.
+var ErrSignature = errors.New("invalid pgp signature")
+
+// loadKeyRing parses the armored public key and, if fingerprint is set, keeps only the key matching it.
+// The fingerprint is the hex encoded fingerprint of the primary key, spaces and colons are ignored.
+func loadKeyRing(pubKey, fingerprint string) (openpgp.EntityList, error) {
+	entityList, err := importPublicKey(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("importPublicKey: %w", err)
+	}
+
+	if fingerprint == "" {
+		return entityList, nil
+	}
+
+	pinned := strings.ToUpper(strings.NewReplacer(" ", "", ":", "").Replace(fingerprint))
+	for _, entity := range entityList {
+		if fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint) == pinned {
+			return openpgp.EntityList{entity}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("public key does not match pinned fingerprint %s", pinned)
+}
+
+// Function to import a public key
+func importPublicKey(pubKey string) (openpgp.EntityList, error) {
+	block, err := armor.Decode(bytes.NewReader([]byte(pubKey)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode public key: %w", err)
+	}
+
+	if block.Type != "PGP PUBLIC KEY BLOCK" {
+		return nil, fmt.Errorf("block type is not [PGP PUBLIC KEY BLOCK]")
+	}
+
+	entityList, err := openpgp.ReadKeyRing(block.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read keyring: %w", err)
+	}
+
+	return entityList, nil
+}
+
+// decryptAndExtractGzippedFile verifies the PGP signature of a gzipped file,
+// then decrypts and decompresses its contents.
+// The signature can only be checked once the whole message is read, so the returned reader
+// fails with ErrSignature instead of io.EOF if the content does not match the signature.
+func decryptAndExtractGzippedFile(keyRing openpgp.EntityList, signedFile io.Reader) (*signedReader, error) {
+	// Decode the armored signature
+	block, err := armor.Decode(signedFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode signed file: %w", err)
+	}
+
+	// Initialize pgp reader
+	md, err := openpgp.ReadMessage(block.Body, keyRing, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize pgp reader: %w", err)
+	}
+
+	if !md.IsSigned {
+		return nil, fmt.Errorf("%w: file is not signed", ErrSignature)
+	}
+	if md.SignedBy == nil {
+		return nil, fmt.Errorf("%w: signed by unknown key %X", ErrSignature, md.SignedByKeyId)
+	}
+
+	body := &verifiedReader{md: md}
+
+	// Wrap with gzip reader
+	reader, err := gzip.NewReader(body)
+	if err != nil {
+		if sigErr := body.verify(); sigErr != nil {
+			return nil, sigErr
+		}
+		return nil, fmt.Errorf("unable to initalize gzip reader: %w", err)
+	}
+
+	return &signedReader{Reader: reader, body: body}, nil
+}
+
+// signedReader reads the decompressed content of a signed distribution file.
+type signedReader struct {
+	*gzip.Reader
+	body *verifiedReader
+}
+
+// Verify reads the rest of the signed message and returns ErrSignature if its signature is invalid.
+func (r *signedReader) Verify() error {
+	return r.body.verify()
+}
+
+// verifiedReader reads the body of a signed message and checks the signature when the body is exhausted.
+type verifiedReader struct {
+	md *openpgp.MessageDetails
+}
+
+func (r *verifiedReader) Read(p []byte) (int, error) {
+	n, err := r.md.UnverifiedBody.Read(p)
+	if err == io.EOF {
+		if sigErr := r.signatureError(); sigErr != nil {
+			return n, sigErr
+		}
+	}
+
+	return n, err
+}
+
+// verify consumes the rest of the body and returns the result of the signature check.
+func (r *verifiedReader) verify() error {
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *verifiedReader) signatureError() error {
+	if r.md.SignatureError != nil {
+		return fmt.Errorf("%w: %v", ErrSignature, r.md.SignatureError)
+	}
+	if r.md.Signature == nil && r.md.SignatureV3 == nil {
+		return fmt.Errorf("%w: no signature found", ErrSignature)
+	}
+
+	return nil
+}
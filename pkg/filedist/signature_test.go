@@ -0,0 +1,279 @@
+package filedist
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// newTestKey generates a signing key and returns it with its armored public key.
+func newTestKey(t *testing.T, name string) (*openpgp.Entity, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatalf("NewEntity: %v", err)
+	}
+
+	var pubKey bytes.Buffer
+	w, err := armor.Encode(&pubKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("armor.Encode: %v", err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing armor: %v", err)
+	}
+
+	// Without hash preferences messages are signed with RIPEMD-160, which is not compiled in
+	for _, identity := range entity.Identities {
+		identity.SelfSignature.PreferredHash = []uint8{8} // SHA-256
+	}
+
+	return entity, pubKey.String()
+}
+
+// gzipped returns content compressed like the distribution files.
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// signedMessage returns the binary pgp message of payload signed by signer, or a message without signature if
+// signer is nil.
+func signedMessage(t *testing.T, signer *openpgp.Entity, payload []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	if signer != nil {
+		w, err = openpgp.Sign(&buf, signer, nil, nil)
+	} else {
+		w, err = packet.SerializeLiteral(nopWriteCloser{&buf}, true, "", 0)
+	}
+	if err != nil {
+		t.Fatalf("writing message: %v", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		t.Fatalf("writing message: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing message: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// armored returns message armored like the distribution files.
+func armored(t *testing.T, message []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, "PGP MESSAGE", nil)
+	if err != nil {
+		t.Fatalf("armor.Encode: %v", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		t.Fatalf("armoring message: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing armor: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// tampered returns a copy of message with the modification time in the gzip header of payload changed.
+// The content still decompresses, so only the signature tells that it was changed.
+func tampered(t *testing.T, message, payload []byte) []byte {
+	t.Helper()
+
+	// The literal data may be split into partial packets, but the gzip header is at the start of the first one
+	i := bytes.Index(message, payload[:10])
+	if i < 0 {
+		t.Fatal("gzip header not found in message")
+	}
+
+	changed := bytes.Clone(message)
+	changed[i+4] ^= 0xff
+	return changed
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// readVerified reads a decrypted file like importFile does: a read error is checked against the signature,
+// since tampered content usually fails to decompress before the signature is checked.
+func readVerified(r io.ReadCloser) ([]byte, error) {
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil && !errors.Is(err, ErrSignature) {
+		if verifier, ok := r.(interface{ Verify() error }); ok {
+			if sigErr := verifier.Verify(); sigErr != nil {
+				return nil, sigErr
+			}
+		}
+	}
+
+	return content, err
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	entity, pubKey := newTestKey(t, "distribution")
+	fingerprint := fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+
+	// Fingerprints are often written in lower case groups separated by spaces or colons
+	var grouped []string
+	for i := 0; i < len(fingerprint); i += 4 {
+		grouped = append(grouped, strings.ToLower(fingerprint[i:i+4]))
+	}
+
+	tests := []struct {
+		name        string
+		fingerprint string
+		wantErr     bool
+	}{
+		{"not pinned", "", false},
+		{"pinned", fingerprint, false},
+		{"pinned with spaces", strings.Join(grouped, " "), false},
+		{"pinned with colons", strings.Join(grouped, ":"), false},
+		{"wrong fingerprint", strings.Repeat("AB", 20), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyRing, err := loadKeyRing(pubKey, tt.fingerprint)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("loadKeyRing accepted a key not matching the pinned fingerprint")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadKeyRing: %v", err)
+			}
+			if len(keyRing) != 1 || keyRing[0].PrimaryKey.KeyId != entity.PrimaryKey.KeyId {
+				t.Errorf("loadKeyRing returned %d keys, want the distribution key", len(keyRing))
+			}
+		})
+	}
+}
+
+func TestDecryptedReaderVerifiesSignature(t *testing.T) {
+	signer, pubKey := newTestKey(t, "distribution")
+	other, _ := newTestKey(t, "other")
+	keyRing, err := loadKeyRing(pubKey, "")
+	if err != nil {
+		t.Fatalf("loadKeyRing: %v", err)
+	}
+
+	const content = "<export><items/></export>"
+	payload := gzipped(t, content)
+	signed := signedMessage(t, signer, payload)
+
+	tests := []struct {
+		name string
+		file []byte
+		// wantErr is ErrSignature for files that must be quarantined, nil for files that are imported
+		wantErr error
+	}{
+		{"signed", armored(t, signed), nil},
+		{"unsigned", armored(t, signedMessage(t, nil, payload)), ErrSignature},
+		{"signed by another key", armored(t, signedMessage(t, other, payload)), ErrSignature},
+		{"tampered payload", armored(t, tampered(t, signed, payload)), ErrSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := decryptedReader("Certificate_tot_241012.xml.pgp", bytes.NewReader(tt.file), keyRing)
+			if err == nil {
+				var got []byte
+				got, err = readVerified(r)
+				if err == nil && string(got) != content {
+					t.Errorf("content = %q, want %q", got, content)
+				}
+			}
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("reading a validly signed file: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecryptedReaderRequiresKey(t *testing.T) {
+	_, err := decryptedReader("Certificate_tot_241012.xml.pgp", strings.NewReader(""), nil)
+	if err == nil {
+		t.Fatal("decryptedReader read a pgp file without a key")
+	}
+}
+
+func TestImportQuarantinesInvalidSignatures(t *testing.T) {
+	conn := testConn(t)
+
+	signer, pubKey := newTestKey(t, "distribution")
+	other, _ := newTestKey(t, "other")
+	payload := gzipped(t, certificateTot)
+	signed := signedMessage(t, signer, payload)
+
+	src := NewMemorySource(pubKey)
+	src.Add(TotDir, "Certificate_unsigned_241010.xml.pgp", armored(t, signedMessage(t, nil, payload)))
+	src.Add(TotDir, "Certificate_other_241011.xml.pgp", armored(t, signedMessage(t, other, payload)))
+	src.Add(TotDir, "Certificate_tampered_241012.xml.pgp", armored(t, tampered(t, signed, payload)))
+
+	cfg := Config{Source: src, KeyFingerprint: fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint)}
+	if err := performDbMaintenance(cfg, conn); err == nil {
+		t.Fatal("performDbMaintenance succeeded with invalid signatures")
+	}
+
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM quarantined_files;"); n != 3 {
+		t.Errorf("quarantined files = %d, want 3", n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM import_file WHERE status = $1;", importQuarantined); n != 3 {
+		t.Errorf("import files with status %s = %d, want 3", importQuarantined, n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM certificate;"); n != 0 {
+		t.Errorf("certificates = %d, want nothing imported from rejected files", n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM inserted_files;"); n != 0 {
+		t.Errorf("inserted files = %d, want 0", n)
+	}
+
+	// Quarantined files are never retried, and a validly signed file is imported
+	src.Add(TotDir, "Certificate_signed_241013.xml.pgp", armored(t, signed))
+	if err := performDbMaintenance(cfg, conn); err != nil {
+		t.Fatalf("performDbMaintenance: %v", err)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM certificate;"); n != 2 {
+		t.Errorf("certificates = %d, want 2 from the signed file", n)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM import_file;"); n != 4 {
+		t.Errorf("import files = %d, want the quarantined files to be skipped", n)
+	}
+}
@@ -0,0 +1,279 @@
+package filedist
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Distribution directories on the Tullverket server.
+const (
+	TotDir = "tot"
+	DifDir = "dif"
+)
+
+// Default locations of the Tullverket file distribution.
+const (
+	DefaultDistURL   = "https://distr.tullverket.se/tulltaxan/xml/"
+	DefaultPubKeyURL = "https://distr.tullverket.se/tulltaxan/Tulltaxan_Fildistribution.asc"
+)
+
+// localPublicKeyFile is the name of the signing key looked for in the root of a local distribution.
+const localPublicKeyFile = "Tulltaxan_Fildistribution.asc"
+
+// Source is a filedist distribution that files can be listed and read from.
+type Source interface {
+	// List returns the names of the files available in dir (TotDir or DifDir).
+	List(ctx context.Context, dir string) ([]string, error)
+	// Open returns the raw content of a file returned by List.
+	// Files ending in .pgp are PGP-armored gzip files, .xml files are already decrypted.
+	Open(ctx context.Context, dir, name string) (io.ReadCloser, error)
+	// PublicKey returns the armored public key the distributed files are signed with.
+	PublicKey(ctx context.Context) (string, error)
+}
+
+// HTTPSource reads files from a web server serving directory listings, such as distr.tullverket.se or a mirror of it.
+type HTTPSource struct {
+	BaseURL   string // URL containing the tot and dif directories
+	PubKeyURL string
+	Client    *http.Client
+	// Retry is how failed requests are retried. The zero value makes a single attempt.
+	Retry RetryPolicy
+	// ReadTimeout is how long a download may go without receiving data before it is resumed, defaults to one minute.
+	ReadTimeout time.Duration
+}
+
+// NewHTTPSource returns a source reading from the given base and public key URLs.
+// Requests time out when the server does not respond and are retried according to DefaultRetry.
+func NewHTTPSource(baseURL, pubKeyURL string) *HTTPSource {
+	return &HTTPSource{BaseURL: baseURL, PubKeyURL: pubKeyURL, Client: newHTTPClient(), Retry: DefaultRetry}
+}
+
+// NewTullverketSource returns a source reading from the official Tullverket distribution.
+func NewTullverketSource() *HTTPSource {
+	return NewHTTPSource(DefaultDistURL, DefaultPubKeyURL)
+}
+
+func (s *HTTPSource) List(ctx context.Context, dir string) ([]string, error) {
+	dirUrl, err := url.JoinPath(s.BaseURL, dir, "/")
+	if err != nil {
+		return nil, fmt.Errorf("unable to construct valid URL for dir %s: %w", dir, err)
+	}
+
+	body, err := s.get(ctx, dirUrl)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get file list, url: [%v] err: %w", dirUrl, err)
+	}
+	defer body.Close()
+
+	// Parse the HTML content for pgp files
+	anchors, err := parseHtmlForPgpAnchors(html.NewTokenizer(body))
+	if err != nil {
+		return nil, fmt.Errorf("parseHtmlForPgpAnchors: %w", err)
+	}
+
+	fileList := make([]string, 0, len(anchors))
+	for _, anchor := range anchors {
+		fileList = append(fileList, path.Base(anchor))
+	}
+
+	return fileList, nil
+}
+
+func (s *HTTPSource) Open(ctx context.Context, dir, name string) (io.ReadCloser, error) {
+	fileUrl, err := url.JoinPath(s.BaseURL, dir, name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to construct valid URL for file %s: %w", name, err)
+	}
+
+	return s.get(ctx, fileUrl)
+}
+
+func (s *HTTPSource) PublicKey(ctx context.Context) (string, error) {
+	body, err := s.get(ctx, s.PubKeyURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download public key: %w", err)
+	}
+	defer body.Close()
+
+	key, err := io.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read public key response: %w", err)
+	}
+
+	return string(key), nil
+}
+
+// get performs a GET request and returns the body of a 200 response.
+// Failed requests are retried, and the body resumes the download where it was interrupted if reading it fails.
+func (s *HTTPSource) get(ctx context.Context, rawUrl string) (io.ReadCloser, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	readTimeout := s.ReadTimeout
+	if readTimeout <= 0 {
+		readTimeout = defaultReadTimeout
+	}
+
+	return startDownload(ctx, client, rawUrl, s.Retry, readTimeout)
+}
+
+// FSSource reads files from a file system, typically a local directory or zip archive.
+// Files are read from the tot and dif subdirectories. If neither exists, all files are read from the root
+// and files prefixed with "Incremental" are treated as dif files.
+type FSSource struct {
+	FS     fs.FS
+	closer io.Closer
+}
+
+// NewFSSource returns a source reading from fsys.
+func NewFSSource(fsys fs.FS) *FSSource {
+	return &FSSource{FS: fsys}
+}
+
+// OpenLocalSource returns a source reading from a local directory or, if path ends in .zip, a zip archive.
+// The source must be closed when no longer used.
+func OpenLocalSource(dir string) (*FSSource, error) {
+	if !strings.EqualFold(path.Ext(dir), ".zip") {
+		return NewFSSource(os.DirFS(dir)), nil
+	}
+
+	archive, err := zip.OpenReader(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open archive %s: %w", dir, err)
+	}
+
+	return &FSSource{FS: archive, closer: archive}, nil
+}
+
+// Close releases the underlying archive, if any.
+func (s *FSSource) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+func (s *FSSource) List(ctx context.Context, dir string) ([]string, error) {
+	flat := !dirExists(s.FS, TotDir) && !dirExists(s.FS, DifDir)
+	listDir := dir
+	if flat {
+		listDir = "."
+	} else if !dirExists(s.FS, dir) {
+		return nil, nil
+	}
+
+	entries, err := fs.ReadDir(s.FS, listDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list directory %s: %w", listDir, err)
+	}
+
+	fileList := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !isDistFile(entry.Name()) {
+			continue
+		}
+		if flat && isIncrementalFile(entry.Name()) != (dir == DifDir) {
+			continue
+		}
+		fileList = append(fileList, entry.Name())
+	}
+
+	return fileList, nil
+}
+
+func (s *FSSource) Open(ctx context.Context, dir, name string) (io.ReadCloser, error) {
+	if !dirExists(s.FS, TotDir) && !dirExists(s.FS, DifDir) {
+		dir = "."
+	}
+
+	return s.FS.Open(path.Join(dir, name))
+}
+
+// PublicKey reads Tulltaxan_Fildistribution.asc from the root of the file system.
+// A missing key is not an error since decrypted xml files can be imported without it.
+func (s *FSSource) PublicKey(ctx context.Context) (string, error) {
+	key, err := fs.ReadFile(s.FS, localPublicKeyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return string(key), nil
+}
+
+// MemorySource serves files held in memory, such as the fixtures of the import tests.
+type MemorySource struct {
+	Files map[string]map[string][]byte // dir -> file name -> content
+	Key   string
+}
+
+// NewMemorySource returns an empty in-memory source signed with key.
+func NewMemorySource(key string) *MemorySource {
+	return &MemorySource{Files: map[string]map[string][]byte{}, Key: key}
+}
+
+// Add stores a file in dir.
+func (s *MemorySource) Add(dir, name string, content []byte) {
+	if s.Files[dir] == nil {
+		s.Files[dir] = map[string][]byte{}
+	}
+	s.Files[dir][name] = content
+}
+
+func (s *MemorySource) List(ctx context.Context, dir string) ([]string, error) {
+	fileList := make([]string, 0, len(s.Files[dir]))
+	for name := range s.Files[dir] {
+		fileList = append(fileList, name)
+	}
+	sort.Strings(fileList)
+
+	return fileList, nil
+}
+
+func (s *MemorySource) Open(ctx context.Context, dir, name string) (io.ReadCloser, error) {
+	content, ok := s.Files[dir][name]
+	if !ok {
+		return nil, fmt.Errorf("file %s/%s: %w", dir, name, fs.ErrNotExist)
+	}
+
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *MemorySource) PublicKey(ctx context.Context) (string, error) {
+	return s.Key, nil
+}
+
+// isDistFile reports whether name is a PGP-armored or decrypted filedist file.
+func isDistFile(name string) bool {
+	ext := path.Ext(name)
+	return ext == ".pgp" || ext == ".xml"
+}
+
+// isIncrementalFile reports whether name is a dif file, which are all named IncrementalObjectTraderExport_*.
+func isIncrementalFile(name string) bool {
+	return strings.HasPrefix(name, "Incremental")
+}
+
+// dirExists reports whether name is a directory in fsys.
+func dirExists(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,211 @@
+package filedist
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"tulltaxan/pkg/xmltypes"
+)
+
+const (
+	// itemChunkSize is the number of elements of one type held in memory before they are inserted.
+	itemChunkSize = 1000
+	// bulkItemChunkSize is the chunk size used when loading with COPY, where larger chunks pay off.
+	bulkItemChunkSize = 10000
+	// insertBatchSize is the number of statements sent to the database per batch.
+	insertBatchSize = 10000
+)
+
+// streamItems decodes the <items> of a filedist export token by token and inserts them in chunks of
+// itemChunkSize elements per type, so memory use does not depend on the size of the file.
+// Elements are dispatched through the item registry. Before a chunk is inserted, the pending elements it
+// depends on are inserted, and the remaining elements are inserted in dependency order at the end of the file.
+//
+// Changes in dif files are wrapped in <record> envelopes. Those are applied in document order instead: pending
+// elements are inserted whenever the type of element changes, so a change is never applied before an earlier one.
+// The attributes of every record are stored in dif_record.
+//
+// If bulk is set, types implementing xmltypes.BulkFileDistItem are loaded with COPY instead of upserted row by row.
+// It returns the number of items read per element name.
+func streamItems(ctx context.Context, r io.Reader, db xmltypes.DB, fileName string, bulk bool) (map[string]itemCount, error) {
+	decoder := xml.NewDecoder(r)
+
+	chunkSize := itemChunkSize
+	if bulk {
+		chunkSize = bulkItemChunkSize
+	}
+
+	// Pending elements per element name
+	buffers := map[string]itemBuffer{}
+	// Number of skipped elements per unregistered element name
+	skipped := map[string]int{}
+	// Number of upserted and deleted items per element name
+	counts := map[string]itemCount{}
+
+	// Envelope of the change being read, nil outside of <record> elements
+	var record *xmltypes.Record
+	records := make(xmltypes.Records, 0, chunkSize)
+	// Number of changes read from records
+	position := 0
+	// Element type of the latest change read from a record
+	lastRecordElement := ""
+
+	flushRecords := func() error {
+		if len(records) == 0 {
+			return nil
+		}
+		if err := records.BatchInsert(ctx, db, insertBatchSize); err != nil {
+			return fmt.Errorf("inserting records: %w", err)
+		}
+		records = records[:0]
+		return nil
+	}
+
+	var flush func(element string) error
+	flush = func(element string) error {
+		buffer, ok := buffers[element]
+		if !ok || buffer.Len() == 0 {
+			return nil
+		}
+
+		for _, dep := range itemHandlers[element].dependsOn {
+			if err := flush(dep); err != nil {
+				return err
+			}
+		}
+
+		slog.Debug("Inserting struct values", "element", element, "count", buffer.Len())
+		if err := buffer.Flush(ctx, db, bulk); err != nil {
+			return fmt.Errorf("inserting %s: %w", element, err)
+		}
+
+		return nil
+	}
+
+	inItems := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read xml token: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if !inItems {
+				inItems = t.Name.Local == "items"
+				continue
+			}
+
+			if t.Name.Local == "record" && record == nil {
+				envelope := xmltypes.NewRecord(fileName, t)
+				record = &envelope
+				continue
+			}
+
+			if record != nil {
+				position++
+				change := *record
+				change.Position = position
+				change.Element = t.Name.Local
+				change.ChangeType = attrValue(t, "changeType")
+				records = append(records, change)
+
+				if len(records) >= chunkSize {
+					if err := flushRecords(); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			handler, ok := itemHandlers[t.Name.Local]
+			if !ok {
+				skipped[t.Name.Local]++
+				if err := decoder.Skip(); err != nil {
+					return nil, fmt.Errorf("unable to skip element %s: %w", t.Name.Local, err)
+				}
+				continue
+			}
+
+			// Changes are applied in the order of the records, so elements read before this one go first
+			if record != nil {
+				if lastRecordElement != "" && lastRecordElement != handler.element {
+					if err := flush(lastRecordElement); err != nil {
+						return nil, err
+					}
+				}
+				lastRecordElement = handler.element
+			}
+
+			count := counts[handler.element]
+			if attrValue(t, "changeType") == "D" {
+				count.Deleted++
+			} else {
+				count.Upserted++
+			}
+			counts[handler.element] = count
+
+			buffer, ok := buffers[handler.element]
+			if !ok {
+				buffer = handler.newBuffer(chunkSize)
+				buffers[handler.element] = buffer
+			}
+
+			if err := buffer.Decode(decoder, &t); err != nil {
+				return nil, fmt.Errorf("unable to decode element %s: %w", t.Name.Local, err)
+			}
+
+			if buffer.Len() >= chunkSize {
+				if err := flush(handler.element); err != nil {
+					return nil, err
+				}
+			}
+
+		case xml.EndElement:
+			if inItems && t.Name.Local == "items" {
+				inItems = false
+			}
+			if record != nil && t.Name.Local == "record" {
+				record = nil
+			}
+		}
+	}
+
+	for element, count := range skipped {
+		slog.Warn("Skipped unsupported filedist elements", "element", element, "count", count)
+	}
+
+	// Insert the remaining elements in dependency order. Elements of dif files are already in order, since
+	// only elements of the latest type can be pending.
+	for _, element := range itemOrder {
+		if err := flush(element); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := flushRecords(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// itemCount is the number of items of one element type read from a file.
+type itemCount struct {
+	Upserted int `json:"upserted"`
+	Deleted  int `json:"deleted"`
+}
+
+// attrValue returns the value of the attribute name of an element, or "" if it has none.
+func attrValue(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
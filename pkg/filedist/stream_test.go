@@ -0,0 +1,444 @@
+package filedist
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"tulltaxan/pkg/xmltypes"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// discardDB accepts every statement without a database, so decoding is measured on its own.
+// It counts the batches it is sent.
+type discardDB struct {
+	batches int
+}
+
+func (db *discardDB) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (db *discardDB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	db.batches++
+	return discardBatch{}
+}
+
+func (db *discardDB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	var n int64
+	for rowSrc.Next() {
+		if _, err := rowSrc.Values(); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rowSrc.Err()
+}
+
+type discardBatch struct{}
+
+func (discardBatch) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, nil }
+func (discardBatch) Query() (pgx.Rows, error)         { return nil, errors.New("not supported") }
+func (discardBatch) QueryRow() pgx.Row                { return nil }
+func (discardBatch) Close() error                     { return nil }
+
+// writeMeasureTot writes a tot export of n measures with components, conditions and footnotes.
+func writeMeasureTot(w io.Writer, n int) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, `<?xml version="1.0" encoding="UTF-8"?>
+<export>
+	<id>1</id>
+	<exportType>tot</exportType>
+	<items>
+`)
+	for sid := 1; sid <= n; sid++ {
+		fmt.Fprintf(bw, `		<measure SID="%d" SIDGeographicalArea="1" SIDGoodsNomenclature="%d" changeType="U" dateStart="2020-01-01" geographicalAreaId="1011" goodsNomenclatureCode="%010d" measureType="103" national="0" regulationId="R8726580" regulationRoleType="1" stoppedFlag="0">
+			<measureComponent dutyAmount="12.8" dutyExpressionId="1" national="0"/>
+			<measureComponent dutyAmount="176.8" dutyExpressionId="4" measurementUnitCode="DTN" monetaryUnitCode="EUR" national="0"/>
+			<measureCondition SID="%d" actionCode="01" certificateCode="400" certificateType="C" conditionCodeId="B" national="0" sequenceNumber="1"/>
+			<measureCondition SID="%d" actionCode="09" conditionCodeId="B" national="0" sequenceNumber="2"/>
+			<measureFootnoteAssociation footnoteId="001" footnoteType="TM" national="0"/>
+		</measure>
+`, sid, sid, 100000000+sid, 2*sid, 2*sid+1)
+	}
+	fmt.Fprint(bw, `	</items>
+</export>
+`)
+
+	return bw.Flush()
+}
+
+// benchmarkMeasures is the number of measures of the benchmark fixture, about as many as the measure tot file.
+const benchmarkMeasures = 100000
+
+var (
+	fixtureOnce sync.Once
+	fixturePath string
+	fixtureErr  error
+)
+
+// measureTotFixture returns the path of a generated tot file of benchmarkMeasures measures.
+// The file is written once per test binary and removed when it exits.
+func measureTotFixture(b *testing.B) string {
+	b.Helper()
+
+	fixtureOnce.Do(func() {
+		var dir string
+		dir, fixtureErr = os.MkdirTemp("", "filedist-bench")
+		if fixtureErr != nil {
+			return
+		}
+		fixturePath = filepath.Join(dir, "Measure_tot_241012.xml")
+
+		var f *os.File
+		f, fixtureErr = os.Create(fixturePath)
+		if fixtureErr != nil {
+			return
+		}
+		defer f.Close()
+		fixtureErr = writeMeasureTot(f, benchmarkMeasures)
+	})
+	if fixtureErr != nil {
+		b.Fatalf("writing fixture: %v", fixtureErr)
+	}
+
+	return fixturePath
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if fixturePath != "" {
+		os.RemoveAll(filepath.Dir(fixturePath))
+	}
+	os.Exit(code)
+}
+
+// peakHeap samples the bytes of live heap objects until stop is called, which returns the largest sample.
+func peakHeap() (stop func() uint64) {
+	samples := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	var peak uint64
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			metrics.Read(samples)
+			peak = max(peak, samples[0].Value.Uint64())
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() uint64 {
+		close(done)
+		<-finished
+		return peak
+	}
+}
+
+// decodeDocument imports an export the way files were imported before streamItems: the whole document is
+// decoded into memory before anything is inserted.
+func decodeDocument(ctx context.Context, r io.Reader, db xmltypes.DB) error {
+	export := new(xmltypes.Export)
+	if err := xml.NewDecoder(r).Decode(export); err != nil {
+		return fmt.Errorf("unable to decode export: %w", err)
+	}
+
+	return export.Items.Measures.BatchInsert(ctx, db, insertBatchSize)
+}
+
+// BenchmarkDecodeTot compares the peak heap of streaming a large tot file with decoding it as a whole.
+// Run with go test -bench DecodeTot -benchtime 1x ./pkg/filedist, peak-heap-MB is the metric to compare.
+func BenchmarkDecodeTot(b *testing.B) {
+	path := measureTotFixture(b)
+	ctx := context.Background()
+
+	imports := []struct {
+		name string
+		run  func(r io.Reader, db xmltypes.DB) error
+	}{
+		{"stream", func(r io.Reader, db xmltypes.DB) error {
+			_, err := streamItems(ctx, r, db, filepath.Base(path), false)
+			return err
+		}},
+		{"document", func(r io.Reader, db xmltypes.DB) error {
+			return decodeDocument(ctx, r, db)
+		}},
+	}
+	for _, imp := range imports {
+		b.Run(imp.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				f, err := os.Open(path)
+				if err != nil {
+					b.Fatal(err)
+				}
+				runtime.GC()
+
+				stop := peakHeap()
+				err = imp.run(f, &discardDB{})
+				peak = max(peak, stop())
+				f.Close()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+		})
+	}
+}
+
+func TestStreamItemsInsertsInChunks(t *testing.T) {
+	const n = 2*itemChunkSize + itemChunkSize/2
+
+	var fixture strings.Builder
+	if err := writeMeasureTot(&fixture, n); err != nil {
+		t.Fatal(err)
+	}
+
+	db := &discardDB{}
+	counts, err := streamItems(context.Background(), strings.NewReader(fixture.String()), db, "Measure_tot_241012.xml", false)
+	if err != nil {
+		t.Fatalf("streamItems: %v", err)
+	}
+
+	if got := counts["measure"]; got != (itemCount{Upserted: n}) {
+		t.Errorf("counts = %+v, want %d upserted measures", got, n)
+	}
+	// Measures are inserted every itemChunkSize elements, one batch per chunk, instead of once the whole file is read
+	if db.batches != 3 {
+		t.Errorf("batches = %d, want 3", db.batches)
+	}
+}
+
+// orderedCertificateDif is a decrypted dif file whose changes only give the expected result in document order:
+// C400 is deleted and created again, N954 is updated and then deleted, and Y001 is created.
+const orderedCertificateDif = `<?xml version="1.0" encoding="UTF-8"?>
+<export>
+	<id>2</id>
+	<exportType>dif</exportType>
+	<items>
+		<record recordType="certificate" transactionId="1" sequenceNumber="1">
+			<certificate certificateCode="400" certificateType="C" changeType="D" national="0"/>
+		</record>
+		<record recordType="certificate" transactionId="1" sequenceNumber="2">
+			<certificate certificateCode="954" certificateType="N" changeType="U" dateStart="2000-01-01" dateEnd="2024-12-31" national="0"/>
+		</record>
+		<record recordType="certificate" transactionId="2" sequenceNumber="1">
+			<certificate certificateCode="400" certificateType="C" changeType="U" dateStart="2025-01-01" national="0"/>
+		</record>
+		<record recordType="certificate" transactionId="2" sequenceNumber="2">
+			<certificate certificateCode="954" certificateType="N" changeType="D" national="0"/>
+		</record>
+		<record recordType="certificate" transactionId="3" sequenceNumber="1">
+			<certificate certificateCode="001" certificateType="Y" changeType="U" dateStart="2025-01-01" national="0"/>
+		</record>
+	</items>
+</export>`
+
+func TestStreamItemsAppliesRecordsInDocumentOrder(t *testing.T) {
+	conn := testConn(t)
+	ctx := context.Background()
+
+	if _, err := streamItems(ctx, strings.NewReader(certificateTot), conn, "Certificate_tot_241012.xml", false); err != nil {
+		t.Fatalf("streamItems tot: %v", err)
+	}
+	const difFile = "IncrementalObjectTraderExport_241013.xml"
+	counts, err := streamItems(ctx, strings.NewReader(orderedCertificateDif), conn, difFile, false)
+	if err != nil {
+		t.Fatalf("streamItems dif: %v", err)
+	}
+	if got := counts["certificate"]; got != (itemCount{Upserted: 3, Deleted: 2}) {
+		t.Errorf("counts = %+v, want 3 upserted and 2 deleted certificates", got)
+	}
+
+	rows, err := conn.Query(ctx, "SELECT certificate_type || certificate_code || ' ' || date_start::TEXT FROM certificate ORDER BY 1;")
+	if err != nil {
+		t.Fatalf("query certificates: %v", err)
+	}
+	certificates, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatalf("collect certificates: %v", err)
+	}
+	if want := []string{"C400 2025-01-01", "Y001 2025-01-01"}; !slices.Equal(certificates, want) {
+		t.Errorf("certificates = %v, want %v", certificates, want)
+	}
+
+	type difRecord struct {
+		Position   int
+		Element    string
+		ChangeType string
+		Attributes map[string]string
+	}
+	rows, err = conn.Query(ctx, "SELECT position, element, change_type, attributes FROM dif_record WHERE file_name = $1 ORDER BY position;", difFile)
+	if err != nil {
+		t.Fatalf("query dif records: %v", err)
+	}
+	records, err := pgx.CollectRows(rows, pgx.RowToStructByPos[difRecord])
+	if err != nil {
+		t.Fatalf("collect dif records: %v", err)
+	}
+
+	changeTypes := []string{"D", "U", "U", "D", "U"}
+	if len(records) != len(changeTypes) {
+		t.Fatalf("dif records = %d, want %d", len(records), len(changeTypes))
+	}
+	for i, record := range records {
+		if record.Position != i+1 || record.Element != "certificate" || record.ChangeType != changeTypes[i] {
+			t.Errorf("dif record %d = %d %s %s, want %d certificate %s", i, record.Position, record.Element, record.ChangeType, i+1, changeTypes[i])
+		}
+		if record.Attributes["recordType"] != "certificate" || record.Attributes["transactionId"] == "" || record.Attributes["sequenceNumber"] == "" {
+			t.Errorf("dif record %d attributes = %v, want those of its <record> element", i, record.Attributes)
+		}
+	}
+}
+
+// measureChildrenTot is a decrypted tot file of two measures with one child of every kind or more.
+const measureChildrenTot = `<?xml version="1.0" encoding="UTF-8"?>
+<export>
+	<id>1</id>
+	<exportType>tot</exportType>
+	<items>
+		<measure SID="1" SIDGeographicalArea="1" SIDGoodsNomenclature="1" changeType="U" dateStart="2020-01-01" geographicalAreaId="1011" goodsNomenclatureCode="0100000001" measureType="103" national="0" regulationId="R8726580" regulationRoleType="1" stoppedFlag="0">
+			<measureComponent dutyAmount="12.8" dutyExpressionId="1" national="0"/>
+			<measureComponent dutyAmount="176.8" dutyExpressionId="4" measurementUnitCode="DTN" monetaryUnitCode="EUR" national="0"/>
+			<measureCondition SID="1" actionCode="01" certificateCode="400" certificateType="C" conditionCodeId="B" national="0" sequenceNumber="1"/>
+			<measureCondition SID="2" actionCode="09" conditionCodeId="B" national="0" sequenceNumber="2"/>
+			<measureExcludedGeographicalArea SIDGeographicalArea="2" geographicalAreaId="CN" national="0"/>
+			<measureFootnoteAssociation footnoteId="001" footnoteType="TM" national="0"/>
+			<measurePartialTemporaryStop national="0" regulationId="R2400010" regulationRoleType="3"/>
+		</measure>
+		<measure SID="2" SIDGeographicalArea="1" SIDGoodsNomenclature="2" changeType="U" dateStart="2020-01-01" geographicalAreaId="1011" goodsNomenclatureCode="0100000002" measureType="103" national="0" regulationId="R8726580" regulationRoleType="1" stoppedFlag="0">
+			<measureComponent dutyAmount="5" dutyExpressionId="1" national="0"/>
+			<measureCondition SID="3" actionCode="01" certificateCode="400" certificateType="C" conditionCodeId="B" national="0" sequenceNumber="1"/>
+			<measureFootnoteAssociation footnoteId="001" footnoteType="TM" national="0"/>
+		</measure>
+	</items>
+</export>`
+
+// measureChildrenDif is a decrypted dif file replacing the children of measure 1 and deleting measure 2.
+const measureChildrenDif = `<?xml version="1.0" encoding="UTF-8"?>
+<export>
+	<id>2</id>
+	<exportType>dif</exportType>
+	<items>
+		<record recordType="measure">
+			<measure SID="1" SIDGeographicalArea="1" SIDGoodsNomenclature="1" changeType="U" dateStart="2020-01-01" geographicalAreaId="1011" goodsNomenclatureCode="0100000001" measureType="103" national="0" regulationId="R8726580" regulationRoleType="1" stoppedFlag="0">
+				<measureComponent dutyAmount="10" dutyExpressionId="1" national="0"/>
+				<measureCondition SID="2" actionCode="09" conditionCodeId="B" national="0" sequenceNumber="1"/>
+			</measure>
+		</record>
+		<record recordType="measure">
+			<measure SID="2" SIDGeographicalArea="1" SIDGoodsNomenclature="2" changeType="D" national="0"/>
+		</record>
+	</items>
+</export>`
+
+func TestStreamItemsReplacesChildren(t *testing.T) {
+	conn := testConn(t)
+	ctx := context.Background()
+
+	if _, err := streamItems(ctx, strings.NewReader(measureChildrenTot), conn, "Measure_tot_241012.xml", false); err != nil {
+		t.Fatalf("streamItems tot: %v", err)
+	}
+	if _, err := streamItems(ctx, strings.NewReader(measureChildrenDif), conn, "IncrementalObjectTraderExport_241013.xml", false); err != nil {
+		t.Fatalf("streamItems dif: %v", err)
+	}
+
+	// Measure 1 keeps only the children of the update, and nothing of the deleted measure 2 is left
+	for _, child := range []struct {
+		table string
+		want  int
+	}{
+		{"measure_component", 1},
+		{"measure_condition", 1},
+		{"measure_excluded_geographical_area", 0},
+		{"measure_footnote_association", 0},
+		{"measure_partial_temporary_stop", 0},
+	} {
+		if n := queryInt(t, conn, "SELECT COUNT(*) FROM "+child.table+" WHERE parent_sid = 1;"); n != child.want {
+			t.Errorf("%s rows of measure 1 = %d, want %d", child.table, n, child.want)
+		}
+		if n := queryInt(t, conn, "SELECT COUNT(*) FROM "+child.table+" WHERE parent_sid = 2;"); n != 0 {
+			t.Errorf("%s rows of deleted measure 2 = %d, want 0", child.table, n)
+		}
+	}
+
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM measure_component WHERE parent_sid = 1 AND duty_expression_id = 1 AND duty_amount = 10;"); n != 1 {
+		t.Error("the component of measure 1 was not updated")
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM measure_condition WHERE parent_sid = 1 AND sid = 2 AND sequence_number = 1;"); n != 1 {
+		t.Error("the condition of measure 1 was not updated")
+	}
+}
+
+// writtenOrderTot is a decrypted tot file of a measure whose components are not written in the order of their
+// duty expression ids.
+const writtenOrderTot = `<?xml version="1.0" encoding="UTF-8"?>
+<export>
+	<id>1</id>
+	<exportType>tot</exportType>
+	<items>
+		<measure SID="1" SIDGeographicalArea="1" SIDGoodsNomenclature="1" changeType="U" dateStart="2020-01-01" geographicalAreaId="1011" goodsNomenclatureCode="0100000001" measureType="103" national="0" regulationId="R8726580" regulationRoleType="1" stoppedFlag="0">
+			<measureComponent dutyAmount="8" dutyExpressionId="1" national="0"/>
+			<measureComponent dutyAmount="18.7" dutyExpressionId="17" national="0"/>
+			<measureComponent dutyExpressionId="12" national="0"/>
+		</measure>
+	</items>
+</export>`
+
+func TestStreamItemsKeepsComponentOrder(t *testing.T) {
+	for _, bulk := range []bool{false, true} {
+		t.Run(fmt.Sprintf("bulk %v", bulk), func(t *testing.T) {
+			conn := testConn(t)
+			ctx := context.Background()
+
+			// Files are imported in a transaction, which the staging tables of bulk loads last for
+			tx, err := conn.Begin(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := streamItems(ctx, strings.NewReader(writtenOrderTot), tx, "Measure_tot_241012.xml", bulk); err != nil {
+				tx.Rollback(ctx)
+				t.Fatalf("streamItems: %v", err)
+			}
+			if err := tx.Commit(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, table := range []string{"measure_component", "measure_component_history"} {
+				rows, err := conn.Query(ctx, "SELECT duty_expression_id FROM "+table+" WHERE parent_sid = 1 ORDER BY sequence_number;")
+				if err != nil {
+					t.Fatalf("query %s: %v", table, err)
+				}
+				ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+				if err != nil {
+					t.Fatalf("collect %s: %v", table, err)
+				}
+				if want := []int{1, 17, 12}; !slices.Equal(ids, want) {
+					t.Errorf("duty expressions of %s = %v, want the written order %v", table, ids, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package filedist
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// materializedViews are the materialized views created by sql/ddl_views.sql.
+// They are listed in refresh order, each view is built from the views before it.
+var materializedViews = []string{
+	"mv_hs_desc",
+	"mv_hs_level_desc",
+	"mv_goods_nomenclature_search",
+}
+
+// refreshMaterializedViews rebuilds the materialized views from the imported data and records when each view was refreshed.
+// The views are refreshed concurrently, so searches keep being served from the previous data during the refresh.
+func refreshMaterializedViews(ctx context.Context, conn *pgx.Conn) error {
+	for _, view := range materializedViews {
+		start := time.Now()
+		if _, err := conn.Exec(ctx, "REFRESH MATERIALIZED VIEW CONCURRENTLY "+pgx.Identifier{view}.Sanitize()); err != nil {
+			return fmt.Errorf("unable to refresh %s: %w", view, err)
+		}
+		duration := time.Since(start)
+
+		_, err := conn.Exec(ctx, `
+		INSERT INTO materialized_view_refresh (view_name, refreshed_at, duration)
+		VALUES ($1, CURRENT_TIMESTAMP, $2)
+		ON CONFLICT (view_name) DO UPDATE
+		SET refreshed_at = EXCLUDED.refreshed_at,
+			duration = EXCLUDED.duration;`, view, duration)
+		if err != nil {
+			return fmt.Errorf("unable to record refresh of %s: %w", view, err)
+		}
+
+		slog.Info("Materialized view refreshed", "view", view, "duration", duration)
+	}
+
+	return nil
+}
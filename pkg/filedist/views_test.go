@@ -0,0 +1,53 @@
+package filedist
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRefreshMaterializedViews(t *testing.T) {
+	conn := testConn(t)
+	ctx := context.Background()
+
+	// Refreshing concurrently needs a unique index on every view, which fails the refresh if one is missing
+	for i := 0; i < 2; i++ {
+		if err := refreshMaterializedViews(ctx, conn); err != nil {
+			t.Fatalf("refreshMaterializedViews: %v", err)
+		}
+	}
+
+	for _, view := range materializedViews {
+		if n := queryInt(t, conn, "SELECT COUNT(*) FROM materialized_view_refresh WHERE view_name = $1 AND refreshed_at IS NOT NULL AND duration IS NOT NULL;", view); n != 1 {
+			t.Errorf("refreshes of %s = %d, want 1", view, n)
+		}
+	}
+}
+
+func TestPerformDbMaintenanceRefreshesViews(t *testing.T) {
+	conn := testConn(t)
+	ctx := context.Background()
+
+	if err := performDbMaintenance(Config{Source: fixtureSource()}, conn); err != nil {
+		t.Fatalf("performDbMaintenance: %v", err)
+	}
+	if n := queryInt(t, conn, "SELECT COUNT(*) FROM materialized_view_refresh;"); n != len(materializedViews) {
+		t.Errorf("refreshed views = %d, want %d", n, len(materializedViews))
+	}
+
+	// Views are not refreshed by a run without new files
+	var refreshed, refreshedAgain time.Time
+	lastRefresh := "SELECT MAX(refreshed_at) FROM materialized_view_refresh;"
+	if err := conn.QueryRow(ctx, lastRefresh).Scan(&refreshed); err != nil {
+		t.Fatalf("query refresh time: %v", err)
+	}
+	if err := performDbMaintenance(Config{Source: fixtureSource()}, conn); err != nil {
+		t.Fatalf("second performDbMaintenance: %v", err)
+	}
+	if err := conn.QueryRow(ctx, lastRefresh).Scan(&refreshedAgain); err != nil {
+		t.Fatalf("query refresh time: %v", err)
+	}
+	if !refreshedAgain.Equal(refreshed) {
+		t.Errorf("views refreshed at %v by a run without new files, want the refresh of %v kept", refreshedAgain, refreshed)
+	}
+}
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
 	"html"
 	"io"
@@ -9,23 +9,72 @@ import (
 	"log/slog"
 	"net/http"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"tulltaxan/pkg/currency"
 	"tulltaxan/pkg/db"
+	"tulltaxan/pkg/duty"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// dateParamError is the response to an invalid ?date= parameter.
+const dateParamError = "Query parameter 'date' must be a date in the format YYYY-MM-DD"
+
+// referenceDate returns the date of the ?date= parameter, or today if it is not set.
+// The tariff is evaluated as of this date, such as the date a customs declaration is accepted.
+func referenceDate(r *http.Request) (time.Time, error) {
+	param := r.URL.Query().Get("date")
+	if param == "" {
+		return time.Now(), nil
+	}
+
+	return time.Parse(time.DateOnly, param)
+}
+
 // SearchHandler processes HTMX search requests
-func SearchHandler(w http.ResponseWriter, r *http.Request, conn *pgx.Conn) {
+func SearchHandler(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
 		http.Error(w, "Query parameter 'q' is required", http.StatusBadRequest)
 		return
 	}
 
+	date, err := referenceDate(r)
+	if err != nil {
+		http.Error(w, dateParamError, http.StatusBadRequest)
+		return
+	}
+
+	direction := db.Direction(r.URL.Query().Get("direction"))
+	if direction == "" {
+		direction = db.Import
+	}
+	if direction != db.Import && direction != db.Export {
+		http.Error(w, "Query parameter 'direction' must be import or export", http.StatusBadRequest)
+		return
+	}
+
+	// The country of origin of imports or destination of exports, measures for every country are shown if it is empty
+	country := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("country")))
+	if country != "" {
+		exists, err := db.GeographicalAreaExists(r.Context(), pool, country, date)
+		if err != nil {
+			http.Error(w, "Database query failed", http.StatusInternalServerError)
+			log.Printf("Database error: %v", err)
+			return
+		}
+		if len(country) != 2 || !exists {
+			http.Error(w, "Query parameter 'country' must be a country code such as CN", http.StatusBadRequest)
+			return
+		}
+	}
+
 	log.Printf("Received query: %s", query)
 
 	// Fetch the results from the database
-	results, err := db.SearchHSCodes(context.Background(), conn, query)
+	results, err := db.SearchHSCodes(r.Context(), pool, query, country, direction, date)
 	if err != nil {
 		http.Error(w, "Database query failed", http.StatusInternalServerError)
 		log.Printf("Database error: %v", err)
@@ -45,6 +94,18 @@ func SearchHandler(w http.ResponseWriter, r *http.Request, conn *pgx.Conn) {
 			additionalCodesHTML += fmt.Sprintf("<li>%s</li>", html.EscapeString(string(code)))
 		}
 
+		dutiesHTML := ""
+		for _, measure := range result.Duties {
+			conditionsHTML := ""
+			for _, group := range measure.Conditions {
+				conditionsHTML += fmt.Sprintf("<li>%s: %s</li>", html.EscapeString(group.Description), html.EscapeString(group.Summary.SV))
+			}
+
+			dutiesHTML += fmt.Sprintf("<li>%s %s (%s): %s<ul>%s</ul></li>", html.EscapeString(measure.MeasureType),
+				html.EscapeString(measure.Description), html.EscapeString(measure.GeographicalAreaID), html.EscapeString(measure.Duty.SV),
+				conditionsHTML)
+		}
+
 		// Construct the result HTML with nested lists
 		fmt.Fprintf(w, `
             <div class="result">
@@ -57,6 +118,9 @@ func SearchHandler(w http.ResponseWriter, r *http.Request, conn *pgx.Conn) {
                     <li><strong>Additional Codes:</strong>
                         <ul>%s</ul>
                     </li>
+                    <li><strong>Duties:</strong>
+                        <ul>%s</ul>
+                    </li>
                 </ul>
             </div>
         `,
@@ -64,6 +128,7 @@ func SearchHandler(w http.ResponseWriter, r *http.Request, conn *pgx.Conn) {
 			highlightText(html.EscapeString(result.Description), query),
 			certificatesHTML,
 			additionalCodesHTML,
+			dutiesHTML,
 		)
 	}
 }
@@ -81,17 +146,287 @@ func highlightText(text, term string) string {
 	return pattern.ReplaceAllString(text, highlighted)
 }
 
+// QuotaHandler returns the current balance and status of a tariff quota as JSON.
+// The quota is given either by its order number, ?order_number=090703, or by a measure referencing it, ?measure_sid=123.
+// The status on an earlier date can be requested with ?date=.
+func QuotaHandler(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	var status *db.QuotaStatus
+
+	date, err := referenceDate(r)
+	if err != nil {
+		http.Error(w, dateParamError, http.StatusBadRequest)
+		return
+	}
+
+	if orderNumber := r.URL.Query().Get("order_number"); orderNumber != "" {
+		if _, err := strconv.Atoi(orderNumber); err != nil || len(orderNumber) > 6 {
+			http.Error(w, "Query parameter 'order_number' must be a quota order number of up to six digits", http.StatusBadRequest)
+			return
+		}
+		status, err = db.GetQuotaStatus(r.Context(), pool, orderNumber, date)
+	} else if measureSID := r.URL.Query().Get("measure_sid"); measureSID != "" {
+		sid, convErr := strconv.Atoi(measureSID)
+		if convErr != nil {
+			http.Error(w, "Query parameter 'measure_sid' must be a number", http.StatusBadRequest)
+			return
+		}
+		status, err = db.GetMeasureQuotaStatus(r.Context(), pool, sid, date)
+	} else {
+		http.Error(w, "Query parameter 'order_number' or 'measure_sid' is required", http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		slog.Error("quota status query failed", "error", err)
+		http.Error(w, "Database query failed", http.StatusInternalServerError)
+		return
+	}
+	if status == nil {
+		http.Error(w, "No quota in force found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		slog.Error("unable to encode quota status", "error", err)
+	}
+}
+
+// ImportsHandler returns the latest database maintenance runs and the files they imported as JSON.
+// The number of runs defaults to 10 and can be set with ?limit=, up to 100.
+func ImportsHandler(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	limit := 10
+	if param := r.URL.Query().Get("limit"); param != "" {
+		var err error
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit < 1 || limit > 100 {
+			http.Error(w, "Query parameter 'limit' must be a number between 1 and 100", http.StatusBadRequest)
+			return
+		}
+	}
+
+	runs, err := db.GetImportRuns(r.Context(), pool, limit)
+	if err != nil {
+		slog.Error("import runs query failed", "error", err)
+		http.Error(w, "Database query failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(runs); err != nil {
+		slog.Error("unable to encode import runs", "error", err)
+	}
+}
+
+// ChangesHandler returns what changed in the tariff of a commodity code between two dates as JSON.
+// The code is given by ?code=0101210000 and the dates by ?from= and ?to= in YYYY-MM-DD format. To defaults to today.
+func ChangesHandler(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	code := r.URL.Query().Get("code")
+	if _, err := strconv.Atoi(code); err != nil || len(code) != 10 {
+		http.Error(w, "Query parameter 'code' must be a commodity code of ten digits", http.StatusBadRequest)
+		return
+	}
+
+	from, err := time.Parse(time.DateOnly, r.URL.Query().Get("from"))
+	if err != nil {
+		http.Error(w, "Query parameter 'from' must be a date in the format YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	to := time.Now()
+	if param := r.URL.Query().Get("to"); param != "" {
+		to, err = time.Parse(time.DateOnly, param)
+		if err != nil {
+			http.Error(w, "Query parameter 'to' must be a date in the format YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	changes, err := db.GetTariffChanges(r.Context(), pool, code, from, to)
+	if err != nil {
+		slog.Error("tariff changes query failed", "error", err)
+		http.Error(w, "Database query failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(changes); err != nil {
+		slog.Error("unable to encode tariff changes", "error", err)
+	}
+}
+
+// CalculateHandler returns the duty and VAT payable on imported goods as JSON, with the measures they are calculated from.
+// The goods are given by ?code= (10 digits), ?origin= (country of origin), ?value= (customs value) and ?currency=,
+// which defaults to SEK. Specific duties require ?net_mass= in kg or ?supplementary_quantity=. Measures for additional
+// codes and conditions are evaluated with ?additional_code= and ?certificates=, a comma separated list such as
+// N954,Y929. The tariff is evaluated as of ?date=.
+func CalculateHandler(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	query := r.URL.Query()
+	req := db.CalculationRequest{
+		Code:           query.Get("code"),
+		Origin:         strings.ToUpper(query.Get("origin")),
+		Currency:       strings.ToUpper(query.Get("currency")),
+		AdditionalCode: strings.ToUpper(query.Get("additional_code")),
+	}
+
+	if _, err := strconv.Atoi(req.Code); err != nil || len(req.Code) != 10 {
+		http.Error(w, "Query parameter 'code' must be a commodity code of ten digits", http.StatusBadRequest)
+		return
+	}
+	if req.Currency == "" {
+		req.Currency = "SEK"
+	}
+
+	var err error
+	req.CustomsValue, err = strconv.ParseFloat(query.Get("value"), 64)
+	if err != nil || req.CustomsValue < 0 {
+		http.Error(w, "Query parameter 'value' must be the customs value of the goods", http.StatusBadRequest)
+		return
+	}
+
+	for name, quantity := range map[string]**float64{"net_mass": &req.NetMass, "supplementary_quantity": &req.SupplementaryQuantity} {
+		if param := query.Get(name); param != "" {
+			value, err := strconv.ParseFloat(param, 64)
+			if err != nil || value < 0 {
+				http.Error(w, fmt.Sprintf("Query parameter '%s' must be a positive number", name), http.StatusBadRequest)
+				return
+			}
+			*quantity = &value
+		}
+	}
+
+	if param := query.Get("certificates"); param != "" {
+		for _, certificate := range strings.Split(param, ",") {
+			req.Certificates = append(req.Certificates, strings.ToUpper(strings.TrimSpace(certificate)))
+		}
+	}
+
+	req.Date, err = referenceDate(r)
+	if err != nil {
+		http.Error(w, dateParamError, http.StatusBadRequest)
+		return
+	}
+
+	exists, err := db.GeographicalAreaExists(r.Context(), pool, req.Origin, req.Date)
+	if err != nil {
+		slog.Error("geographical area query failed", "error", err)
+		http.Error(w, "Database query failed", http.StatusInternalServerError)
+		return
+	}
+	if len(req.Origin) != 2 || !exists {
+		http.Error(w, "Query parameter 'origin' must be a country code such as CN", http.StatusBadRequest)
+		return
+	}
+
+	converter, err := currency.Load(r.Context(), pool, req.Date)
+	if err != nil {
+		slog.Error("exchange rates query failed", "error", err)
+		http.Error(w, "Database query failed", http.StatusInternalServerError)
+		return
+	}
+
+	calculation, err := db.CalculateLandedCost(r.Context(), pool, req, converter)
+	if err != nil {
+		slog.Error("landed cost calculation failed", "error", err)
+		http.Error(w, "Database query failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(calculation); err != nil {
+		slog.Error("unable to encode calculation", "error", err)
+	}
+}
+
+// PreferencesHandler returns the tariff preferences of a commodity code as JSON, with their proofs of origin and
+// savings compared to the erga omnes third country duty. The code is given by ?code= (10 digits), and ?origin= limits
+// the preferences to those for a country of origin. If the customs value is given by ?value=, with ?currency= and
+// ?net_mass= in kg, the savings are also calculated for the goods. The tariff is evaluated as of ?date=.
+func PreferencesHandler(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	query := r.URL.Query()
+	code := query.Get("code")
+	if _, err := strconv.Atoi(code); err != nil || len(code) != 10 {
+		http.Error(w, "Query parameter 'code' must be a commodity code of ten digits", http.StatusBadRequest)
+		return
+	}
+
+	date, err := referenceDate(r)
+	if err != nil {
+		http.Error(w, dateParamError, http.StatusBadRequest)
+		return
+	}
+
+	origin := strings.ToUpper(query.Get("origin"))
+	if origin != "" {
+		exists, err := db.GeographicalAreaExists(r.Context(), pool, origin, date)
+		if err != nil {
+			slog.Error("geographical area query failed", "error", err)
+			http.Error(w, "Database query failed", http.StatusInternalServerError)
+			return
+		}
+		if len(origin) != 2 || !exists {
+			http.Error(w, "Query parameter 'origin' must be a country code such as CN", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var (
+		goods     *duty.Goods
+		converter duty.Converter
+	)
+	if param := query.Get("value"); param != "" {
+		goods = &duty.Goods{Currency: strings.ToUpper(query.Get("currency")), Quantities: duty.Quantities{}}
+		if goods.Currency == "" {
+			goods.Currency = "SEK"
+		}
+
+		goods.CustomsValue, err = strconv.ParseFloat(param, 64)
+		if err != nil || goods.CustomsValue < 0 {
+			http.Error(w, "Query parameter 'value' must be the customs value of the goods", http.StatusBadRequest)
+			return
+		}
+		if param := query.Get("net_mass"); param != "" {
+			netMass, err := strconv.ParseFloat(param, 64)
+			if err != nil || netMass < 0 {
+				http.Error(w, "Query parameter 'net_mass' must be a positive number", http.StatusBadRequest)
+				return
+			}
+			goods.Quantities["KGM"] = netMass
+		}
+
+		rates, err := currency.Load(r.Context(), pool, date)
+		if err != nil {
+			slog.Error("exchange rates query failed", "error", err)
+			http.Error(w, "Database query failed", http.StatusInternalServerError)
+			return
+		}
+		converter = rates
+	}
+
+	preferences, err := db.GetPreferences(r.Context(), pool, code, origin, date, goods, converter)
+	if err != nil {
+		slog.Error("preferences query failed", "error", err)
+		http.Error(w, "Database query failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(preferences); err != nil {
+		slog.Error("unable to encode preferences", "error", err)
+	}
+}
+
 func IpHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Info("IP Endpoint hit..")
 	response, err := http.Get(`https://ipinfo.io/ip`)
 	if err != nil {
-		slog.Error("unable to get ip adress", err)
+		slog.Error("unable to get ip adress", "error", err)
 		http.Error(w, "unable to get req for ip: "+err.Error(), http.StatusInternalServerError)
 	}
 
 	ip, err := io.ReadAll(response.Body)
 	if err != nil {
-		slog.Error("unable to read response body", err)
+		slog.Error("unable to read response body", "error", err)
 		http.Error(w, "unable to read response body: "+err.Error(), http.StatusInternalServerError)
 	}
 
@@ -22,7 +22,7 @@ type AdditionalCode struct {
 	AdditionalCodeFootnoteAssociations AdditionalCodeFootnoteAssociations `xml:"additionalCodeFootnoteAssociation"`
 }
 
-func (codes AdditionalCodes) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (codes AdditionalCodes) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO additional_code (sid, additional_code_id, additional_code_type, change_type, date_start, date_end, national)
 	VALUES ($1, $2, $3, $4, $5, $6, $7)
@@ -36,7 +36,7 @@ func (codes AdditionalCodes) BatchInsert(ctx context.Context, conn *pgx.Conn, ba
 	`
 
 	deleteQuery := `
-	DELETE FROM additional_codes
+	DELETE FROM additional_code
 	WHERE sid = $1;
 	`
 
@@ -49,17 +49,13 @@ func (codes AdditionalCodes) BatchInsert(ctx context.Context, conn *pgx.Conn, ba
 			batch.Queue(insertQuery, code.SID, code.AdditionalCodeID, code.AdditionalCodeType, code.ChangeType, code.DateStart, code.DateEnd, code.National)
 
 			// Queue child description periods
-			if len(code.AdditionalCodeDescriptionPeriods) > 0 {
-				if err := code.AdditionalCodeDescriptionPeriods.QueueBatch(ctx, batch, code.SID); err != nil {
-					return fmt.Errorf("failed to queue description periods for SID %d: %w", code.SID, err)
-				}
+			if err := code.AdditionalCodeDescriptionPeriods.QueueBatch(ctx, batch, code.SID); err != nil {
+				return fmt.Errorf("failed to queue description periods for SID %d: %w", code.SID, err)
 			}
 
 			// Queue child footnote associations
-			if len(code.AdditionalCodeFootnoteAssociations) > 0 {
-				if err := code.AdditionalCodeFootnoteAssociations.QueueBatch(ctx, batch, code.SID); err != nil {
-					return fmt.Errorf("failed to queue footnote associations for SID %d: %w", code.SID, err)
-				}
+			if err := code.AdditionalCodeFootnoteAssociations.QueueBatch(ctx, batch, code.SID); err != nil {
+				return fmt.Errorf("failed to queue footnote associations for SID %d: %w", code.SID, err)
 			}
 
 		case "D": // Delete record
@@ -72,7 +68,7 @@ func (codes AdditionalCodes) BatchInsert(ctx context.Context, conn *pgx.Conn, ba
 
 		// Execute the batch when reaching batchSize or end of data
 		if (i+1)%batchSize == 0 || i == len(codes)-1 {
-			results := conn.SendBatch(ctx, batch)
+			results := db.SendBatch(ctx, batch)
 			if err := results.Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
@@ -104,17 +100,26 @@ func (periods AdditionalCodeDescriptionPeriods) QueueBatch(ctx context.Context,
 		national = EXCLUDED.national;
 	`
 
+	// Children missing from an update have been removed from the additional code
+	deleteQuery := `
+	DELETE FROM additional_code_description_period
+	WHERE parent_sid = $1
+		AND sid <> ALL($2::INT[]);
+	`
+
+	sids := make([]int, 0, len(periods))
 	for _, period := range periods {
 		period.ParentSID = parentSID // Ensure parent relationship
+		sids = append(sids, period.SID)
 		batch.Queue(insertQuery, period.SID, period.ParentSID, period.DateStart, period.DateEnd, period.National)
 
 		// Queue child descriptions
-		if len(period.AdditionalCodeDescriptions) > 0 {
-			if err := period.AdditionalCodeDescriptions.QueueBatch(ctx, batch, period.SID); err != nil {
-				return fmt.Errorf("failed to queue descriptions for SID %d: %w", period.SID, err)
-			}
+		if err := period.AdditionalCodeDescriptions.QueueBatch(ctx, batch, period.SID); err != nil {
+			return fmt.Errorf("failed to queue descriptions for SID %d: %w", period.SID, err)
 		}
 	}
+	batch.Queue(deleteQuery, parentSID, sids)
+
 	return nil
 }
 
@@ -136,10 +141,20 @@ func (descriptions AdditionalCodeDescriptions) QueueBatch(ctx context.Context, b
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM additional_code_description
+	WHERE parent_sid = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentSID = parentSID // Ensure parent relationship
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentSID, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentSID, languageIDs)
+
 	return nil
 }
 
@@ -164,10 +179,23 @@ func (associations AdditionalCodeFootnoteAssociations) QueueBatch(ctx context.Co
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM additional_code_footnote_association
+	WHERE parent_sid = $1
+		AND (footnote_id, footnote_type) NOT IN (
+			SELECT * FROM unnest($2::INT[], $3::TEXT[])
+		);
+	`
+
+	footnoteIDs := make([]int, 0, len(associations))
+	footnoteTypes := make([]string, 0, len(associations))
 	for _, assoc := range associations {
 		assoc.ParentSID = parentSID // Set parent relationship
+		footnoteIDs = append(footnoteIDs, assoc.FootnoteID)
+		footnoteTypes = append(footnoteTypes, assoc.FootnoteType)
 		batch.Queue(insertQuery, assoc.ParentSID, assoc.DateStart, assoc.DateEnd, assoc.FootnoteID, assoc.FootnoteType, assoc.National)
 	}
+	batch.Queue(deleteQuery, parentSID, footnoteIDs, footnoteTypes)
 
 	return nil
 }
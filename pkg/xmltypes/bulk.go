@@ -0,0 +1,99 @@
+package xmltypes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// BulkFileDistItem is implemented by the large item types that can be loaded with COPY.
+// BulkInsert is meant for the initial load of tot files into an empty database and must be called
+// inside a transaction, since the staging tables it uses are dropped on commit.
+// Only Measures and GoodsNomenclatures, with their child elements, implement it so far. Every other item type
+// is upserted with BatchInsert also during the initial load.
+type BulkFileDistItem interface {
+	FileDistItem
+	BulkInsert(context.Context, DB) error
+}
+
+// bulkTable collects the rows of one table to be loaded with copyMerge.
+type bulkTable struct {
+	name     string
+	columns  []string
+	conflict []string // columns of the primary key, used to merge into existing rows
+	rows     [][]any
+}
+
+func newBulkTable(name string, columns []string, conflict ...string) *bulkTable {
+	return &bulkTable{name: name, columns: columns, conflict: conflict}
+}
+
+// add appends a row. Values must be given in the order of the table columns.
+func (t *bulkTable) add(values ...any) {
+	// The row number is stored with the row so the last occurrence of a key wins, like with row by row upserts
+	t.rows = append(t.rows, append(values, int64(len(t.rows))))
+}
+
+// copyMerge loads the rows of each table into a temporary staging table with COPY and merges them
+// into the target table with a single INSERT ... ON CONFLICT per table.
+// Tables are merged in the order given, so parent tables must be given before their children.
+func copyMerge(ctx context.Context, db DB, tables ...*bulkTable) error {
+	for _, t := range tables {
+		if len(t.rows) == 0 {
+			continue
+		}
+
+		stage := "stage_" + t.name
+		columns := strings.Join(t.columns, ", ")
+		conflict := strings.Join(t.conflict, ", ")
+
+		createStmt := fmt.Sprintf(`
+		CREATE TEMP TABLE IF NOT EXISTS %s ON COMMIT DROP AS
+		SELECT %s, 0::BIGINT AS stage_ord FROM %s WITH NO DATA;`, stage, columns, t.name)
+		if _, err := db.Exec(ctx, createStmt); err != nil {
+			return fmt.Errorf("unable to create staging table for %s: %w", t.name, err)
+		}
+
+		copyColumns := append(append([]string{}, t.columns...), "stage_ord")
+		if _, err := db.CopyFrom(ctx, pgx.Identifier{stage}, copyColumns, pgx.CopyFromRows(t.rows)); err != nil {
+			return fmt.Errorf("unable to copy rows into %s: %w", stage, err)
+		}
+
+		if _, err := db.Exec(ctx, mergeStatement(t, stage, columns, conflict)); err != nil {
+			return fmt.Errorf("unable to merge %s into %s: %w", stage, t.name, err)
+		}
+
+		if _, err := db.Exec(ctx, fmt.Sprintf("TRUNCATE %s;", stage)); err != nil {
+			return fmt.Errorf("unable to truncate %s: %w", stage, err)
+		}
+	}
+
+	return nil
+}
+
+// mergeStatement returns the set-based upsert of the staged rows into the target table.
+func mergeStatement(t *bulkTable, stage, columns, conflict string) string {
+	updates := make([]string, 0, len(t.columns))
+	for _, column := range t.columns {
+		isKey := false
+		for _, key := range t.conflict {
+			isKey = isKey || key == column
+		}
+		if !isKey {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+		}
+	}
+
+	onConflict := "DO NOTHING"
+	if len(updates) > 0 {
+		onConflict = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+
+	return fmt.Sprintf(`
+	INSERT INTO %s (%s)
+	SELECT DISTINCT ON (%s) %s FROM %s
+	ORDER BY %s, stage_ord DESC
+	ON CONFLICT (%s) %s;`, t.name, columns, conflict, columns, stage, conflict, conflict, onConflict)
+}
@@ -19,7 +19,7 @@ type Certificate struct {
 	CertificateDescriptionPeriods CertificateDescriptionPeriods `xml:"certificateDescriptionPeriod"`
 }
 
-func (certificates Certificates) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (certificates Certificates) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO certificate (certificate_code, certificate_type, change_type, date_start, date_end, national)
 	VALUES ($1, $2, $3, $4, $5, $6)
@@ -43,10 +43,8 @@ func (certificates Certificates) BatchInsert(ctx context.Context, conn *pgx.Conn
 			batch.Queue(insertQuery, cert.CertificateCode, cert.CertificateType, cert.ChangeType, cert.DateStart, cert.DateEnd, cert.National)
 
 			// Queue child description periods
-			if len(cert.CertificateDescriptionPeriods) > 0 {
-				if err := cert.CertificateDescriptionPeriods.QueueBatch(ctx, batch, cert.CertificateCode, cert.CertificateType); err != nil {
-					return fmt.Errorf("failed to queue description periods for certificate %s-%s: %w", cert.CertificateCode, cert.CertificateType, err)
-				}
+			if err := cert.CertificateDescriptionPeriods.QueueBatch(ctx, batch, cert.CertificateCode, cert.CertificateType); err != nil {
+				return fmt.Errorf("failed to queue description periods for certificate %s-%s: %w", cert.CertificateCode, cert.CertificateType, err)
 			}
 
 		case "D": // Delete
@@ -57,7 +55,7 @@ func (certificates Certificates) BatchInsert(ctx context.Context, conn *pgx.Conn
 		}
 
 		if (i+1)%batchSize == 0 || i == len(certificates)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -87,15 +85,25 @@ func (periods CertificateDescriptionPeriods) QueueBatch(ctx context.Context, bat
 		national = EXCLUDED.national;
 	`
 
+	// Children missing from an update have been removed from the certificate
+	deleteQuery := `
+	DELETE FROM certificate_description_period
+	WHERE parent_certificate_code = $1
+		AND parent_certificate_type = $2
+		AND sid <> ALL($3::INT[]);
+	`
+
+	sids := make([]int, 0, len(periods))
 	for _, period := range periods {
+		sids = append(sids, period.SID)
 		batch.Queue(insertQuery, period.SID, parentCode, parentType, period.DateStart, period.DateEnd, period.National)
 
-		if len(period.CertificateDescriptions) > 0 {
-			if err := period.CertificateDescriptions.QueueBatch(ctx, batch, period.SID); err != nil {
-				return fmt.Errorf("failed to queue descriptions for SID %d: %w", period.SID, err)
-			}
+		if err := period.CertificateDescriptions.QueueBatch(ctx, batch, period.SID); err != nil {
+			return fmt.Errorf("failed to queue descriptions for SID %d: %w", period.SID, err)
 		}
 	}
+	batch.Queue(deleteQuery, parentCode, parentType, sids)
+
 	return nil
 }
 
@@ -116,8 +124,18 @@ func (descriptions CertificateDescriptions) QueueBatch(ctx context.Context, batc
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM certificate_description
+	WHERE parent_sid = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, parentSID, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentSID, languageIDs)
+
 	return nil
 }
@@ -21,7 +21,7 @@ type CodeType struct {
 	CodeTypeDescriptions CodeTypeDescriptions `xml:"codeTypeDescription"`
 }
 
-func (codeTypes CodeTypes) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (codeTypes CodeTypes) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO code_type (id, code_type_id, change_type, date_start, date_end, export_import_type, measure_type_series_id, national)
 	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
@@ -48,10 +48,8 @@ func (codeTypes CodeTypes) BatchInsert(ctx context.Context, conn *pgx.Conn, batc
 			batch.Queue(insertQuery, codeType.ID, codeType.CodeTypeID, codeType.ChangeType, codeType.DateStart, codeType.DateEnd, codeType.ExportImportType, codeType.MeasureTypeSeriesID, codeType.National)
 
 			// Queue child descriptions
-			if len(codeType.CodeTypeDescriptions) > 0 {
-				if err := codeType.CodeTypeDescriptions.QueueBatch(ctx, batch, codeType.ID); err != nil {
-					return fmt.Errorf("failed to queue descriptions for CodeType ID %s: %w", codeType.ID, err)
-				}
+			if err := codeType.CodeTypeDescriptions.QueueBatch(ctx, batch, codeType.ID); err != nil {
+				return fmt.Errorf("failed to queue descriptions for CodeType ID %s: %w", codeType.ID, err)
 			}
 
 		case "D": // Delete
@@ -62,7 +60,7 @@ func (codeTypes CodeTypes) BatchInsert(ctx context.Context, conn *pgx.Conn, batc
 		}
 
 		if (i+1)%batchSize == 0 || i == len(codeTypes)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -90,9 +88,20 @@ func (descriptions CodeTypeDescriptions) QueueBatch(ctx context.Context, batch *
 		national = EXCLUDED.national;
 	`
 
+	// Descriptions missing from an update have been removed from the code type
+	deleteQuery := `
+	DELETE FROM code_type_description
+	WHERE parent_id = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentID = parentID // Ensure parent relationship
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentID, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentID, languageIDs)
+
 	return nil
 }
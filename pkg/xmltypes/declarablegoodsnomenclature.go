@@ -17,7 +17,7 @@ type DeclarableGoodsNomenclature struct {
 	Type                  string       `xml:"type,attr"`
 }
 
-func (nomenclatures DeclarableGoodsNomenclatures) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (nomenclatures DeclarableGoodsNomenclatures) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO declarable_goods_nomenclature (goods_nomenclature_code, change_type, date_start, date_end, type)
 	VALUES ($1, $2, $3, $4, $5)
@@ -48,7 +48,7 @@ func (nomenclatures DeclarableGoodsNomenclatures) BatchInsert(ctx context.Contex
 		}
 
 		if (i+1)%batchSize == 0 || i == len(nomenclatures)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -21,7 +21,7 @@ type DutyExpression struct {
 	DutyExpressionDescriptions       DutyExpressionDescriptions `xml:"dutyExpressionDescription"`
 }
 
-func (expressions DutyExpressions) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (expressions DutyExpressions) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO duty_expression (duty_expression_id, change_type, date_start, date_end, duty_amount_applicability_code, measurement_unit_applicability_code, monetary_unit_applicability_code, national)
 	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
@@ -48,10 +48,8 @@ func (expressions DutyExpressions) BatchInsert(ctx context.Context, conn *pgx.Co
 			batch.Queue(insertQuery, expr.DutyExpressionID, expr.ChangeType, expr.DateStart, expr.DateEnd, expr.DutyAmountApplicabilityCode, expr.MeasurementUnitApplicabilityCode, expr.MonetaryUnitApplicabilityCode, expr.National)
 
 			// Queue child descriptions
-			if len(expr.DutyExpressionDescriptions) > 0 {
-				if err := expr.DutyExpressionDescriptions.QueueBatch(ctx, batch, expr.DutyExpressionID); err != nil {
-					return fmt.Errorf("failed to queue descriptions for DutyExpressionID %s: %w", expr.DutyExpressionID, err)
-				}
+			if err := expr.DutyExpressionDescriptions.QueueBatch(ctx, batch, expr.DutyExpressionID); err != nil {
+				return fmt.Errorf("failed to queue descriptions for DutyExpressionID %s: %w", expr.DutyExpressionID, err)
 			}
 
 		case "D": // Delete
@@ -62,7 +60,7 @@ func (expressions DutyExpressions) BatchInsert(ctx context.Context, conn *pgx.Co
 		}
 
 		if (i+1)%batchSize == 0 || i == len(expressions)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -90,9 +88,20 @@ func (descriptions DutyExpressionDescriptions) QueueBatch(ctx context.Context, b
 		national = EXCLUDED.national;
 	`
 
+	// Descriptions missing from an update have been removed from the duty expression
+	deleteQuery := `
+	DELETE FROM duty_expression_description
+	WHERE parent_duty_expression_id = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentDutyExpressionID = parentID // Ensure parent relationship
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentDutyExpressionID, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentID, languageIDs)
+
 	return nil
 }
@@ -25,7 +25,7 @@ type ExportRefundNomenclature struct {
 	ExportRefundNomenclatureFootnoteAssociations ExportRefundNomenclatureFootnoteAssociations `xml:"exportRefundNomenclatureFootnoteAssociation"`
 }
 
-func (nomenclatures ExportRefundNomenclatures) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (nomenclatures ExportRefundNomenclatures) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO export_refund_nomenclature (sid, goods_nomenclature_code, additional_code_type, export_refund_code, product_line_suffix, sid_goods_nomenclature, change_type, date_start, date_end, national)
 	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
@@ -54,22 +54,16 @@ func (nomenclatures ExportRefundNomenclatures) BatchInsert(ctx context.Context,
 			batch.Queue(insertQuery, nomenclature.SID, fmt.Sprint(nomenclature.GoodsNomenclatureCode), fmt.Sprint(nomenclature.AdditionalCodeType), nomenclature.ExportRefundCode, nomenclature.ProductLineSuffix, nomenclature.SIDGoodsNomenclature, nomenclature.ChangeType, nomenclature.DateStart, nomenclature.DateEnd, nomenclature.National)
 
 			// Queue child elements
-			if len(nomenclature.ExportRefundNomenclatureIndents) > 0 {
-				if err := nomenclature.ExportRefundNomenclatureIndents.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
-					return fmt.Errorf("failed to queue indents for SID %d: %w", nomenclature.SID, err)
-				}
+			if err := nomenclature.ExportRefundNomenclatureIndents.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
+				return fmt.Errorf("failed to queue indents for SID %d: %w", nomenclature.SID, err)
 			}
 
-			if len(nomenclature.ExportRefundNomenclatureDescriptionPeriods) > 0 {
-				if err := nomenclature.ExportRefundNomenclatureDescriptionPeriods.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
-					return fmt.Errorf("failed to queue description periods for SID %d: %w", nomenclature.SID, err)
-				}
+			if err := nomenclature.ExportRefundNomenclatureDescriptionPeriods.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
+				return fmt.Errorf("failed to queue description periods for SID %d: %w", nomenclature.SID, err)
 			}
 
-			if len(nomenclature.ExportRefundNomenclatureFootnoteAssociations) > 0 {
-				if err := nomenclature.ExportRefundNomenclatureFootnoteAssociations.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
-					return fmt.Errorf("failed to queue footnote associations for SID %d: %w", nomenclature.SID, err)
-				}
+			if err := nomenclature.ExportRefundNomenclatureFootnoteAssociations.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
+				return fmt.Errorf("failed to queue footnote associations for SID %d: %w", nomenclature.SID, err)
 			}
 
 		case "D": // Delete
@@ -80,7 +74,7 @@ func (nomenclatures ExportRefundNomenclatures) BatchInsert(ctx context.Context,
 		}
 
 		if (i+1)%batchSize == 0 || i == len(nomenclatures)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -110,10 +104,21 @@ func (indents ExportRefundNomenclatureIndents) QueueBatch(ctx context.Context, b
 		national = EXCLUDED.national;
 	`
 
+	// Children missing from an update have been removed from the export refund nomenclature
+	deleteQuery := `
+	DELETE FROM export_refund_nomenclature_indent
+	WHERE parent_sid = $1
+		AND sid <> ALL($2::INT[]);
+	`
+
+	sids := make([]int, 0, len(indents))
 	for _, indent := range indents {
 		indent.ParentSID = parentSID
+		sids = append(sids, indent.SID)
 		batch.Queue(insertQuery, indent.SID, indent.ParentSID, indent.DateStart, indent.QuantityIndents, indent.National)
 	}
+	batch.Queue(deleteQuery, parentSID, sids)
+
 	return nil
 }
 
@@ -136,17 +141,25 @@ func (periods ExportRefundNomenclatureDescriptionPeriods) QueueBatch(ctx context
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM export_refund_nomenclature_description_period
+	WHERE parent_sid = $1
+		AND sid <> ALL($2::INT[]);
+	`
+
+	sids := make([]int, 0, len(periods))
 	for _, period := range periods {
 		period.ParentSID = parentSID
+		sids = append(sids, period.SID)
 		batch.Queue(insertQuery, period.SID, period.ParentSID, period.DateStart, period.National)
 
 		// Queue child descriptions
-		if len(period.ExportRefundNomenclatureDescriptions) > 0 {
-			if err := period.ExportRefundNomenclatureDescriptions.QueueBatch(ctx, batch, period.SID); err != nil {
-				return fmt.Errorf("failed to queue descriptions for SID %d: %w", period.SID, err)
-			}
+		if err := period.ExportRefundNomenclatureDescriptions.QueueBatch(ctx, batch, period.SID); err != nil {
+			return fmt.Errorf("failed to queue descriptions for SID %d: %w", period.SID, err)
 		}
 	}
+	batch.Queue(deleteQuery, parentSID, sids)
+
 	return nil
 }
 
@@ -168,10 +181,20 @@ func (descriptions ExportRefundNomenclatureDescriptions) QueueBatch(ctx context.
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM export_refund_nomenclature_description
+	WHERE parent_sid = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentSID = parentSID
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentSID, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentSID, languageIDs)
+
 	return nil
 }
 
@@ -196,9 +219,23 @@ func (associations ExportRefundNomenclatureFootnoteAssociations) QueueBatch(ctx
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM export_refund_nomenclature_footnote_association
+	WHERE parent_sid = $1
+		AND (footnote_id, footnote_type) NOT IN (
+			SELECT * FROM unnest($2::INT[], $3::TEXT[])
+		);
+	`
+
+	footnoteIDs := make([]int, 0, len(associations))
+	footnoteTypes := make([]string, 0, len(associations))
 	for _, assoc := range associations {
 		assoc.ParentSID = parentSID
+		footnoteIDs = append(footnoteIDs, assoc.FootnoteID)
+		footnoteTypes = append(footnoteTypes, assoc.FootnoteType)
 		batch.Queue(insertQuery, assoc.ParentSID, assoc.DateStart, assoc.DateEnd, assoc.FootnoteID, assoc.FootnoteType, assoc.National)
 	}
+	batch.Queue(deleteQuery, parentSID, footnoteIDs, footnoteTypes)
+
 	return nil
 }
@@ -19,7 +19,7 @@ type Footnote struct {
 	FootnoteDescriptionPeriods FootnoteDescriptionPeriods `xml:"footnoteDescriptionPeriod"`
 }
 
-func (footnotes Footnotes) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (footnotes Footnotes) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO footnote (footnote_id, footnote_type, change_type, date_start, date_end, national)
 	VALUES ($1, $2, $3, $4, $5, $6)
@@ -43,10 +43,8 @@ func (footnotes Footnotes) BatchInsert(ctx context.Context, conn *pgx.Conn, batc
 			batch.Queue(insertQuery, footnote.FootnoteID, footnote.FootnoteType, footnote.ChangeType, footnote.DateStart, footnote.DateEnd, footnote.National)
 
 			// Queue child description periods
-			if len(footnote.FootnoteDescriptionPeriods) > 0 {
-				if err := footnote.FootnoteDescriptionPeriods.QueueBatch(ctx, batch, footnote.FootnoteID, footnote.FootnoteType); err != nil {
-					return fmt.Errorf("failed to queue description periods for FootnoteID %s, FootnoteType %s: %w", footnote.FootnoteID, footnote.FootnoteType, err)
-				}
+			if err := footnote.FootnoteDescriptionPeriods.QueueBatch(ctx, batch, footnote.FootnoteID, footnote.FootnoteType); err != nil {
+				return fmt.Errorf("failed to queue description periods for FootnoteID %s, FootnoteType %s: %w", footnote.FootnoteID, footnote.FootnoteType, err)
 			}
 
 		case "D": // Delete
@@ -57,7 +55,7 @@ func (footnotes Footnotes) BatchInsert(ctx context.Context, conn *pgx.Conn, batc
 		}
 
 		if (i+1)%batchSize == 0 || i == len(footnotes)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -89,18 +87,28 @@ func (periods FootnoteDescriptionPeriods) QueueBatch(ctx context.Context, batch
 		national = EXCLUDED.national;
 	`
 
+	// Children missing from an update have been removed from the footnote
+	deleteQuery := `
+	DELETE FROM footnote_description_period
+	WHERE parent_footnote_id = $1
+		AND parent_footnote_type = $2
+		AND sid <> ALL($3::INT[]);
+	`
+
+	sids := make([]int, 0, len(periods))
 	for _, period := range periods {
 		period.ParentFootnoteID = parentFootnoteID
 		period.ParentFootnoteType = parentFootnoteType
+		sids = append(sids, period.SID)
 		batch.Queue(insertQuery, period.SID, period.ParentFootnoteID, period.ParentFootnoteType, period.DateStart, period.DateEnd, period.National)
 
 		// Queue child descriptions
-		if len(period.FootnoteDescriptions) > 0 {
-			if err := period.FootnoteDescriptions.QueueBatch(ctx, batch, period.SID); err != nil {
-				return fmt.Errorf("failed to queue descriptions for SID %d: %w", period.SID, err)
-			}
+		if err := period.FootnoteDescriptions.QueueBatch(ctx, batch, period.SID); err != nil {
+			return fmt.Errorf("failed to queue descriptions for SID %d: %w", period.SID, err)
 		}
 	}
+	batch.Queue(deleteQuery, parentFootnoteID, parentFootnoteType, sids)
+
 	return nil
 }
 
@@ -122,9 +130,19 @@ func (descriptions FootnoteDescriptions) QueueBatch(ctx context.Context, batch *
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM footnote_description
+	WHERE parent_sid = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentSID = parentSID
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentSID, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentSID, languageIDs)
+
 	return nil
 }
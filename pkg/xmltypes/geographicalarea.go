@@ -3,6 +3,7 @@ package xmltypes
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -22,7 +23,7 @@ type GeographicalArea struct {
 	GeographicalAreaDescriptionPeriods GeographicalAreaDescriptionPeriods `xml:"geographicalAreaDescriptionPeriod"`
 }
 
-func (areas GeographicalAreas) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (areas GeographicalAreas) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO geographical_area (sid, sid_parent_group, change_type, date_start, date_end, geographical_area_code, geographical_area_id, national)
 	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
@@ -49,17 +50,13 @@ func (areas GeographicalAreas) BatchInsert(ctx context.Context, conn *pgx.Conn,
 			batch.Queue(insertQuery, area.SID, area.SIDParentGroup, area.ChangeType, area.DateStart, area.DateEnd, area.GeographicalAreaCode, area.GeographicalAreaID, area.National)
 
 			// Queue child memberships
-			if len(area.GeographicalAreaMemberships) > 0 {
-				if err := area.GeographicalAreaMemberships.QueueBatch(ctx, batch, area.SID); err != nil {
-					return fmt.Errorf("failed to queue memberships for SID %d: %w", area.SID, err)
-				}
+			if err := area.GeographicalAreaMemberships.QueueBatch(ctx, batch, area.SID); err != nil {
+				return fmt.Errorf("failed to queue memberships for SID %d: %w", area.SID, err)
 			}
 
 			// Queue child description periods
-			if len(area.GeographicalAreaDescriptionPeriods) > 0 {
-				if err := area.GeographicalAreaDescriptionPeriods.QueueBatch(ctx, batch, area.SID); err != nil {
-					return fmt.Errorf("failed to queue description periods for SID %d: %w", area.SID, err)
-				}
+			if err := area.GeographicalAreaDescriptionPeriods.QueueBatch(ctx, batch, area.SID); err != nil {
+				return fmt.Errorf("failed to queue description periods for SID %d: %w", area.SID, err)
 			}
 
 		case "D": // Delete
@@ -70,7 +67,7 @@ func (areas GeographicalAreas) BatchInsert(ctx context.Context, conn *pgx.Conn,
 		}
 
 		if (i+1)%batchSize == 0 || i == len(areas)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -99,10 +96,25 @@ func (memberships GeographicalAreaMemberships) QueueBatch(ctx context.Context, b
 		national = EXCLUDED.national;
 	`
 
+	// Children missing from an update have been removed from the geographical area
+	deleteQuery := `
+	DELETE FROM geographical_area_membership
+	WHERE parent_sid = $1
+		AND (sid_geographical_area_group, date_start) NOT IN (
+			SELECT * FROM unnest($2::INT[], $3::TIMESTAMP[])
+		);
+	`
+
+	groupSIDs := make([]int, 0, len(memberships))
+	startDates := make([]time.Time, 0, len(memberships))
 	for _, membership := range memberships {
 		membership.ParentSID = parentSID
+		groupSIDs = append(groupSIDs, membership.SIDGeographicalAreaGroup)
+		startDates = append(startDates, membership.DateStart.Time)
 		batch.Queue(insertQuery, membership.SIDGeographicalAreaGroup, membership.ParentSID, membership.DateStart, membership.DateEnd, membership.National)
 	}
+	batch.Queue(deleteQuery, parentSID, groupSIDs, startDates)
+
 	return nil
 }
 
@@ -127,17 +139,25 @@ func (periods GeographicalAreaDescriptionPeriods) QueueBatch(ctx context.Context
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM geographical_area_description_period
+	WHERE parent_sid = $1
+		AND sid <> ALL($2::INT[]);
+	`
+
+	sids := make([]int, 0, len(periods))
 	for _, period := range periods {
 		period.ParentSID = parentSID
+		sids = append(sids, period.SID)
 		batch.Queue(insertQuery, period.SID, period.ParentSID, period.DateStart, period.DateEnd, period.National)
 
 		// Queue child descriptions
-		if len(period.GeographicalAreaDescriptions) > 0 {
-			if err := period.GeographicalAreaDescriptions.QueueBatch(ctx, batch, period.SID); err != nil {
-				return fmt.Errorf("failed to queue descriptions for SID %d: %w", period.SID, err)
-			}
+		if err := period.GeographicalAreaDescriptions.QueueBatch(ctx, batch, period.SID); err != nil {
+			return fmt.Errorf("failed to queue descriptions for SID %d: %w", period.SID, err)
 		}
 	}
+	batch.Queue(deleteQuery, parentSID, sids)
+
 	return nil
 }
 
@@ -159,9 +179,19 @@ func (descriptions GeographicalAreaDescriptions) QueueBatch(ctx context.Context,
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM geographical_area_description
+	WHERE parent_sid = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentSID = parentSID
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentSID, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentSID, languageIDs)
+
 	return nil
 }
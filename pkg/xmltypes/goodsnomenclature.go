@@ -24,7 +24,7 @@ type GoodsNomenclature struct {
 	GoodsNomenclatureGroupMemberships     GoodsNomenclatureGroupMemberships     `xml:"goodsNomenclatureGroupMembership"`
 }
 
-func (nomenclatures GoodsNomenclatures) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (nomenclatures GoodsNomenclatures) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO goods_nomenclature (sid, goods_nomenclature_code, product_line_suffix, statistical_indicator, change_type, date_start, date_end, national)
 	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
@@ -51,25 +51,17 @@ func (nomenclatures GoodsNomenclatures) BatchInsert(ctx context.Context, conn *p
 			batch.Queue(insertQuery, nomenclature.SID, fmt.Sprint(nomenclature.GoodsNomenclatureCode), nomenclature.ProductLineSuffix, nomenclature.StatisticalIndicator, nomenclature.ChangeType, nomenclature.DateStart, nomenclature.DateEnd, nomenclature.National)
 
 			// Queue child elements
-			if len(nomenclature.GoodsNomenclatureIndents) > 0 {
-				if err := nomenclature.GoodsNomenclatureIndents.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
-					return fmt.Errorf("failed to queue indents for SID %d: %w", nomenclature.SID, err)
-				}
+			if err := nomenclature.GoodsNomenclatureIndents.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
+				return fmt.Errorf("failed to queue indents for SID %d: %w", nomenclature.SID, err)
 			}
-			if len(nomenclature.GoodsNomenclatureDescriptionPeriods) > 0 {
-				if err := nomenclature.GoodsNomenclatureDescriptionPeriods.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
-					return fmt.Errorf("failed to queue description periods for SID %d: %w", nomenclature.SID, err)
-				}
+			if err := nomenclature.GoodsNomenclatureDescriptionPeriods.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
+				return fmt.Errorf("failed to queue description periods for SID %d: %w", nomenclature.SID, err)
 			}
-			if len(nomenclature.GoodsNomenclatureFootnoteAssociations) > 0 {
-				if err := nomenclature.GoodsNomenclatureFootnoteAssociations.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
-					return fmt.Errorf("failed to queue footnote associations for SID %d: %w", nomenclature.SID, err)
-				}
+			if err := nomenclature.GoodsNomenclatureFootnoteAssociations.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
+				return fmt.Errorf("failed to queue footnote associations for SID %d: %w", nomenclature.SID, err)
 			}
-			if len(nomenclature.GoodsNomenclatureGroupMemberships) > 0 {
-				if err := nomenclature.GoodsNomenclatureGroupMemberships.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
-					return fmt.Errorf("failed to queue group memberships for SID %d: %w", nomenclature.SID, err)
-				}
+			if err := nomenclature.GoodsNomenclatureGroupMemberships.QueueBatch(ctx, batch, nomenclature.SID); err != nil {
+				return fmt.Errorf("failed to queue group memberships for SID %d: %w", nomenclature.SID, err)
 			}
 
 		case "D": // Delete
@@ -80,7 +72,7 @@ func (nomenclatures GoodsNomenclatures) BatchInsert(ctx context.Context, conn *p
 		}
 
 		if (i+1)%batchSize == 0 || i == len(nomenclatures)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -90,6 +82,67 @@ func (nomenclatures GoodsNomenclatures) BatchInsert(ctx context.Context, conn *p
 	return nil
 }
 
+// BulkInsert loads the goods nomenclatures and their child elements with COPY.
+// Elements that are not inserts or updates are passed on to BatchInsert.
+func (nomenclatures GoodsNomenclatures) BulkInsert(ctx context.Context, db DB) error {
+	nomenclatureRows := newBulkTable("goods_nomenclature", []string{
+		"sid", "goods_nomenclature_code", "product_line_suffix", "statistical_indicator", "change_type", "date_start", "date_end", "national",
+	}, "sid")
+	indentRows := newBulkTable("goods_nomenclature_indent", []string{
+		"sid", "parent_sid", "date_start", "date_end", "quantity_indents", "national",
+	}, "sid")
+	periodRows := newBulkTable("goods_nomenclature_description_period", []string{
+		"sid", "parent_sid", "date_start", "date_end", "national",
+	}, "sid")
+	descriptionRows := newBulkTable("goods_nomenclature_description", []string{
+		"parent_sid", "description", "language_id", "national",
+	}, "parent_sid", "language_id")
+	footnoteRows := newBulkTable("goods_nomenclature_footnote_association", []string{
+		"parent_sid", "date_start", "date_end", "footnote_id", "footnote_type", "national",
+	}, "parent_sid", "footnote_id", "footnote_type")
+	membershipRows := newBulkTable("goods_nomenclature_group_membership", []string{
+		"parent_sid", "date_start", "date_end", "goods_nomenclature_group_id", "goods_nomenclature_group_type", "national",
+	}, "parent_sid", "goods_nomenclature_group_id", "goods_nomenclature_group_type")
+
+	var other GoodsNomenclatures
+	for _, nomenclature := range nomenclatures {
+		if nomenclature.ChangeType != "U" {
+			other = append(other, nomenclature)
+			continue
+		}
+
+		nomenclatureRows.add(nomenclature.SID, fmt.Sprint(nomenclature.GoodsNomenclatureCode), nomenclature.ProductLineSuffix, nomenclature.StatisticalIndicator, nomenclature.ChangeType, nomenclature.DateStart, nomenclature.DateEnd, nomenclature.National)
+
+		for _, indent := range nomenclature.GoodsNomenclatureIndents {
+			indentRows.add(indent.SID, nomenclature.SID, indent.DateStart, indent.DateEnd, indent.QuantityIndents, indent.National)
+		}
+		for _, period := range nomenclature.GoodsNomenclatureDescriptionPeriods {
+			periodRows.add(period.SID, nomenclature.SID, period.DateStart, period.DateEnd, period.National)
+
+			for _, desc := range period.GoodsNomenclatureDescriptions {
+				descriptionRows.add(period.SID, desc.Description, desc.LanguageID, desc.National)
+			}
+		}
+		for _, assoc := range nomenclature.GoodsNomenclatureFootnoteAssociations {
+			footnoteRows.add(nomenclature.SID, assoc.DateStart, assoc.DateEnd, assoc.FootnoteID, assoc.FootnoteType, assoc.National)
+		}
+		for _, membership := range nomenclature.GoodsNomenclatureGroupMemberships {
+			membershipRows.add(nomenclature.SID, membership.DateStart, membership.DateEnd, membership.GoodsNomenclatureGroupID, membership.GoodsNomenclatureGroupType, membership.National)
+		}
+	}
+
+	err := copyMerge(ctx, db, nomenclatureRows, indentRows, periodRows, descriptionRows, footnoteRows, membershipRows)
+	if err != nil {
+		return fmt.Errorf("copyMerge: %w", err)
+	}
+
+	if len(other) > 0 {
+		return other.BatchInsert(ctx, db, len(other))
+	}
+
+	return nil
+}
+
 type GoodsNomenclatureIndents []GoodsNomenclatureIndent
 
 type GoodsNomenclatureIndent struct {
@@ -112,10 +165,21 @@ func (indents GoodsNomenclatureIndents) QueueBatch(ctx context.Context, batch *p
 		national = EXCLUDED.national;
 	`
 
+	// Children missing from an update have been removed from the goods nomenclature
+	deleteQuery := `
+	DELETE FROM goods_nomenclature_indent
+	WHERE parent_sid = $1
+		AND sid <> ALL($2::INT[]);
+	`
+
+	sids := make([]int, 0, len(indents))
 	for _, indent := range indents {
 		indent.ParentSID = parentSID
+		sids = append(sids, indent.SID)
 		batch.Queue(insertQuery, indent.SID, indent.ParentSID, indent.DateStart, indent.DateEnd, indent.QuantityIndents, indent.National)
 	}
+	batch.Queue(deleteQuery, parentSID, sids)
+
 	return nil
 }
 
@@ -140,17 +204,25 @@ func (periods GoodsNomenclatureDescriptionPeriods) QueueBatch(ctx context.Contex
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM goods_nomenclature_description_period
+	WHERE parent_sid = $1
+		AND sid <> ALL($2::INT[]);
+	`
+
+	sids := make([]int, 0, len(periods))
 	for _, period := range periods {
 		period.ParentSID = parentSID
+		sids = append(sids, period.SID)
 		batch.Queue(insertQuery, period.SID, period.ParentSID, period.DateStart, period.DateEnd, period.National)
 
 		// Queue child descriptions
-		if len(period.GoodsNomenclatureDescriptions) > 0 {
-			if err := period.GoodsNomenclatureDescriptions.QueueBatch(ctx, batch, period.SID); err != nil {
-				return fmt.Errorf("failed to queue descriptions for SID %d: %w", period.SID, err)
-			}
+		if err := period.GoodsNomenclatureDescriptions.QueueBatch(ctx, batch, period.SID); err != nil {
+			return fmt.Errorf("failed to queue descriptions for SID %d: %w", period.SID, err)
 		}
 	}
+	batch.Queue(deleteQuery, parentSID, sids)
+
 	return nil
 }
 
@@ -172,10 +244,20 @@ func (descriptions GoodsNomenclatureDescriptions) QueueBatch(ctx context.Context
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM goods_nomenclature_description
+	WHERE parent_sid = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentSID = parentSID
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentSID, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentSID, languageIDs)
+
 	return nil
 }
 
@@ -200,10 +282,24 @@ func (associations GoodsNomenclatureFootnoteAssociations) QueueBatch(ctx context
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM goods_nomenclature_footnote_association
+	WHERE parent_sid = $1
+		AND (footnote_id, footnote_type) NOT IN (
+			SELECT * FROM unnest($2::INT[], $3::TEXT[])
+		);
+	`
+
+	footnoteIDs := make([]int, 0, len(associations))
+	footnoteTypes := make([]string, 0, len(associations))
 	for _, assoc := range associations {
 		assoc.ParentSID = parentSID
+		footnoteIDs = append(footnoteIDs, assoc.FootnoteID)
+		footnoteTypes = append(footnoteTypes, assoc.FootnoteType)
 		batch.Queue(insertQuery, assoc.ParentSID, assoc.DateStart, assoc.DateEnd, assoc.FootnoteID, assoc.FootnoteType, assoc.National)
 	}
+	batch.Queue(deleteQuery, parentSID, footnoteIDs, footnoteTypes)
+
 	return nil
 }
 
@@ -228,9 +324,23 @@ func (memberships GoodsNomenclatureGroupMemberships) QueueBatch(ctx context.Cont
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM goods_nomenclature_group_membership
+	WHERE parent_sid = $1
+		AND (goods_nomenclature_group_id, goods_nomenclature_group_type) NOT IN (
+			SELECT * FROM unnest($2::TEXT[], $3::TEXT[])
+		);
+	`
+
+	groupIDs := make([]string, 0, len(memberships))
+	groupTypes := make([]string, 0, len(memberships))
 	for _, membership := range memberships {
 		membership.ParentSID = parentSID
+		groupIDs = append(groupIDs, membership.GoodsNomenclatureGroupID)
+		groupTypes = append(groupTypes, membership.GoodsNomenclatureGroupType)
 		batch.Queue(insertQuery, membership.ParentSID, membership.DateStart, membership.DateEnd, membership.GoodsNomenclatureGroupID, membership.GoodsNomenclatureGroupType, membership.National)
 	}
+	batch.Queue(deleteQuery, parentSID, groupIDs, groupTypes)
+
 	return nil
 }
@@ -20,7 +20,7 @@ type GoodsNomenclatureGroup struct {
 	GoodsNomenclatureGroupDescriptions GoodsNomenclatureGroupDescriptions `xml:"goodsNomenclatureGroupDescription"`
 }
 
-func (groups GoodsNomenclatureGroups) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (groups GoodsNomenclatureGroups) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO goods_nomenclature_group (goods_nomenclature_group_id, goods_nomenclature_group_type, change_type, date_start, date_end, nomenclature_group_facility_code, national)
 	VALUES ($1, $2, $3, $4, $5, $6, $7)
@@ -45,10 +45,8 @@ func (groups GoodsNomenclatureGroups) BatchInsert(ctx context.Context, conn *pgx
 			batch.Queue(insertQuery, group.GoodsNomenclatureGroupID, group.GoodsNomenclatureGroupType, group.ChangeType, group.DateStart, group.DateEnd, group.NomenclatureGroupFacilityCode, group.National)
 
 			// Queue child descriptions
-			if len(group.GoodsNomenclatureGroupDescriptions) > 0 {
-				if err := group.GoodsNomenclatureGroupDescriptions.QueueBatch(ctx, batch, group.GoodsNomenclatureGroupID, group.GoodsNomenclatureGroupType); err != nil {
-					return fmt.Errorf("failed to queue descriptions for GoodsNomenclatureGroupID %s, GoodsNomenclatureGroupType %s: %w", group.GoodsNomenclatureGroupID, group.GoodsNomenclatureGroupType, err)
-				}
+			if err := group.GoodsNomenclatureGroupDescriptions.QueueBatch(ctx, batch, group.GoodsNomenclatureGroupID, group.GoodsNomenclatureGroupType); err != nil {
+				return fmt.Errorf("failed to queue descriptions for GoodsNomenclatureGroupID %s, GoodsNomenclatureGroupType %s: %w", group.GoodsNomenclatureGroupID, group.GoodsNomenclatureGroupType, err)
 			}
 
 		case "D": // Delete
@@ -59,7 +57,7 @@ func (groups GoodsNomenclatureGroups) BatchInsert(ctx context.Context, conn *pgx
 		}
 
 		if (i+1)%batchSize == 0 || i == len(groups)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -88,10 +86,22 @@ func (descriptions GoodsNomenclatureGroupDescriptions) QueueBatch(ctx context.Co
 		national = EXCLUDED.national;
 	`
 
+	// Descriptions missing from an update have been removed from the group
+	deleteQuery := `
+	DELETE FROM goods_nomenclature_group_description
+	WHERE parent_goods_nomenclature_group_id = $1
+		AND parent_goods_nomenclature_group_type = $2
+		AND language_id <> ALL($3::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentGoodsNomenclatureGroupID = parentID
 		desc.ParentGoodsNomenclatureGroupType = parentType
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentGoodsNomenclatureGroupID, desc.ParentGoodsNomenclatureGroupType, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentID, parentType, languageIDs)
+
 	return nil
 }
@@ -21,7 +21,7 @@ type LookupTable struct {
 	LookupTableDescription LookupTableDescriptions `xml:"lookupTableDescription"`
 }
 
-func (tables LookupTables) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (tables LookupTables) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO lookup_table (sid, table_id, change_type, date_start, interpolate, max_interval, min_interval)
 	VALUES ($1, $2, $3, $4, $5, $6, $7)
@@ -47,17 +47,13 @@ func (tables LookupTables) BatchInsert(ctx context.Context, conn *pgx.Conn, batc
 			batch.Queue(insertQuery, table.SID, table.TableID, table.ChangeType, table.DateStart, table.Interpolate, table.MaxInterval, table.MinInterval)
 
 			// Queue child items
-			if len(table.LookupTableItem) > 0 {
-				if err := table.LookupTableItem.QueueBatch(ctx, batch, table.SID); err != nil {
-					return fmt.Errorf("failed to queue lookup table items for SID %d: %w", table.SID, err)
-				}
+			if err := table.LookupTableItem.QueueBatch(ctx, batch, table.SID); err != nil {
+				return fmt.Errorf("failed to queue lookup table items for SID %d: %w", table.SID, err)
 			}
 
 			// Queue child descriptions
-			if len(table.LookupTableDescription) > 0 {
-				if err := table.LookupTableDescription.QueueBatch(ctx, batch, table.SID); err != nil {
-					return fmt.Errorf("failed to queue lookup table descriptions for SID %d: %w", table.SID, err)
-				}
+			if err := table.LookupTableDescription.QueueBatch(ctx, batch, table.SID); err != nil {
+				return fmt.Errorf("failed to queue lookup table descriptions for SID %d: %w", table.SID, err)
 			}
 
 		case "D": // Delete
@@ -68,7 +64,7 @@ func (tables LookupTables) BatchInsert(ctx context.Context, conn *pgx.Conn, batc
 		}
 
 		if (i+1)%batchSize == 0 || i == len(tables)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -93,10 +89,25 @@ func (items LookupTableItems) QueueBatch(ctx context.Context, batch *pgx.Batch,
 	ON CONFLICT (parent_sid, threshold, value) DO NOTHING;
 	`
 
+	// Children missing from an update have been removed from the lookup table
+	deleteQuery := `
+	DELETE FROM lookup_table_item
+	WHERE parent_sid = $1
+		AND (threshold, value) NOT IN (
+			SELECT * FROM unnest($2::FLOAT[], $3::FLOAT[])
+		);
+	`
+
+	thresholds := make([]float64, 0, len(items))
+	values := make([]float64, 0, len(items))
 	for _, item := range items {
 		item.ParentSID = parentSID
+		thresholds = append(thresholds, item.Threshold)
+		values = append(values, item.Value)
 		batch.Queue(insertQuery, item.ParentSID, item.Threshold, item.Value)
 	}
+	batch.Queue(deleteQuery, parentSID, thresholds, values)
+
 	return nil
 }
 
@@ -116,9 +127,19 @@ func (descriptions LookupTableDescriptions) QueueBatch(ctx context.Context, batc
 	SET description = EXCLUDED.description;
 	`
 
+	deleteQuery := `
+	DELETE FROM lookup_table_description
+	WHERE parent_sid = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentSID = parentSID
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentSID, desc.Description, desc.LanguageID)
 	}
+	batch.Queue(deleteQuery, parentSID, languageIDs)
+
 	return nil
 }
@@ -39,7 +39,7 @@ type Measure struct {
 	MeasurePartialTemporaryStops     MeasurePartialTemporaryStops     `xml:"measurePartialTemporaryStop"`
 }
 
-func (measures Measures) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (measures Measures) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO measure (
 		sid, sid_additional_code, sid_export_refund_nomenclature, sid_geographical_area,
@@ -88,34 +88,24 @@ func (measures Measures) BatchInsert(ctx context.Context, conn *pgx.Conn, batchS
 			batch.Queue(insertQuery, measure.SID, measure.SIDAdditionalCode, measure.SIDExportRefundNomenclature, measure.SIDGeographicalArea, measure.SIDGoodsNomenclature, measure.AdditionalCodeID, measure.AdditionalCodeType, measure.ChangeType, measure.DateEnd, measure.DateStart, measure.Expression, measure.GeographicalAreaID, fmt.Sprint(measure.GoodsNomenclatureCode), measure.JustificationRegulationID, measure.JustificationRegulationRoleType, measure.MeasureType, measure.National, measure.QuotaOrderNumber, measure.ReductionIndicator, measure.RegulationID, measure.RegulationRoleType, measure.StoppedFlag)
 
 			// Queue child elements (already implemented in previous methods)
-			if len(measure.MeasureConditions) > 0 {
-				if err := measure.MeasureConditions.QueueBatch(ctx, batch, measure.SID); err != nil {
-					return fmt.Errorf("failed to queue measure conditions for SID %d: %w", measure.SID, err)
-				}
+			if err := measure.MeasureConditions.QueueBatch(ctx, batch, measure.SID); err != nil {
+				return fmt.Errorf("failed to queue measure conditions for SID %d: %w", measure.SID, err)
 			}
 
-			if len(measure.MeasureFootnoteAssociations) > 0 {
-				if err := measure.MeasureFootnoteAssociations.QueueBatch(ctx, batch, measure.SID); err != nil {
-					return fmt.Errorf("failed to queue measure footnotes for SID %d: %w", measure.SID, err)
-				}
+			if err := measure.MeasureFootnoteAssociations.QueueBatch(ctx, batch, measure.SID); err != nil {
+				return fmt.Errorf("failed to queue measure footnotes for SID %d: %w", measure.SID, err)
 			}
 
-			if len(measure.MeasureComponents) > 0 {
-				if err := measure.MeasureComponents.QueueBatch(ctx, batch, measure.SID); err != nil {
-					return fmt.Errorf("failed to queue measure components for SID %d: %w", measure.SID, err)
-				}
+			if err := measure.MeasureComponents.QueueBatch(ctx, batch, measure.SID); err != nil {
+				return fmt.Errorf("failed to queue measure components for SID %d: %w", measure.SID, err)
 			}
 
-			if len(measure.MeasureExcludedGeographicalAreas) > 0 {
-				if err := measure.MeasureExcludedGeographicalAreas.QueueBatch(ctx, batch, measure.SID); err != nil {
-					return fmt.Errorf("failed to queue excluded geographical areas for SID %d: %w", measure.SID, err)
-				}
+			if err := measure.MeasureExcludedGeographicalAreas.QueueBatch(ctx, batch, measure.SID); err != nil {
+				return fmt.Errorf("failed to queue excluded geographical areas for SID %d: %w", measure.SID, err)
 			}
 
-			if len(measure.MeasurePartialTemporaryStops) > 0 {
-				if err := measure.MeasurePartialTemporaryStops.QueueBatch(ctx, batch, measure.SID); err != nil {
-					return fmt.Errorf("failed to queue partial temporary stops for SID %d: %w", measure.SID, err)
-				}
+			if err := measure.MeasurePartialTemporaryStops.QueueBatch(ctx, batch, measure.SID); err != nil {
+				return fmt.Errorf("failed to queue partial temporary stops for SID %d: %w", measure.SID, err)
 			}
 
 		case "D": // Delete
@@ -126,7 +116,7 @@ func (measures Measures) BatchInsert(ctx context.Context, conn *pgx.Conn, batchS
 		}
 
 		if (i+1)%batchSize == 0 || i == len(measures)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -136,6 +126,83 @@ func (measures Measures) BatchInsert(ctx context.Context, conn *pgx.Conn, batchS
 	return nil
 }
 
+// BulkInsert loads the measures and their child elements with COPY.
+// Elements that are not inserts or updates are passed on to BatchInsert.
+func (measures Measures) BulkInsert(ctx context.Context, db DB) error {
+	measureRows := newBulkTable("measure", []string{
+		"sid", "sid_additional_code", "sid_export_refund_nomenclature", "sid_geographical_area",
+		"sid_goods_nomenclature", "additional_code_id", "additional_code_type", "change_type",
+		"date_end", "date_start", "expression", "geographical_area_id", "goods_nomenclature_code",
+		"justification_regulation_id", "justification_regulation_role_type", "measure_type",
+		"national", "quota_order_number", "reduction_indicator", "regulation_id", "regulation_role_type", "stopped_flag",
+	}, "sid")
+	conditionRows := newBulkTable("measure_condition", []string{
+		"sid", "parent_sid", "condition_code_id", "sequence_number", "action_code", "certificate_code",
+		"certificate_type", "duty_amount", "expression", "measurement_unit_code", "measurement_unit_qualifier_code", "monetary_unit_code", "national",
+	}, "sid")
+	conditionComponentRows := newBulkTable("measure_condition_component", []string{
+		"parent_sid", "duty_amount", "duty_expression_id", "measurement_unit_code", "measurement_unit_qualifier_code", "monetary_unit_code", "national", "sequence_number",
+	}, "parent_sid", "duty_expression_id")
+	footnoteRows := newBulkTable("measure_footnote_association", []string{
+		"parent_sid", "footnote_id", "footnote_type", "national",
+	}, "parent_sid", "footnote_id", "footnote_type")
+	componentRows := newBulkTable("measure_component", []string{
+		"parent_sid", "duty_amount", "duty_expression_id", "measurement_unit_code", "measurement_unit_qualifier_code", "monetary_unit_code", "national", "sequence_number",
+	}, "parent_sid", "duty_expression_id")
+	excludedAreaRows := newBulkTable("measure_excluded_geographical_area", []string{
+		"parent_sid", "geographical_area_id", "sid_geographical_area", "national",
+	}, "parent_sid", "geographical_area_id", "sid_geographical_area")
+	stopRows := newBulkTable("measure_partial_temporary_stop", []string{
+		"parent_sid", "regulation_id", "regulation_role_type", "national",
+	}, "parent_sid")
+
+	var other Measures
+	for _, measure := range measures {
+		if measure.ChangeType != "U" {
+			other = append(other, measure)
+			continue
+		}
+
+		measureRows.add(measure.SID, measure.SIDAdditionalCode, measure.SIDExportRefundNomenclature, measure.SIDGeographicalArea, measure.SIDGoodsNomenclature, measure.AdditionalCodeID, measure.AdditionalCodeType, measure.ChangeType, measure.DateEnd, measure.DateStart, measure.Expression, measure.GeographicalAreaID, fmt.Sprint(measure.GoodsNomenclatureCode), measure.JustificationRegulationID, measure.JustificationRegulationRoleType, measure.MeasureType, measure.National, measure.QuotaOrderNumber, measure.ReductionIndicator, measure.RegulationID, measure.RegulationRoleType, measure.StoppedFlag)
+
+		for _, condition := range measure.MeasureConditions {
+			conditionRows.add(condition.SID, measure.SID, condition.ConditionCodeID, condition.SequenceNumber, condition.ActionCode, condition.CertificateCode, condition.CertificateType, condition.DutyAmount, condition.Expression, condition.MeasurementUnitCode, condition.MeasurementUnitQualifierCode, condition.MonetaryUnitCode, condition.National)
+
+			for i, component := range condition.MeasureConditionComponent {
+				// Handle potential nil values for duty_amount
+				dutyAmount := float64(0.0)
+				if component.DutyAmount != nil {
+					dutyAmount = *component.DutyAmount
+				}
+				conditionComponentRows.add(condition.SID, dutyAmount, component.DutyExpressionID, component.MeasurementUnitCode, component.MeasurementUnitQualifierCode, component.MonetaryUnitCode, component.National, i+1)
+			}
+		}
+		for _, assoc := range measure.MeasureFootnoteAssociations {
+			footnoteRows.add(measure.SID, assoc.FootnoteID, assoc.FootnoteType, assoc.National)
+		}
+		for i, comp := range measure.MeasureComponents {
+			componentRows.add(measure.SID, comp.DutyAmount, comp.DutyExpressionID, comp.MeasurementUnitCode, comp.MeasurementUnitQualifierCode, comp.MonetaryUnitCode, comp.National, i+1)
+		}
+		for _, area := range measure.MeasureExcludedGeographicalAreas {
+			excludedAreaRows.add(measure.SID, area.GeographicalAreaID, area.SIDGeographicalArea, area.National)
+		}
+		for _, stop := range measure.MeasurePartialTemporaryStops {
+			stopRows.add(measure.SID, stop.RegulationID, stop.RegulationRoleType, stop.National)
+		}
+	}
+
+	err := copyMerge(ctx, db, measureRows, conditionRows, conditionComponentRows, footnoteRows, componentRows, excludedAreaRows, stopRows)
+	if err != nil {
+		return fmt.Errorf("copyMerge: %w", err)
+	}
+
+	if len(other) > 0 {
+		return other.BatchInsert(ctx, db, len(other))
+	}
+
+	return nil
+}
+
 type MeasureConditions []MeasureCondition
 
 type MeasureCondition struct {
@@ -176,17 +243,26 @@ func (conditions MeasureConditions) QueueBatch(ctx context.Context, batch *pgx.B
 		national = EXCLUDED.national;
 	`
 
+	// Conditions missing from an update have been removed from the measure
+	deleteQuery := `
+	DELETE FROM measure_condition
+	WHERE parent_sid = $1
+		AND sid <> ALL($2::INT[]);
+	`
+
+	sids := make([]int, 0, len(conditions))
 	for _, condition := range conditions {
 		condition.ParentSID = parentSID
+		sids = append(sids, condition.SID)
 		batch.Queue(insertQuery, condition.SID, condition.ParentSID, condition.ConditionCodeID, condition.SequenceNumber, condition.ActionCode, condition.CertificateCode, condition.CertificateType, condition.DutyAmount, condition.Expression, condition.MeasurementUnitCode, condition.MeasurementUnitQualifierCode, condition.MonetaryUnitCode, condition.National)
 
 		// Queue child components
-		if len(condition.MeasureConditionComponent) > 0 {
-			if err := condition.MeasureConditionComponent.QueueBatch(ctx, batch, condition.SID); err != nil {
-				return fmt.Errorf("failed to queue condition components for Condition SID %d: %w", condition.SID, err)
-			}
+		if err := condition.MeasureConditionComponent.QueueBatch(ctx, batch, condition.SID); err != nil {
+			return fmt.Errorf("failed to queue condition components for Condition SID %d: %w", condition.SID, err)
 		}
 	}
+	batch.Queue(deleteQuery, parentSID, sids)
+
 	return nil
 }
 
@@ -205,19 +281,28 @@ type MeasureConditionComponent struct {
 func (components MeasureConditionComponents) QueueBatch(ctx context.Context, batch *pgx.Batch, parentSID int) error {
 	insertQuery := `
 	INSERT INTO measure_condition_component (
-		parent_sid, duty_amount, duty_expression_id, measurement_unit_code, measurement_unit_qualifier_code, monetary_unit_code, national
+		parent_sid, duty_amount, duty_expression_id, measurement_unit_code, measurement_unit_qualifier_code, monetary_unit_code, national, sequence_number
 	)
-	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	ON CONFLICT (parent_sid, duty_expression_id) DO UPDATE 
 	SET duty_amount = EXCLUDED.duty_amount,
 		measurement_unit_code = EXCLUDED.measurement_unit_code,
 		measurement_unit_qualifier_code = EXCLUDED.measurement_unit_qualifier_code,
 		monetary_unit_code = EXCLUDED.monetary_unit_code,
-		national = EXCLUDED.national;
+		national = EXCLUDED.national,
+		sequence_number = EXCLUDED.sequence_number;
+	`
+
+	deleteQuery := `
+	DELETE FROM measure_condition_component
+	WHERE parent_sid = $1
+		AND duty_expression_id <> ALL($2::INT[]);
 	`
 
-	for _, component := range components {
+	dutyExpressionIDs := make([]int, 0, len(components))
+	for i, component := range components {
 		component.ParentSID = parentSID
+		dutyExpressionIDs = append(dutyExpressionIDs, component.DutyExpressionID)
 
 		// Handle potential nil values for duty_amount
 		dutyAmount := float64(0.0)
@@ -225,8 +310,10 @@ func (components MeasureConditionComponents) QueueBatch(ctx context.Context, bat
 			dutyAmount = *component.DutyAmount
 		}
 
-		batch.Queue(insertQuery, component.ParentSID, dutyAmount, component.DutyExpressionID, component.MeasurementUnitCode, component.MeasurementUnitQualifierCode, component.MonetaryUnitCode, component.National)
+		batch.Queue(insertQuery, component.ParentSID, dutyAmount, component.DutyExpressionID, component.MeasurementUnitCode, component.MeasurementUnitQualifierCode, component.MonetaryUnitCode, component.National, i+1)
 	}
+	batch.Queue(deleteQuery, parentSID, dutyExpressionIDs)
+
 	return nil
 }
 
@@ -247,10 +334,24 @@ func (associations MeasureFootnoteAssociations) QueueBatch(ctx context.Context,
 	SET national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM measure_footnote_association
+	WHERE parent_sid = $1
+		AND (footnote_id, footnote_type) NOT IN (
+			SELECT * FROM unnest($2::TEXT[], $3::TEXT[])
+		);
+	`
+
+	footnoteIDs := make([]string, 0, len(associations))
+	footnoteTypes := make([]string, 0, len(associations))
 	for _, assoc := range associations {
 		assoc.ParentSID = parentSID
+		footnoteIDs = append(footnoteIDs, assoc.FootnoteID)
+		footnoteTypes = append(footnoteTypes, assoc.FootnoteType)
 		batch.Queue(insertQuery, assoc.ParentSID, assoc.FootnoteID, assoc.FootnoteType, assoc.National)
 	}
+	batch.Queue(deleteQuery, parentSID, footnoteIDs, footnoteTypes)
+
 	return nil
 }
 
@@ -268,20 +369,32 @@ type MeasureComponent struct {
 
 func (components MeasureComponents) QueueBatch(ctx context.Context, batch *pgx.Batch, parentSID int) error {
 	insertQuery := `
-	INSERT INTO measure_component (parent_sid, duty_amount, duty_expression_id, measurement_unit_code, measurement_unit_qualifier_code, monetary_unit_code, national)
-	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	INSERT INTO measure_component (parent_sid, duty_amount, duty_expression_id, measurement_unit_code, measurement_unit_qualifier_code, monetary_unit_code, national, sequence_number)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	ON CONFLICT (parent_sid, duty_expression_id) DO UPDATE 
 	SET duty_amount = EXCLUDED.duty_amount,
 		measurement_unit_code = EXCLUDED.measurement_unit_code,
 		measurement_unit_qualifier_code = EXCLUDED.measurement_unit_qualifier_code,
 		monetary_unit_code = EXCLUDED.monetary_unit_code,
-		national = EXCLUDED.national;
+		national = EXCLUDED.national,
+		sequence_number = EXCLUDED.sequence_number;
+	`
+
+	deleteQuery := `
+	DELETE FROM measure_component
+	WHERE parent_sid = $1
+		AND duty_expression_id <> ALL($2::INT[]);
 	`
 
-	for _, comp := range components {
+	dutyExpressionIDs := make([]int, 0, len(components))
+	for i, comp := range components {
 		comp.ParentSID = parentSID
-		batch.Queue(insertQuery, comp.ParentSID, comp.DutyAmount, comp.DutyExpressionID, comp.MeasurementUnitCode, comp.MeasurementUnitQualifierCode, comp.MonetaryUnitCode, comp.National)
+		dutyExpressionIDs = append(dutyExpressionIDs, comp.DutyExpressionID)
+		// Components are numbered in the order they are written, which they are calculated and formatted in
+		batch.Queue(insertQuery, comp.ParentSID, comp.DutyAmount, comp.DutyExpressionID, comp.MeasurementUnitCode, comp.MeasurementUnitQualifierCode, comp.MonetaryUnitCode, comp.National, i+1)
 	}
+	batch.Queue(deleteQuery, parentSID, dutyExpressionIDs)
+
 	return nil
 }
 
@@ -302,10 +415,24 @@ func (areas MeasureExcludedGeographicalAreas) QueueBatch(ctx context.Context, ba
 	SET national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM measure_excluded_geographical_area
+	WHERE parent_sid = $1
+		AND (geographical_area_id, sid_geographical_area) NOT IN (
+			SELECT * FROM unnest($2::TEXT[], $3::INT[])
+		);
+	`
+
+	areaIDs := make([]string, 0, len(areas))
+	areaSIDs := make([]int, 0, len(areas))
 	for _, area := range areas {
 		area.ParentSID = parentSID
+		areaIDs = append(areaIDs, area.GeographicalAreaID)
+		areaSIDs = append(areaSIDs, area.SIDGeographicalArea)
 		batch.Queue(insertQuery, area.ParentSID, area.GeographicalAreaID, area.SIDGeographicalArea, area.National)
 	}
+	batch.Queue(deleteQuery, parentSID, areaIDs, areaSIDs)
+
 	return nil
 }
 
@@ -328,9 +455,19 @@ func (stops MeasurePartialTemporaryStops) QueueBatch(ctx context.Context, batch
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM measure_partial_temporary_stop
+	WHERE parent_sid = $1
+		AND regulation_id <> ALL($2::TEXT[]);
+	`
+
+	regulationIDs := make([]string, 0, len(stops))
 	for _, stop := range stops {
 		stop.ParentSID = parentSID
+		regulationIDs = append(regulationIDs, stop.RegulationID)
 		batch.Queue(insertQuery, stop.ParentSID, stop.RegulationID, stop.RegulationRoleType, stop.National)
 	}
+	batch.Queue(deleteQuery, parentSID, regulationIDs)
+
 	return nil
 }
@@ -18,7 +18,7 @@ type MeasureAction struct {
 	MeasureActionDescriptions MeasureActionDescriptions `xml:"measureActionDescription"`
 }
 
-func (actions MeasureActions) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (actions MeasureActions) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO measure_action (action_code, change_type, date_start, date_end, national)
 	VALUES ($1, $2, $3, $4, $5)
@@ -42,10 +42,8 @@ func (actions MeasureActions) BatchInsert(ctx context.Context, conn *pgx.Conn, b
 			batch.Queue(insertQuery, action.ActionCode, action.ChangeType, action.DateStart, action.DateEnd, action.National)
 
 			// Queue child descriptions
-			if len(action.MeasureActionDescriptions) > 0 {
-				if err := action.MeasureActionDescriptions.QueueBatch(ctx, batch, action.ActionCode); err != nil {
-					return fmt.Errorf("failed to queue descriptions for ActionCode %s: %w", action.ActionCode, err)
-				}
+			if err := action.MeasureActionDescriptions.QueueBatch(ctx, batch, action.ActionCode); err != nil {
+				return fmt.Errorf("failed to queue descriptions for ActionCode %s: %w", action.ActionCode, err)
 			}
 
 		case "D": // Delete
@@ -56,7 +54,7 @@ func (actions MeasureActions) BatchInsert(ctx context.Context, conn *pgx.Conn, b
 		}
 
 		if (i+1)%batchSize == 0 || i == len(actions)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -84,9 +82,20 @@ func (descriptions MeasureActionDescriptions) QueueBatch(ctx context.Context, ba
 		national = EXCLUDED.national;
 	`
 
+	// Descriptions missing from an update have been removed from the action
+	deleteQuery := `
+	DELETE FROM measure_action_description
+	WHERE parent_action_code = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentActionCode = parentActionCode
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentActionCode, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentActionCode, languageIDs)
+
 	return nil
 }
@@ -19,7 +19,7 @@ type MeasureConditionCode struct {
 	MeasureConditionCodeDescriptions MeasureConditionCodeDescriptions `xml:"measureConditionCodeDescription"`
 }
 
-func (codes MeasureConditionCodes) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (codes MeasureConditionCodes) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO measure_condition_code (condition_code, change_type, date_start, date_end, type, national)
 	VALUES ($1, $2, $3, $4, $5, $6)
@@ -44,10 +44,8 @@ func (codes MeasureConditionCodes) BatchInsert(ctx context.Context, conn *pgx.Co
 			batch.Queue(insertQuery, code.ConditionCode, code.ChangeType, code.DateStart, code.DateEnd, code.Type, code.National)
 
 			// Queue child descriptions
-			if len(code.MeasureConditionCodeDescriptions) > 0 {
-				if err := code.MeasureConditionCodeDescriptions.QueueBatch(ctx, batch, code.ConditionCode); err != nil {
-					return fmt.Errorf("failed to queue descriptions for ConditionCode %s: %w", code.ConditionCode, err)
-				}
+			if err := code.MeasureConditionCodeDescriptions.QueueBatch(ctx, batch, code.ConditionCode); err != nil {
+				return fmt.Errorf("failed to queue descriptions for ConditionCode %s: %w", code.ConditionCode, err)
 			}
 
 		case "D": // Delete
@@ -58,7 +56,7 @@ func (codes MeasureConditionCodes) BatchInsert(ctx context.Context, conn *pgx.Co
 		}
 
 		if (i+1)%batchSize == 0 || i == len(codes)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -86,9 +84,20 @@ func (descriptions MeasureConditionCodeDescriptions) QueueBatch(ctx context.Cont
 		national = EXCLUDED.national;
 	`
 
+	// Descriptions missing from an update have been removed from the condition code
+	deleteQuery := `
+	DELETE FROM measure_condition_code_description
+	WHERE parent_condition_code = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentConditionCode = parentConditionCode
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentConditionCode, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentConditionCode, languageIDs)
+
 	return nil
 }
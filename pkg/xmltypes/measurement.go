@@ -18,7 +18,7 @@ type Measurement struct {
 	National                     int          `xml:"national,attr"`
 }
 
-func (measurements Measurements) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (measurements Measurements) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO measurement (
 		measurement_unit_code, measurement_unit_qualifier_code, change_type, date_start, date_end, national
@@ -51,7 +51,7 @@ func (measurements Measurements) BatchInsert(ctx context.Context, conn *pgx.Conn
 		}
 
 		if (i+1)%batchSize == 0 || i == len(measurements)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
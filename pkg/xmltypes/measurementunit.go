@@ -19,7 +19,7 @@ type MeasurementUnit struct {
 	MeasurementUnitDescription []MeasurementUnitDescription `xml:"measurementUnitDescription"`
 }
 
-func (units MeasurementUnits) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (units MeasurementUnits) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO measurement_unit (
 		measurement_unit_code, date_start, date_end, national, national_abbreviation, change_type
@@ -46,10 +46,8 @@ func (units MeasurementUnits) BatchInsert(ctx context.Context, conn *pgx.Conn, b
 			batch.Queue(insertQuery, unit.MeasurementUnitCode, unit.DateStart, unit.DateEnd, unit.National, unit.NationalAbbreviation, unit.ChangeType)
 
 			// Queue child descriptions
-			if len(unit.MeasurementUnitDescription) > 0 {
-				if err := MeasurementUnitDescriptions(unit.MeasurementUnitDescription).QueueBatch(ctx, batch, unit.MeasurementUnitCode); err != nil {
-					return fmt.Errorf("failed to queue descriptions for MeasurementUnitCode %s: %w", unit.MeasurementUnitCode, err)
-				}
+			if err := MeasurementUnitDescriptions(unit.MeasurementUnitDescription).QueueBatch(ctx, batch, unit.MeasurementUnitCode); err != nil {
+				return fmt.Errorf("failed to queue descriptions for MeasurementUnitCode %s: %w", unit.MeasurementUnitCode, err)
 			}
 
 		case "D": // Delete
@@ -60,7 +58,7 @@ func (units MeasurementUnits) BatchInsert(ctx context.Context, conn *pgx.Conn, b
 		}
 
 		if (i+1)%batchSize == 0 || i == len(units)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -89,8 +87,19 @@ func (descriptions MeasurementUnitDescriptions) QueueBatch(ctx context.Context,
 		national = EXCLUDED.national;
 	`
 
+	// Descriptions missing from an update have been removed from the unit
+	deleteQuery := `
+	DELETE FROM measurement_unit_description
+	WHERE parent_unit_code = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, parentUnitCode, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentUnitCode, languageIDs)
+
 	return nil
 }
@@ -17,7 +17,7 @@ type MeasurementUnitQualifier struct {
 	MeasurementUnitQualifierDescriptions MeasurementUnitQualifierDescriptions `xml:"measurementUnitQualifierDescription"`
 }
 
-func (qualifiers MeasurementUnitQualifiers) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (qualifiers MeasurementUnitQualifiers) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO measurement_unit_qualifier (
 		measurement_unit_qualifier_code, change_type, date_start, national
@@ -42,10 +42,8 @@ func (qualifiers MeasurementUnitQualifiers) BatchInsert(ctx context.Context, con
 			batch.Queue(insertQuery, qualifier.MeasurementUnitQualifierCode, qualifier.ChangeType, qualifier.DateStart, qualifier.National)
 
 			// Queue child descriptions
-			if len(qualifier.MeasurementUnitQualifierDescriptions) > 0 {
-				if err := qualifier.MeasurementUnitQualifierDescriptions.QueueBatch(ctx, batch, qualifier.MeasurementUnitQualifierCode); err != nil {
-					return fmt.Errorf("failed to queue descriptions for MeasurementUnitQualifierCode %s: %w", qualifier.MeasurementUnitQualifierCode, err)
-				}
+			if err := qualifier.MeasurementUnitQualifierDescriptions.QueueBatch(ctx, batch, qualifier.MeasurementUnitQualifierCode); err != nil {
+				return fmt.Errorf("failed to queue descriptions for MeasurementUnitQualifierCode %s: %w", qualifier.MeasurementUnitQualifierCode, err)
 			}
 
 		case "D": // Delete
@@ -56,7 +54,7 @@ func (qualifiers MeasurementUnitQualifiers) BatchInsert(ctx context.Context, con
 		}
 
 		if (i+1)%batchSize == 0 || i == len(qualifiers)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -86,9 +84,20 @@ func (descriptions MeasurementUnitQualifierDescriptions) QueueBatch(ctx context.
 		national = EXCLUDED.national;
 	`
 
+	// Descriptions missing from an update have been removed from the qualifier
+	deleteQuery := `
+	DELETE FROM measurement_unit_qualifier_description
+	WHERE parent_measurement_unit_qualifier_code = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentMeasurementUnitQualifierCode = parentQualifierCode
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentMeasurementUnitQualifierCode, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentQualifierCode, languageIDs)
+
 	return nil
 }
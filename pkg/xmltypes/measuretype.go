@@ -25,7 +25,7 @@ type MeasureType struct {
 	MeasureTypeDescriptions        MeasureTypeDescriptions `xml:"measureTypeDescription"`
 }
 
-func (types MeasureTypes) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (types MeasureTypes) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO measure_type (
 		measure_type, measure_type_series_id, change_type, date_start, date_end, explosion_level,
@@ -60,10 +60,8 @@ func (types MeasureTypes) BatchInsert(ctx context.Context, conn *pgx.Conn, batch
 			batch.Queue(insertQuery, measureType.MeasureType, measureType.MeasureTypeSeriesID, measureType.ChangeType, measureType.DateStart, measureType.DateEnd, measureType.ExplosionLevel, measureType.MeasureComponentApplicableCode, measureType.OrderNumberCaptureCode, measureType.OriginDestinationCode, measureType.PriorityCode, measureType.TradeMovementCode, measureType.National)
 
 			// Queue child descriptions
-			if len(measureType.MeasureTypeDescriptions) > 0 {
-				if err := measureType.MeasureTypeDescriptions.QueueBatch(ctx, batch, measureType.MeasureType); err != nil {
-					return fmt.Errorf("failed to queue descriptions for MeasureType %s: %w", measureType.MeasureType, err)
-				}
+			if err := measureType.MeasureTypeDescriptions.QueueBatch(ctx, batch, measureType.MeasureType); err != nil {
+				return fmt.Errorf("failed to queue descriptions for MeasureType %s: %w", measureType.MeasureType, err)
 			}
 
 		case "D": // Delete
@@ -74,7 +72,7 @@ func (types MeasureTypes) BatchInsert(ctx context.Context, conn *pgx.Conn, batch
 		}
 
 		if (i+1)%batchSize == 0 || i == len(types)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -102,9 +100,20 @@ func (descriptions MeasureTypeDescriptions) QueueBatch(ctx context.Context, batc
 		national = EXCLUDED.national;
 	`
 
+	// Descriptions missing from an update have been removed from the measure type
+	deleteQuery := `
+	DELETE FROM measure_type_description
+	WHERE parent_measure_type = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
 		desc.ParentMeasureType = parentMeasureType
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, desc.ParentMeasureType, desc.Description, desc.LanguageID, desc.National)
 	}
+	batch.Queue(deleteQuery, parentMeasureType, languageIDs)
+
 	return nil
 }
@@ -19,7 +19,7 @@ type MeursingAdditionalCode struct {
 	MeursingTableCellComponents MeursingTableCellComponents `xml:"meursingTableCellComponent"`
 }
 
-func (codes MeursingAdditionalCodes) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (codes MeursingAdditionalCodes) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO meursing_additional_code (
 		meursing_table_plan_id, additional_code_id, date_start, date_end, national, change_type
@@ -46,10 +46,8 @@ func (codes MeursingAdditionalCodes) BatchInsert(ctx context.Context, conn *pgx.
 			batch.Queue(insertQuery, code.SID, code.AdditionalCodeID, code.DateStart, nil, code.National, code.ChangeType)
 
 			// Queue child components
-			if len(code.MeursingTableCellComponents) > 0 {
-				if err := code.MeursingTableCellComponents.QueueBatch(ctx, batch, code.SID); err != nil {
-					return fmt.Errorf("failed to queue cell components for SID %d: %w", code.SID, err)
-				}
+			if err := code.MeursingTableCellComponents.QueueBatch(ctx, batch, code.SID); err != nil {
+				return fmt.Errorf("failed to queue cell components for SID %d: %w", code.SID, err)
 			}
 
 		case "D": // Delete
@@ -60,7 +58,7 @@ func (codes MeursingAdditionalCodes) BatchInsert(ctx context.Context, conn *pgx.
 		}
 
 		if (i+1)%batchSize == 0 || i == len(codes)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -92,10 +90,26 @@ func (components MeursingTableCellComponents) QueueBatch(ctx context.Context, ba
 		national = EXCLUDED.national;
 	`
 
+	// Components missing from an update have been removed from the additional code
+	deleteQuery := `
+	DELETE FROM meursing_table_cell_component
+	WHERE parent_table_plan_id = $1
+		AND (heading_number, row_column_code, subheading_sequence_number) NOT IN (
+			SELECT * FROM unnest($2::INT[], $3::INT[], $4::INT[])
+		);
+	`
+
+	headingNumbers := make([]int, 0, len(components))
+	rowColumnCodes := make([]int, 0, len(components))
+	sequenceNumbers := make([]int, 0, len(components))
 	for _, component := range components {
 		component.MeursingTablePlanID = parentTablePlanID
+		headingNumbers = append(headingNumbers, component.HeadingNumber)
+		rowColumnCodes = append(rowColumnCodes, component.RowColumnCode)
+		sequenceNumbers = append(sequenceNumbers, component.SubheadingSequenceNumber)
 		batch.Queue(insertQuery, component.MeursingTablePlanID, component.HeadingNumber, component.RowColumnCode, component.SubheadingSequenceNumber, component.DateStart, component.National)
 	}
+	batch.Queue(deleteQuery, parentTablePlanID, headingNumbers, rowColumnCodes, sequenceNumbers)
 
 	return nil
 }
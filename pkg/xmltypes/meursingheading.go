@@ -20,7 +20,7 @@ type MeursingHeading struct {
 	MeursingHeadingText                 MeursingHeadingTexts                `xml:"meursingHeadingText"`
 }
 
-func (headings MeursingHeadings) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (headings MeursingHeadings) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO meursing_heading (
 		heading_number, meursing_table_plan_id, row_column_code, date_start, national, change_type
@@ -37,6 +37,18 @@ func (headings MeursingHeadings) BatchInsert(ctx context.Context, conn *pgx.Conn
 	WHERE heading_number = $1 AND meursing_table_plan_id = $2 AND row_column_code = $3;
 	`
 
+	// Children are keyed by heading number only, so they are kept while another heading has the same number
+	deleteChildrenQuery := `
+	WITH associations AS (
+		DELETE FROM meursing_heading_footnote_association
+		WHERE parent_heading_id = $1
+			AND NOT EXISTS (SELECT 1 FROM meursing_heading WHERE heading_number = $1)
+	)
+	DELETE FROM meursing_heading_text
+	WHERE parent_heading_id = $1
+		AND NOT EXISTS (SELECT 1 FROM meursing_heading WHERE heading_number = $1);
+	`
+
 	batch := &pgx.Batch{}
 
 	for i, heading := range headings {
@@ -45,28 +57,25 @@ func (headings MeursingHeadings) BatchInsert(ctx context.Context, conn *pgx.Conn
 			batch.Queue(insertQuery, heading.HeadingNumber, heading.MeursingTablePlanID, heading.RowColumnCode, heading.DateStart, heading.National, heading.ChangeType)
 
 			// Queue child footnote associations
-			if len(heading.MeursingHeadingFootnoteAssociations) > 0 {
-				if err := heading.MeursingHeadingFootnoteAssociations.QueueBatch(ctx, batch, heading.HeadingNumber); err != nil {
-					return fmt.Errorf("failed to queue footnote associations for HeadingNumber %d: %w", heading.HeadingNumber, err)
-				}
+			if err := heading.MeursingHeadingFootnoteAssociations.QueueBatch(ctx, batch, heading.HeadingNumber); err != nil {
+				return fmt.Errorf("failed to queue footnote associations for HeadingNumber %d: %w", heading.HeadingNumber, err)
 			}
 
 			// Queue child texts
-			if len(heading.MeursingHeadingText) > 0 {
-				if err := heading.MeursingHeadingText.QueueBatch(ctx, batch, heading.HeadingNumber); err != nil {
-					return fmt.Errorf("failed to queue texts for HeadingNumber %d: %w", heading.HeadingNumber, err)
-				}
+			if err := heading.MeursingHeadingText.QueueBatch(ctx, batch, heading.HeadingNumber); err != nil {
+				return fmt.Errorf("failed to queue texts for HeadingNumber %d: %w", heading.HeadingNumber, err)
 			}
 
 		case "D": // Delete
 			batch.Queue(deleteQuery, heading.HeadingNumber, heading.MeursingTablePlanID, heading.RowColumnCode)
+			batch.Queue(deleteChildrenQuery, heading.HeadingNumber)
 
 		default:
 			return fmt.Errorf("unknown ChangeType: %s for HeadingNumber: %d", heading.ChangeType, heading.HeadingNumber)
 		}
 
 		if (i+1)%batchSize == 0 || i == len(headings)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -96,9 +105,23 @@ func (associations MeursingHeadingFootnoteAssociations) QueueBatch(ctx context.C
 		national = EXCLUDED.national;
 	`
 
+	// Children missing from an update have been removed from the heading
+	deleteQuery := `
+	DELETE FROM meursing_heading_footnote_association
+	WHERE parent_heading_id = $1
+		AND (footnote_id, footnote_type) NOT IN (
+			SELECT * FROM unnest($2::INT[], $3::TEXT[])
+		);
+	`
+
+	footnoteIDs := make([]int, 0, len(associations))
+	footnoteTypes := make([]string, 0, len(associations))
 	for _, assoc := range associations {
+		footnoteIDs = append(footnoteIDs, assoc.FootnoteID)
+		footnoteTypes = append(footnoteTypes, assoc.FootnoteType)
 		batch.Queue(insertQuery, parentHeadingID, assoc.FootnoteID, assoc.FootnoteType, assoc.DateStart, assoc.National)
 	}
+	batch.Queue(deleteQuery, parentHeadingID, footnoteIDs, footnoteTypes)
 
 	return nil
 }
@@ -122,9 +145,18 @@ func (texts MeursingHeadingTexts) QueueBatch(ctx context.Context, batch *pgx.Bat
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM meursing_heading_text
+	WHERE parent_heading_id = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(texts))
 	for _, text := range texts {
+		languageIDs = append(languageIDs, text.LanguageID)
 		batch.Queue(insertQuery, parentHeadingID, text.Description, text.LanguageID, text.National)
 	}
+	batch.Queue(deleteQuery, parentHeadingID, languageIDs)
 
 	return nil
 }
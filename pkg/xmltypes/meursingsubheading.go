@@ -20,7 +20,7 @@ type MeursingSubheading struct {
 	SubheadingSequenceNumber int          `xml:"subheadingSequenceNumber,attr"`
 }
 
-func (subheadings MeursingSubheadings) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (subheadings MeursingSubheadings) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO meursing_subheading (
 		heading_number, meursing_table_plan_id, row_column_code, subheading_sequence_number,
@@ -55,7 +55,7 @@ func (subheadings MeursingSubheadings) BatchInsert(ctx context.Context, conn *pg
 		}
 
 		if (i+1)%batchSize == 0 || i == len(subheadings)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -16,7 +16,7 @@ type MeursingTablePlan struct {
 	National            int          `xml:"national,attr"`
 }
 
-func (plans MeursingTablePlans) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (plans MeursingTablePlans) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO meursing_table_plan (
 		meursing_table_plan_id, date_start, national, change_type
@@ -48,7 +48,7 @@ func (plans MeursingTablePlans) BatchInsert(ctx context.Context, conn *pgx.Conn,
 		}
 
 		if (i+1)%batchSize == 0 || i == len(plans)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
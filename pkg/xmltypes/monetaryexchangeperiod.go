@@ -19,7 +19,7 @@ type MonetaryExchangePeriod struct {
 	MonetaryExchangeRate MonetaryExchangeRates `xml:"monetaryExchangeRate"`
 }
 
-func (periods MonetaryExchangePeriods) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (periods MonetaryExchangePeriods) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO monetary_exchange_period (
 		sid, monetary_unit_code, change_type, date_start, date_end, national, is_quoted
@@ -47,10 +47,8 @@ func (periods MonetaryExchangePeriods) BatchInsert(ctx context.Context, conn *pg
 			batch.Queue(insertQuery, period.SID, period.MonetaryUnitCode, period.ChangeType, period.DateStart, period.DateEnd, period.National, true)
 
 			// Queue child exchange rates
-			if len(period.MonetaryExchangeRate) > 0 {
-				if err := period.MonetaryExchangeRate.QueueBatch(ctx, batch, period.SID); err != nil {
-					return fmt.Errorf("failed to queue monetary exchange rates for SID %d: %w", period.SID, err)
-				}
+			if err := period.MonetaryExchangeRate.QueueBatch(ctx, batch, period.SID); err != nil {
+				return fmt.Errorf("failed to queue monetary exchange rates for SID %d: %w", period.SID, err)
 			}
 
 		case "D": // Delete
@@ -61,7 +59,7 @@ func (periods MonetaryExchangePeriods) BatchInsert(ctx context.Context, conn *pg
 		}
 
 		if (i+1)%batchSize == 0 || i == len(periods)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -83,7 +81,7 @@ type UnquotedMonetaryExchangePeriod struct {
 	MonetaryExchangeRates MonetaryExchangeRates `xml:"unquotedMonetaryExchangeRate"`
 }
 
-func (periods UnquotedMonetaryExchangePeriods) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (periods UnquotedMonetaryExchangePeriods) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO monetary_exchange_period (
 		sid, monetary_unit_code, change_type, date_start, date_end, national, is_quoted
@@ -99,7 +97,7 @@ func (periods UnquotedMonetaryExchangePeriods) BatchInsert(ctx context.Context,
 	`
 
 	deleteQuery := `
-	DELETE FROM unquoted_monetary_exchange_period
+	DELETE FROM monetary_exchange_period
 	WHERE sid = $1;
 	`
 
@@ -111,10 +109,8 @@ func (periods UnquotedMonetaryExchangePeriods) BatchInsert(ctx context.Context,
 			batch.Queue(insertQuery, period.SID, period.MonetaryUnitCode, period.ChangeType, period.DateStart, period.DateEnd, period.National, false)
 
 			// Queue child exchange rates
-			if len(period.MonetaryExchangeRates) > 0 {
-				if err := period.MonetaryExchangeRates.QueueBatch(ctx, batch, period.SID); err != nil {
-					return fmt.Errorf("failed to queue unquoted monetary exchange rates for SID %d: %w", period.SID, err)
-				}
+			if err := period.MonetaryExchangeRates.QueueBatch(ctx, batch, period.SID); err != nil {
+				return fmt.Errorf("failed to queue unquoted monetary exchange rates for SID %d: %w", period.SID, err)
 			}
 
 		case "D": // Delete
@@ -125,7 +121,7 @@ func (periods UnquotedMonetaryExchangePeriods) BatchInsert(ctx context.Context,
 		}
 
 		if (i+1)%batchSize == 0 || i == len(periods)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -157,9 +153,20 @@ func (rates MonetaryExchangeRates) QueueBatch(ctx context.Context, batch *pgx.Ba
 		national = EXCLUDED.national;
 	`
 
+	// Rates missing from an update have been removed from the period
+	deleteQuery := `
+	DELETE FROM monetary_exchange_rate
+	WHERE parent_sid = $1
+		AND monetary_unit_code <> ALL($2::TEXT[]);
+	`
+
+	unitCodes := make([]string, 0, len(rates))
 	for _, rate := range rates {
 		rate.ParentSID = parentSID
+		unitCodes = append(unitCodes, rate.MonetaryUnitCode)
 		batch.Queue(insertQuery, rate.ParentSID, rate.CalculationUnit, rate.MonetaryConversionRate, rate.MonetaryUnitCode, rate.National)
 	}
+	batch.Queue(deleteQuery, parentSID, unitCodes)
+
 	return nil
 }
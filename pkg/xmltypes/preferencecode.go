@@ -16,7 +16,7 @@ type PreferenceCode struct {
 	PreferenceCodeDescriptions PreferenceCodeDescriptions `xml:"preferenceCodeDescription"`
 }
 
-func (codes PreferenceCodes) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (codes PreferenceCodes) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO preference_code (
 		pref_code, date_start, change_type
@@ -40,10 +40,8 @@ func (codes PreferenceCodes) BatchInsert(ctx context.Context, conn *pgx.Conn, ba
 			batch.Queue(insertQuery, code.PrefCode, code.DateStart, code.ChangeType)
 
 			// Queue child descriptions
-			if len(code.PreferenceCodeDescriptions) > 0 {
-				if err := code.PreferenceCodeDescriptions.QueueBatch(ctx, batch, code.PrefCode); err != nil {
-					return fmt.Errorf("failed to queue descriptions for PrefCode %d: %w", code.PrefCode, err)
-				}
+			if err := code.PreferenceCodeDescriptions.QueueBatch(ctx, batch, code.PrefCode); err != nil {
+				return fmt.Errorf("failed to queue descriptions for PrefCode %d: %w", code.PrefCode, err)
 			}
 
 		case "D": // Delete
@@ -54,7 +52,7 @@ func (codes PreferenceCodes) BatchInsert(ctx context.Context, conn *pgx.Conn, ba
 		}
 
 		if (i+1)%batchSize == 0 || i == len(codes)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -81,9 +79,19 @@ func (descriptions PreferenceCodeDescriptions) QueueBatch(ctx context.Context, b
 	SET description = EXCLUDED.description;
 	`
 
+	// Descriptions missing from an update have been removed from the preference code
+	deleteQuery := `
+	DELETE FROM preference_code_description
+	WHERE parent_pref_code = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
 	for _, desc := range descriptions {
+		languageIDs = append(languageIDs, desc.LanguageID)
 		batch.Queue(insertQuery, parentPrefCode, desc.Description, desc.LanguageID)
 	}
+	batch.Queue(deleteQuery, parentPrefCode, languageIDs)
 
 	return nil
 }
@@ -31,7 +31,7 @@ type QuotaDefinition struct {
 	QuotaSuspensionPeriod        []QuotaSuspensionPeriod `xml:"quotaSuspensionPeriod"`
 }
 
-func (definitions QuotaDefinitions) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (definitions QuotaDefinitions) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO quota_definition (
 		sid, sid_quota_order_number, quota_critical_state_code, quota_critical_threshold, quota_maximum_precision,
@@ -57,46 +57,63 @@ func (definitions QuotaDefinitions) BatchInsert(ctx context.Context, conn *pgx.C
 		national = EXCLUDED.national;
 	`
 
-	batch := &pgx.Batch{}
+	deleteQuery := `
+	DELETE FROM quota_definition
+	WHERE sid = $1;
+	`
 
-	// Insert parent records first
-	for i, def := range definitions {
-		batch.Queue(insertQuery, def.SID, def.SIDQuotaOrderNumber, def.QuotaCriticalStateCode, def.QuotaCriticalThreshold,
-			def.QuotaMaximumPrecision, def.QuotaOrderNumber, def.ChangeType, def.Description, def.InitialVolume,
-			def.MeasurementUnitCode, def.MeasurementUnitQualifierCode, def.MonetaryUnitCode, def.Volume, def.DateStart,
-			def.DateEnd, def.National)
+	// Quota events are not children of the definition in the files, so they are removed along with it
+	deleteEventsQuery := `
+	WITH balance AS (
+		DELETE FROM quota_balance_event WHERE sid_quota_definition = $1
+	), critical AS (
+		DELETE FROM quota_critical_event WHERE sid_quota_definition = $1
+	), exhaustion AS (
+		DELETE FROM quota_exhaustion_event WHERE sid_quota_definition = $1
+	), reopening AS (
+		DELETE FROM quota_reopening_event WHERE sid_quota_definition = $1
+	), unblocking AS (
+		DELETE FROM quota_unblocking_event WHERE sid_quota_definition = $1
+	)
+	DELETE FROM quota_unsuspension_event WHERE sid_quota_definition = $1;
+	`
 
-		// Commit the batch every batchSize or at the end
-		if (i+1)%batchSize == 0 || i == len(definitions)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
-				return fmt.Errorf("failed to insert quota definitions: %w", err)
-			}
-			batch = &pgx.Batch{} // Reset the batch
-		}
-	}
+	batch := &pgx.Batch{}
 
-	// Insert child records after parent records are fully committed
-	for _, def := range definitions {
-		if len(def.QuotaBlockingPeriod) > 0 {
+	for i, def := range definitions {
+		switch def.ChangeType {
+		case "U": // Insert or update
+			batch.Queue(insertQuery, def.SID, def.SIDQuotaOrderNumber, def.QuotaCriticalStateCode, def.QuotaCriticalThreshold,
+				def.QuotaMaximumPrecision, def.QuotaOrderNumber, def.ChangeType, def.Description, def.InitialVolume,
+				def.MeasurementUnitCode, def.MeasurementUnitQualifierCode, def.MonetaryUnitCode, def.Volume, def.DateStart,
+				def.DateEnd, def.National)
+
+			// Queue child records
 			if err := QuotaBlockingPeriods(def.QuotaBlockingPeriod).QueueBatch(ctx, batch, def.SID); err != nil {
 				return fmt.Errorf("failed to queue blocking periods for SID %d: %w", def.SID, err)
 			}
-		}
-		if len(def.QuotaAssociation) > 0 {
 			if err := QuotaAssociations(def.QuotaAssociation).QueueBatch(ctx, batch, def.SID); err != nil {
 				return fmt.Errorf("failed to queue associations for SID %d: %w", def.SID, err)
 			}
-		}
-		if len(def.QuotaSuspensionPeriod) > 0 {
 			if err := QuotaSuspensionPeriods(def.QuotaSuspensionPeriod).QueueBatch(ctx, batch, def.SID); err != nil {
 				return fmt.Errorf("failed to queue suspension periods for SID %d: %w", def.SID, err)
 			}
+
+		case "D": // Delete
+			batch.Queue(deleteEventsQuery, def.SID)
+			batch.Queue(deleteQuery, def.SID)
+
+		default:
+			return fmt.Errorf("unknown ChangeType: %s for SID: %d", def.ChangeType, def.SID)
 		}
 
-		if err := conn.SendBatch(ctx, batch).Close(); err != nil {
-			return fmt.Errorf("failed to insert child records: %w", err)
+		// Commit the batch every batchSize or at the end
+		if (i+1)%batchSize == 0 || i == len(definitions)-1 {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
+				return fmt.Errorf("failed to insert quota definitions: %w", err)
+			}
+			batch = &pgx.Batch{} // Reset the batch
 		}
-		batch = &pgx.Batch{} // Reset the batch
 	}
 
 	return nil
@@ -126,9 +143,19 @@ func (periods QuotaBlockingPeriods) QueueBatch(ctx context.Context, batch *pgx.B
 		national = EXCLUDED.national;
 	`
 
+	// Children missing from an update have been removed from the definition
+	deleteQuery := `
+	DELETE FROM quota_blocking_period
+	WHERE parent_sid = $1
+		AND sid <> ALL($2::INT[]);
+	`
+
+	sids := make([]int, 0, len(periods))
 	for _, period := range periods {
+		sids = append(sids, period.SID)
 		batch.Queue(insertQuery, period.SID, parentSID, period.BlockingPeriodType, period.Description, period.DateStart, period.DateEnd, period.National)
 	}
+	batch.Queue(deleteQuery, parentSID, sids)
 
 	return nil
 }
@@ -153,9 +180,18 @@ func (associations QuotaAssociations) QueueBatch(ctx context.Context, batch *pgx
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM quota_association
+	WHERE parent_sid = $1
+		AND sid_sub_quota <> ALL($2::INT[]);
+	`
+
+	subQuotaSIDs := make([]int, 0, len(associations))
 	for _, assoc := range associations {
+		subQuotaSIDs = append(subQuotaSIDs, assoc.SIDSubQuota)
 		batch.Queue(insertQuery, assoc.SIDSubQuota, parentSID, assoc.RelationType, assoc.Coefficient, assoc.National)
 	}
+	batch.Queue(deleteQuery, parentSID, subQuotaSIDs)
 
 	return nil
 }
@@ -182,93 +218,18 @@ func (periods QuotaSuspensionPeriods) QueueBatch(ctx context.Context, batch *pgx
 		national = EXCLUDED.national;
 	`
 
+	deleteQuery := `
+	DELETE FROM quota_suspension_period
+	WHERE parent_sid = $1
+		AND sid <> ALL($2::INT[]);
+	`
+
+	sids := make([]int, 0, len(periods))
 	for _, period := range periods {
+		sids = append(sids, period.SID)
 		batch.Queue(insertQuery, period.SID, parentSID, period.Description, period.DateStart, period.DateEnd, period.National)
 	}
+	batch.Queue(deleteQuery, parentSID, sids)
 
 	return nil
 }
-
-// type QuotaBalanceEvent struct {
-// 	ChangeType             string            `xml:"changeType,attr"`
-// 	EndOccurrenceTimestamp FileDistTimeStamp `xml:"endOccurrenceTimestamp,attr"`
-// 	ImportedAmount         float64           `xml:"importedAmount,attr"`
-// 	LastImportDate         FileDistTime      `xml:"lastImportDate,attr"`
-// 	National               int               `xml:"national,attr"`
-// 	NewBalance             float64           `xml:"newBalance,attr"`
-// 	OccurrenceTimestamp    FileDistTimeStamp `xml:"occurrenceTimestamp,attr"`
-// 	OldBalance             float64           `xml:"oldBalance,attr"`
-// 	SIDQuotaDefinition     int               `xml:"SIDQuotaDefinition,attr"`
-// }
-
-// type QuotaCriticalEvent struct {
-// 	ChangeType             string            `xml:"changeType,attr"`
-// 	CriticalDate           FileDistTime      `xml:"criticalDate,attr"`
-// 	EndOccurrenceTimestamp FileDistTimeStamp `xml:"endOccurrenceTimestamp,attr"`
-// 	National               int               `xml:"national,attr"`
-// 	OccurrenceTimestamp    FileDistTimeStamp `xml:"occurrenceTimestamp,attr"`
-// 	QuotaCriticalStateCode string            `xml:"quotaCriticalStateCode,attr"`
-// 	SIDQuotaDefinition     int               `xml:"SIDQuotaDefinition,attr"`
-// }
-
-// type QuotaExhaustionEvent struct {
-// 	ChangeType             string            `xml:"changeType,attr"`
-// 	EndOccurrenceTimestamp FileDistTimeStamp `xml:"endOccurrenceTimestamp,attr"`
-// 	ExhaustionDate         FileDistTime      `xml:"exhaustionDate,attr"`
-// 	National               int               `xml:"national,attr"`
-// 	OccurrenceTimestamp    FileDistTimeStamp `xml:"occurrenceTimestamp,attr"`
-// 	SIDQuotaDefinition     int               `xml:"SIDQuotaDefinition,attr"`
-// }
-
-// type QuotaReopeningEvent struct {
-// 	ChangeType             string            `xml:"changeType,attr"`
-// 	EndOccurrenceTimestamp FileDistTimeStamp `xml:"endOccurrenceTimestamp,attr"`
-// 	National               int               `xml:"national,attr"`
-// 	OccurrenceTimestamp    FileDistTimeStamp `xml:"occurrenceTimestamp,attr"`
-// 	ReopeningDate          FileDistTime      `xml:"reopeningDate,attr"`
-// 	SIDQuotaDefinition     int               `xml:"SIDQuotaDefinition,attr"`
-// }
-
-// type QuotaOrderNumber struct {
-// 	ChangeType             string                   `xml:"changeType,attr"`
-// 	DateEnd                FileDistTime             `xml:"dateEnd,attr"`
-// 	DateStart              FileDistTime             `xml:"dateStart,attr"`
-// 	National               int                      `xml:"national,attr"`
-// 	QuotaOrderNumber       int                      `xml:"quotaOrderNumber,attr"`
-// 	SID                    int                      `xml:"SID,attr"`
-// 	QuotaOrderNumberOrigin []QuotaOrderNumberOrigin `xml:"quotaOrderNumberOrigin"`
-// }
-
-// type QuotaOrderNumberOrigin struct {
-// 	DateEnd                         FileDistTime                      `xml:"dateEnd,attr"`
-// 	DateStart                       FileDistTime                      `xml:"dateStart,attr"`
-// 	GeographicalAreaID              string                            `xml:"geographicalAreaId,attr"`
-// 	National                        int                               `xml:"national,attr"`
-// 	SID                             int                               `xml:"SID,attr"`
-// 	SIDGeographicalArea             int                               `xml:"SIDGeographicalArea,attr"`
-// 	QuotaOrderNumberOriginExclusion []QuotaOrderNumberOriginExclusion `xml:"quotaOrderNumberOriginExclusion"`
-// }
-
-// type QuotaUnblockingEvent struct {
-// 	ChangeType             string            `xml:"changeType,attr"`
-// 	EndOccurrenceTimestamp FileDistTimeStamp `xml:"endOccurrenceTimestamp,attr"`
-// 	National               int               `xml:"national,attr"`
-// 	OccurrenceTimestamp    FileDistTimeStamp `xml:"occurrenceTimestamp,attr"`
-// 	SIDQuotaDefinition     int               `xml:"SIDQuotaDefinition,attr"`
-// 	UnblockingDate         FileDistTime      `xml:"unblockingDate,attr"`
-// }
-
-// type QuotaUnsuspensionEvent struct {
-// 	ChangeType             string            `xml:"changeType,attr"`
-// 	EndOccurrenceTimestamp FileDistTimeStamp `xml:"endOccurrenceTimestamp,attr"`
-// 	National               int               `xml:"national,attr"`
-// 	OccurrenceTimestamp    FileDistTimeStamp `xml:"occurrenceTimestamp,attr"`
-// 	SIDQuotaDefinition     int               `xml:"SIDQuotaDefinition,attr"`
-// 	UnsuspensionDate       FileDistTime      `xml:"unsuspensionDate,attr"`
-// }
-
-// type QuotaOrderNumberOriginExclusion struct {
-// 	GeographicalAreaID  string `xml:"geographicalAreaId,attr"`
-// 	National            int    `xml:"national,attr"`
-// 	SIDGeographicalArea int    `xml:"SIDGeographicalArea,attr"`
-// }
@@ -0,0 +1,331 @@
+package xmltypes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Quota events are published by the quota administration and record how the balance and state of a
+// quota definition change over time. An event is identified by its quota definition and occurrence timestamp.
+
+type QuotaBalanceEvents []QuotaBalanceEvent
+
+type QuotaBalanceEvent struct {
+	ChangeType             string            `xml:"changeType,attr"`
+	EndOccurrenceTimestamp FileDistTimeStamp `xml:"endOccurrenceTimestamp,attr"`
+	ImportedAmount         float64           `xml:"importedAmount,attr"`
+	LastImportDate         FileDistTime      `xml:"lastImportDate,attr"`
+	National               int               `xml:"national,attr"`
+	NewBalance             float64           `xml:"newBalance,attr"`
+	OccurrenceTimestamp    FileDistTimeStamp `xml:"occurrenceTimestamp,attr"`
+	OldBalance             float64           `xml:"oldBalance,attr"`
+	SIDQuotaDefinition     int               `xml:"SIDQuotaDefinition,attr"`
+}
+
+func (events QuotaBalanceEvents) BatchInsert(ctx context.Context, db DB, batchSize int) error {
+	insertQuery := `
+	INSERT INTO quota_balance_event (sid_quota_definition, occurrence_timestamp, end_occurrence_timestamp, imported_amount, last_import_date, new_balance, old_balance, change_type, national)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (sid_quota_definition, occurrence_timestamp) DO UPDATE
+	SET end_occurrence_timestamp = EXCLUDED.end_occurrence_timestamp,
+		imported_amount = EXCLUDED.imported_amount,
+		last_import_date = EXCLUDED.last_import_date,
+		new_balance = EXCLUDED.new_balance,
+		old_balance = EXCLUDED.old_balance,
+		change_type = EXCLUDED.change_type,
+		national = EXCLUDED.national;
+	`
+
+	deleteQuery := `
+	DELETE FROM quota_balance_event
+	WHERE sid_quota_definition = $1 AND occurrence_timestamp = $2;
+	`
+
+	batch := &pgx.Batch{}
+
+	for i, event := range events {
+		switch event.ChangeType {
+		case "U": // Insert or update
+			batch.Queue(insertQuery, event.SIDQuotaDefinition, event.OccurrenceTimestamp.Value(), event.EndOccurrenceTimestamp.Value(), event.ImportedAmount, event.LastImportDate, event.NewBalance, event.OldBalance, event.ChangeType, event.National)
+
+		case "D": // Delete
+			batch.Queue(deleteQuery, event.SIDQuotaDefinition, event.OccurrenceTimestamp.Value())
+
+		default:
+			return fmt.Errorf("unknown ChangeType: %s for QuotaBalanceEvent SIDQuotaDefinition: %d", event.ChangeType, event.SIDQuotaDefinition)
+		}
+
+		if (i+1)%batchSize == 0 || i == len(events)-1 {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
+				return fmt.Errorf("failed to insert quota balance events: %w", err)
+			}
+			batch = &pgx.Batch{}
+		}
+	}
+
+	return nil
+}
+
+type QuotaCriticalEvents []QuotaCriticalEvent
+
+type QuotaCriticalEvent struct {
+	ChangeType             string            `xml:"changeType,attr"`
+	CriticalDate           FileDistTime      `xml:"criticalDate,attr"`
+	EndOccurrenceTimestamp FileDistTimeStamp `xml:"endOccurrenceTimestamp,attr"`
+	National               int               `xml:"national,attr"`
+	OccurrenceTimestamp    FileDistTimeStamp `xml:"occurrenceTimestamp,attr"`
+	QuotaCriticalStateCode string            `xml:"quotaCriticalStateCode,attr"`
+	SIDQuotaDefinition     int               `xml:"SIDQuotaDefinition,attr"`
+}
+
+func (events QuotaCriticalEvents) BatchInsert(ctx context.Context, db DB, batchSize int) error {
+	insertQuery := `
+	INSERT INTO quota_critical_event (sid_quota_definition, occurrence_timestamp, end_occurrence_timestamp, critical_date, quota_critical_state_code, change_type, national)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (sid_quota_definition, occurrence_timestamp) DO UPDATE
+	SET end_occurrence_timestamp = EXCLUDED.end_occurrence_timestamp,
+		critical_date = EXCLUDED.critical_date,
+		quota_critical_state_code = EXCLUDED.quota_critical_state_code,
+		change_type = EXCLUDED.change_type,
+		national = EXCLUDED.national;
+	`
+
+	deleteQuery := `
+	DELETE FROM quota_critical_event
+	WHERE sid_quota_definition = $1 AND occurrence_timestamp = $2;
+	`
+
+	batch := &pgx.Batch{}
+
+	for i, event := range events {
+		switch event.ChangeType {
+		case "U": // Insert or update
+			batch.Queue(insertQuery, event.SIDQuotaDefinition, event.OccurrenceTimestamp.Value(), event.EndOccurrenceTimestamp.Value(), event.CriticalDate, event.QuotaCriticalStateCode, event.ChangeType, event.National)
+
+		case "D": // Delete
+			batch.Queue(deleteQuery, event.SIDQuotaDefinition, event.OccurrenceTimestamp.Value())
+
+		default:
+			return fmt.Errorf("unknown ChangeType: %s for QuotaCriticalEvent SIDQuotaDefinition: %d", event.ChangeType, event.SIDQuotaDefinition)
+		}
+
+		if (i+1)%batchSize == 0 || i == len(events)-1 {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
+				return fmt.Errorf("failed to insert quota critical events: %w", err)
+			}
+			batch = &pgx.Batch{}
+		}
+	}
+
+	return nil
+}
+
+type QuotaExhaustionEvents []QuotaExhaustionEvent
+
+type QuotaExhaustionEvent struct {
+	ChangeType             string            `xml:"changeType,attr"`
+	EndOccurrenceTimestamp FileDistTimeStamp `xml:"endOccurrenceTimestamp,attr"`
+	ExhaustionDate         FileDistTime      `xml:"exhaustionDate,attr"`
+	National               int               `xml:"national,attr"`
+	OccurrenceTimestamp    FileDistTimeStamp `xml:"occurrenceTimestamp,attr"`
+	SIDQuotaDefinition     int               `xml:"SIDQuotaDefinition,attr"`
+}
+
+func (events QuotaExhaustionEvents) BatchInsert(ctx context.Context, db DB, batchSize int) error {
+	insertQuery := `
+	INSERT INTO quota_exhaustion_event (sid_quota_definition, occurrence_timestamp, end_occurrence_timestamp, exhaustion_date, change_type, national)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (sid_quota_definition, occurrence_timestamp) DO UPDATE
+	SET end_occurrence_timestamp = EXCLUDED.end_occurrence_timestamp,
+		exhaustion_date = EXCLUDED.exhaustion_date,
+		change_type = EXCLUDED.change_type,
+		national = EXCLUDED.national;
+	`
+
+	deleteQuery := `
+	DELETE FROM quota_exhaustion_event
+	WHERE sid_quota_definition = $1 AND occurrence_timestamp = $2;
+	`
+
+	batch := &pgx.Batch{}
+
+	for i, event := range events {
+		switch event.ChangeType {
+		case "U": // Insert or update
+			batch.Queue(insertQuery, event.SIDQuotaDefinition, event.OccurrenceTimestamp.Value(), event.EndOccurrenceTimestamp.Value(), event.ExhaustionDate, event.ChangeType, event.National)
+
+		case "D": // Delete
+			batch.Queue(deleteQuery, event.SIDQuotaDefinition, event.OccurrenceTimestamp.Value())
+
+		default:
+			return fmt.Errorf("unknown ChangeType: %s for QuotaExhaustionEvent SIDQuotaDefinition: %d", event.ChangeType, event.SIDQuotaDefinition)
+		}
+
+		if (i+1)%batchSize == 0 || i == len(events)-1 {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
+				return fmt.Errorf("failed to insert quota exhaustion events: %w", err)
+			}
+			batch = &pgx.Batch{}
+		}
+	}
+
+	return nil
+}
+
+type QuotaReopeningEvents []QuotaReopeningEvent
+
+type QuotaReopeningEvent struct {
+	ChangeType             string            `xml:"changeType,attr"`
+	EndOccurrenceTimestamp FileDistTimeStamp `xml:"endOccurrenceTimestamp,attr"`
+	National               int               `xml:"national,attr"`
+	OccurrenceTimestamp    FileDistTimeStamp `xml:"occurrenceTimestamp,attr"`
+	ReopeningDate          FileDistTime      `xml:"reopeningDate,attr"`
+	SIDQuotaDefinition     int               `xml:"SIDQuotaDefinition,attr"`
+}
+
+func (events QuotaReopeningEvents) BatchInsert(ctx context.Context, db DB, batchSize int) error {
+	insertQuery := `
+	INSERT INTO quota_reopening_event (sid_quota_definition, occurrence_timestamp, end_occurrence_timestamp, reopening_date, change_type, national)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (sid_quota_definition, occurrence_timestamp) DO UPDATE
+	SET end_occurrence_timestamp = EXCLUDED.end_occurrence_timestamp,
+		reopening_date = EXCLUDED.reopening_date,
+		change_type = EXCLUDED.change_type,
+		national = EXCLUDED.national;
+	`
+
+	deleteQuery := `
+	DELETE FROM quota_reopening_event
+	WHERE sid_quota_definition = $1 AND occurrence_timestamp = $2;
+	`
+
+	batch := &pgx.Batch{}
+
+	for i, event := range events {
+		switch event.ChangeType {
+		case "U": // Insert or update
+			batch.Queue(insertQuery, event.SIDQuotaDefinition, event.OccurrenceTimestamp.Value(), event.EndOccurrenceTimestamp.Value(), event.ReopeningDate, event.ChangeType, event.National)
+
+		case "D": // Delete
+			batch.Queue(deleteQuery, event.SIDQuotaDefinition, event.OccurrenceTimestamp.Value())
+
+		default:
+			return fmt.Errorf("unknown ChangeType: %s for QuotaReopeningEvent SIDQuotaDefinition: %d", event.ChangeType, event.SIDQuotaDefinition)
+		}
+
+		if (i+1)%batchSize == 0 || i == len(events)-1 {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
+				return fmt.Errorf("failed to insert quota reopening events: %w", err)
+			}
+			batch = &pgx.Batch{}
+		}
+	}
+
+	return nil
+}
+
+type QuotaUnblockingEvents []QuotaUnblockingEvent
+
+type QuotaUnblockingEvent struct {
+	ChangeType             string            `xml:"changeType,attr"`
+	EndOccurrenceTimestamp FileDistTimeStamp `xml:"endOccurrenceTimestamp,attr"`
+	National               int               `xml:"national,attr"`
+	OccurrenceTimestamp    FileDistTimeStamp `xml:"occurrenceTimestamp,attr"`
+	SIDQuotaDefinition     int               `xml:"SIDQuotaDefinition,attr"`
+	UnblockingDate         FileDistTime      `xml:"unblockingDate,attr"`
+}
+
+func (events QuotaUnblockingEvents) BatchInsert(ctx context.Context, db DB, batchSize int) error {
+	insertQuery := `
+	INSERT INTO quota_unblocking_event (sid_quota_definition, occurrence_timestamp, end_occurrence_timestamp, unblocking_date, change_type, national)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (sid_quota_definition, occurrence_timestamp) DO UPDATE
+	SET end_occurrence_timestamp = EXCLUDED.end_occurrence_timestamp,
+		unblocking_date = EXCLUDED.unblocking_date,
+		change_type = EXCLUDED.change_type,
+		national = EXCLUDED.national;
+	`
+
+	deleteQuery := `
+	DELETE FROM quota_unblocking_event
+	WHERE sid_quota_definition = $1 AND occurrence_timestamp = $2;
+	`
+
+	batch := &pgx.Batch{}
+
+	for i, event := range events {
+		switch event.ChangeType {
+		case "U": // Insert or update
+			batch.Queue(insertQuery, event.SIDQuotaDefinition, event.OccurrenceTimestamp.Value(), event.EndOccurrenceTimestamp.Value(), event.UnblockingDate, event.ChangeType, event.National)
+
+		case "D": // Delete
+			batch.Queue(deleteQuery, event.SIDQuotaDefinition, event.OccurrenceTimestamp.Value())
+
+		default:
+			return fmt.Errorf("unknown ChangeType: %s for QuotaUnblockingEvent SIDQuotaDefinition: %d", event.ChangeType, event.SIDQuotaDefinition)
+		}
+
+		if (i+1)%batchSize == 0 || i == len(events)-1 {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
+				return fmt.Errorf("failed to insert quota unblocking events: %w", err)
+			}
+			batch = &pgx.Batch{}
+		}
+	}
+
+	return nil
+}
+
+type QuotaUnsuspensionEvents []QuotaUnsuspensionEvent
+
+type QuotaUnsuspensionEvent struct {
+	ChangeType             string            `xml:"changeType,attr"`
+	EndOccurrenceTimestamp FileDistTimeStamp `xml:"endOccurrenceTimestamp,attr"`
+	National               int               `xml:"national,attr"`
+	OccurrenceTimestamp    FileDistTimeStamp `xml:"occurrenceTimestamp,attr"`
+	SIDQuotaDefinition     int               `xml:"SIDQuotaDefinition,attr"`
+	UnsuspensionDate       FileDistTime      `xml:"unsuspensionDate,attr"`
+}
+
+func (events QuotaUnsuspensionEvents) BatchInsert(ctx context.Context, db DB, batchSize int) error {
+	insertQuery := `
+	INSERT INTO quota_unsuspension_event (sid_quota_definition, occurrence_timestamp, end_occurrence_timestamp, unsuspension_date, change_type, national)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (sid_quota_definition, occurrence_timestamp) DO UPDATE
+	SET end_occurrence_timestamp = EXCLUDED.end_occurrence_timestamp,
+		unsuspension_date = EXCLUDED.unsuspension_date,
+		change_type = EXCLUDED.change_type,
+		national = EXCLUDED.national;
+	`
+
+	deleteQuery := `
+	DELETE FROM quota_unsuspension_event
+	WHERE sid_quota_definition = $1 AND occurrence_timestamp = $2;
+	`
+
+	batch := &pgx.Batch{}
+
+	for i, event := range events {
+		switch event.ChangeType {
+		case "U": // Insert or update
+			batch.Queue(insertQuery, event.SIDQuotaDefinition, event.OccurrenceTimestamp.Value(), event.EndOccurrenceTimestamp.Value(), event.UnsuspensionDate, event.ChangeType, event.National)
+
+		case "D": // Delete
+			batch.Queue(deleteQuery, event.SIDQuotaDefinition, event.OccurrenceTimestamp.Value())
+
+		default:
+			return fmt.Errorf("unknown ChangeType: %s for QuotaUnsuspensionEvent SIDQuotaDefinition: %d", event.ChangeType, event.SIDQuotaDefinition)
+		}
+
+		if (i+1)%batchSize == 0 || i == len(events)-1 {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
+				return fmt.Errorf("failed to insert quota unsuspension events: %w", err)
+			}
+			batch = &pgx.Batch{}
+		}
+	}
+
+	return nil
+}
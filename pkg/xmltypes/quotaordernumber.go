@@ -0,0 +1,150 @@
+package xmltypes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type QuotaOrderNumbers []QuotaOrderNumber
+
+type QuotaOrderNumber struct {
+	SID                     int                     `xml:"SID,attr"`
+	ChangeType              string                  `xml:"changeType,attr"`
+	DateEnd                 FileDistTime            `xml:"dateEnd,attr"`
+	DateStart               FileDistTime            `xml:"dateStart,attr"`
+	National                int                     `xml:"national,attr"`
+	QuotaOrderNumber        string                  `xml:"quotaOrderNumber,attr"` // six digits with leading zero, e.g. 090703
+	QuotaOrderNumberOrigins QuotaOrderNumberOrigins `xml:"quotaOrderNumberOrigin"`
+}
+
+func (orderNumbers QuotaOrderNumbers) BatchInsert(ctx context.Context, db DB, batchSize int) error {
+	insertQuery := `
+	INSERT INTO quota_order_number (sid, quota_order_number, change_type, date_start, date_end, national)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (sid) DO UPDATE
+	SET quota_order_number = EXCLUDED.quota_order_number,
+		change_type = EXCLUDED.change_type,
+		date_start = EXCLUDED.date_start,
+		date_end = EXCLUDED.date_end,
+		national = EXCLUDED.national;
+	`
+
+	deleteQuery := `
+	DELETE FROM quota_order_number
+	WHERE sid = $1;
+	`
+
+	batch := &pgx.Batch{}
+
+	for i, orderNumber := range orderNumbers {
+		switch orderNumber.ChangeType {
+		case "U": // Insert or update
+			batch.Queue(insertQuery, orderNumber.SID, orderNumber.QuotaOrderNumber, orderNumber.ChangeType, orderNumber.DateStart, orderNumber.DateEnd, orderNumber.National)
+
+			// Queue child origins
+			if err := orderNumber.QuotaOrderNumberOrigins.QueueBatch(ctx, batch, orderNumber.SID); err != nil {
+				return fmt.Errorf("failed to queue origins for QuotaOrderNumber SID %d: %w", orderNumber.SID, err)
+			}
+
+		case "D": // Delete
+			batch.Queue(deleteQuery, orderNumber.SID)
+
+		default:
+			return fmt.Errorf("unknown ChangeType: %s for QuotaOrderNumber SID: %d", orderNumber.ChangeType, orderNumber.SID)
+		}
+
+		if (i+1)%batchSize == 0 || i == len(orderNumbers)-1 {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
+				return fmt.Errorf("failed to execute batch: %w", err)
+			}
+			batch = &pgx.Batch{}
+		}
+	}
+
+	return nil
+}
+
+type QuotaOrderNumberOrigins []QuotaOrderNumberOrigin
+
+type QuotaOrderNumberOrigin struct {
+	SID                              int                              `xml:"SID,attr"`
+	ParentSID                        int                              // added type
+	DateEnd                          FileDistTime                     `xml:"dateEnd,attr"`
+	DateStart                        FileDistTime                     `xml:"dateStart,attr"`
+	GeographicalAreaID               string                           `xml:"geographicalAreaId,attr"`
+	National                         int                              `xml:"national,attr"`
+	SIDGeographicalArea              int                              `xml:"SIDGeographicalArea,attr"`
+	QuotaOrderNumberOriginExclusions QuotaOrderNumberOriginExclusions `xml:"quotaOrderNumberOriginExclusion"`
+}
+
+func (origins QuotaOrderNumberOrigins) QueueBatch(ctx context.Context, batch *pgx.Batch, parentSID int) error {
+	insertQuery := `
+	INSERT INTO quota_order_number_origin (sid, parent_sid, geographical_area_id, sid_geographical_area, date_start, date_end, national)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (sid) DO UPDATE
+	SET geographical_area_id = EXCLUDED.geographical_area_id,
+		sid_geographical_area = EXCLUDED.sid_geographical_area,
+		date_start = EXCLUDED.date_start,
+		date_end = EXCLUDED.date_end,
+		national = EXCLUDED.national;
+	`
+
+	// Children missing from an update have been removed from the order number
+	deleteQuery := `
+	DELETE FROM quota_order_number_origin
+	WHERE parent_sid = $1
+		AND sid <> ALL($2::INT[]);
+	`
+
+	sids := make([]int, 0, len(origins))
+	for _, origin := range origins {
+		origin.ParentSID = parentSID
+		sids = append(sids, origin.SID)
+		batch.Queue(insertQuery, origin.SID, origin.ParentSID, origin.GeographicalAreaID, origin.SIDGeographicalArea, origin.DateStart, origin.DateEnd, origin.National)
+
+		// Queue child exclusions
+		if err := origin.QuotaOrderNumberOriginExclusions.QueueBatch(ctx, batch, origin.SID); err != nil {
+			return fmt.Errorf("failed to queue exclusions for origin SID %d: %w", origin.SID, err)
+		}
+	}
+	batch.Queue(deleteQuery, parentSID, sids)
+
+	return nil
+}
+
+type QuotaOrderNumberOriginExclusions []QuotaOrderNumberOriginExclusion
+
+type QuotaOrderNumberOriginExclusion struct {
+	ParentSID           int    // added type
+	GeographicalAreaID  string `xml:"geographicalAreaId,attr"`
+	National            int    `xml:"national,attr"`
+	SIDGeographicalArea int    `xml:"SIDGeographicalArea,attr"`
+}
+
+func (exclusions QuotaOrderNumberOriginExclusions) QueueBatch(ctx context.Context, batch *pgx.Batch, parentSID int) error {
+	insertQuery := `
+	INSERT INTO quota_order_number_origin_exclusion (parent_sid, sid_geographical_area, geographical_area_id, national)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (parent_sid, sid_geographical_area) DO UPDATE
+	SET geographical_area_id = EXCLUDED.geographical_area_id,
+		national = EXCLUDED.national;
+	`
+
+	deleteQuery := `
+	DELETE FROM quota_order_number_origin_exclusion
+	WHERE parent_sid = $1
+		AND sid_geographical_area <> ALL($2::INT[]);
+	`
+
+	areaSIDs := make([]int, 0, len(exclusions))
+	for _, exclusion := range exclusions {
+		exclusion.ParentSID = parentSID
+		areaSIDs = append(areaSIDs, exclusion.SIDGeographicalArea)
+		batch.Queue(insertQuery, exclusion.ParentSID, exclusion.SIDGeographicalArea, exclusion.GeographicalAreaID, exclusion.National)
+	}
+	batch.Queue(deleteQuery, parentSID, areaSIDs)
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package xmltypes
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Records are the change envelopes of a dif file. Every changed item in a dif file is wrapped in a
+// <record> element whose attributes carry the transaction and sequence metadata of the change.
+// The set of attributes is not documented, so they are kept as they are for auditing.
+type Records []Record
+
+type Record struct {
+	FileName   string            // dif file the record was read from
+	Position   int               // position of the changed item among the records of the file, starting at 1
+	Element    string            // element name of the changed item, e.g. measure
+	ChangeType string            // changeType of the changed item
+	Attributes map[string]string // attributes of the <record> element
+}
+
+// NewRecord returns the record described by a <record> start element.
+// The changed item and position are set once the content of the record is read.
+func NewRecord(fileName string, start xml.StartElement) Record {
+	attributes := make(map[string]string, len(start.Attr))
+	for _, attr := range start.Attr {
+		attributes[attr.Name.Local] = attr.Value
+	}
+
+	return Record{FileName: fileName, Attributes: attributes}
+}
+
+func (records Records) BatchInsert(ctx context.Context, db DB, batchSize int) error {
+	insertQuery := `
+	INSERT INTO dif_record (file_name, position, element, change_type, attributes)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (file_name, position) DO UPDATE
+	SET element = EXCLUDED.element,
+		change_type = EXCLUDED.change_type,
+		attributes = EXCLUDED.attributes;
+	`
+
+	batch := &pgx.Batch{}
+
+	for i, record := range records {
+		batch.Queue(insertQuery, record.FileName, record.Position, record.Element, record.ChangeType, record.Attributes)
+
+		if (i+1)%batchSize == 0 || i == len(records)-1 {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
+				return fmt.Errorf("failed to insert dif records: %w", err)
+			}
+			batch = &pgx.Batch{}
+		}
+	}
+
+	return nil
+}
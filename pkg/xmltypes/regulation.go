@@ -31,7 +31,7 @@ type BaseRegulation struct {
 	Url                           *string      `xml:"url,attr"`
 }
 
-func (regulations BaseRegulations) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (regulations BaseRegulations) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO base_regulation (
 		regulation_id, regulation_role_type, antidumping_regulation_id, antidumping_regulation_role_type, change_type,
@@ -82,7 +82,7 @@ func (regulations BaseRegulations) BatchInsert(ctx context.Context, conn *pgx.Co
 		}
 
 		if (i+1)%batchSize == 0 || i == len(regulations)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -113,7 +113,7 @@ type ModificationRegulation struct {
 	StoppedFlag                    int          `xml:"stoppedFlag,attr"`
 }
 
-func (regs ModificationRegulations) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (regs ModificationRegulations) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO modification_regulation (
 		modification_regulation_id, modification_regulation_role_type, base_regulation_id, base_regulation_role_type,
@@ -139,16 +139,30 @@ func (regs ModificationRegulations) BatchInsert(ctx context.Context, conn *pgx.C
 		stopped_flag = EXCLUDED.stopped_flag;
 	`
 
+	deleteQuery := `
+	DELETE FROM modification_regulation
+	WHERE modification_regulation_id = $1;
+	`
+
 	batch := &pgx.Batch{}
 
 	fmt.Print("Inserting modification regulations\n")
 	for i, reg := range regs {
-		batch.Queue(insertQuery, reg.ModificationRegulationID, reg.ModificationRegulationRoleType, reg.BaseRegulationID, reg.BaseRegulationRoleType,
-			reg.ChangeType, reg.DateEnd, reg.DatePublished, reg.DateStart, reg.Description, reg.EffectiveEndDate, reg.JournalPage,
-			reg.National, reg.OfficialJournalID, reg.RegulationApprovedFlag, reg.ReplacementIndicator, reg.StoppedFlag)
+		switch reg.ChangeType {
+		case "U": // Insert or update
+			batch.Queue(insertQuery, reg.ModificationRegulationID, reg.ModificationRegulationRoleType, reg.BaseRegulationID, reg.BaseRegulationRoleType,
+				reg.ChangeType, reg.DateEnd, reg.DatePublished, reg.DateStart, reg.Description, reg.EffectiveEndDate, reg.JournalPage,
+				reg.National, reg.OfficialJournalID, reg.RegulationApprovedFlag, reg.ReplacementIndicator, reg.StoppedFlag)
+
+		case "D": // Delete
+			batch.Queue(deleteQuery, reg.ModificationRegulationID)
+
+		default:
+			return fmt.Errorf("unknown ChangeType: %s for ModificationRegulationID: %s", reg.ChangeType, reg.ModificationRegulationID)
+		}
 
 		if (i+1)%batchSize == 0 || i == len(regs)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -186,7 +200,7 @@ type FullTemporaryStopRegulationAction struct {
 	StoppedRegulationRoleType int    `xml:"stoppedRegulationRoleType,attr"`
 }
 
-func (regs FullTemporaryStopRegulations) BatchInsert(ctx context.Context, conn *pgx.Conn, batchSize int) error {
+func (regs FullTemporaryStopRegulations) BatchInsert(ctx context.Context, db DB, batchSize int) error {
 	insertQuery := `
 	INSERT INTO full_temporary_stop_regulation (
 		fts_regulation_id, fts_regulation_role_type, change_type, date_start, date_end, date_published,
@@ -219,20 +233,48 @@ func (regs FullTemporaryStopRegulations) BatchInsert(ctx context.Context, conn *
 	SET national = EXCLUDED.national;
 	`
 
+	// Actions missing from an update have been removed from the regulation
+	actionDeleteQuery := `
+	DELETE FROM full_temporary_stop_regulation_action
+	WHERE fts_regulation_id = $1
+		AND (stopped_regulation_id, stopped_regulation_role_type) NOT IN (
+			SELECT * FROM unnest($2::TEXT[], $3::INT[])
+		);
+	`
+
+	deleteQuery := `
+	DELETE FROM full_temporary_stop_regulation
+	WHERE fts_regulation_id = $1;
+	`
+
 	batch := &pgx.Batch{}
 
 	for i, reg := range regs {
-		batch.Queue(insertQuery, reg.FtsRegulationID, reg.FtsRegulationRoleType, reg.ChangeType, reg.DateStart, reg.DateEnd,
-			reg.DatePublished, reg.Description, reg.EffectiveEndDate, reg.JournalPage, reg.National, reg.OfficialJournalID,
-			reg.RegulationApprovedFlag, reg.ReplacementIndicator, reg.StoppedFlag)
+		switch reg.ChangeType {
+		case "U": // Insert or update
+			batch.Queue(insertQuery, reg.FtsRegulationID, reg.FtsRegulationRoleType, reg.ChangeType, reg.DateStart, reg.DateEnd,
+				reg.DatePublished, reg.Description, reg.EffectiveEndDate, reg.JournalPage, reg.National, reg.OfficialJournalID,
+				reg.RegulationApprovedFlag, reg.ReplacementIndicator, reg.StoppedFlag)
 
-		// Queue child actions
-		for _, action := range reg.FullTemporaryStopRegulationActions {
-			batch.Queue(actionInsertQuery, reg.FtsRegulationID, action.StoppedRegulationID, action.StoppedRegulationRoleType, action.National)
+			// Queue child actions
+			stoppedIDs := make([]string, 0, len(reg.FullTemporaryStopRegulationActions))
+			stoppedRoleTypes := make([]int, 0, len(reg.FullTemporaryStopRegulationActions))
+			for _, action := range reg.FullTemporaryStopRegulationActions {
+				stoppedIDs = append(stoppedIDs, action.StoppedRegulationID)
+				stoppedRoleTypes = append(stoppedRoleTypes, action.StoppedRegulationRoleType)
+				batch.Queue(actionInsertQuery, reg.FtsRegulationID, action.StoppedRegulationID, action.StoppedRegulationRoleType, action.National)
+			}
+			batch.Queue(actionDeleteQuery, reg.FtsRegulationID, stoppedIDs, stoppedRoleTypes)
+
+		case "D": // Delete
+			batch.Queue(deleteQuery, reg.FtsRegulationID)
+
+		default:
+			return fmt.Errorf("unknown ChangeType: %s for FtsRegulationID: %s", reg.ChangeType, reg.FtsRegulationID)
 		}
 
 		if (i+1)%batchSize == 0 || i == len(regs)-1 {
-			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
 				return fmt.Errorf("failed to execute batch: %w", err)
 			}
 			batch = &pgx.Batch{}
@@ -0,0 +1,104 @@
+package xmltypes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TaxCodes are the national Swedish tax codes, used for VAT and excise duties.
+type TaxCodes []TaxCode
+
+type TaxCode struct {
+	SID                 int                 `xml:"SID,attr"`
+	ChangeType          string              `xml:"changeType,attr"`
+	DateEnd             FileDistTime        `xml:"dateEnd,attr"`
+	DateStart           FileDistTime        `xml:"dateStart,attr"`
+	National            int                 `xml:"national,attr"`
+	TaxCode             string              `xml:"taxCode,attr"`
+	TaxCodeDescriptions TaxCodeDescriptions `xml:"taxCodeDescription"`
+}
+
+func (taxCodes TaxCodes) BatchInsert(ctx context.Context, db DB, batchSize int) error {
+	insertQuery := `
+	INSERT INTO tax_code (sid, tax_code, change_type, date_start, date_end, national)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (sid) DO UPDATE
+	SET tax_code = EXCLUDED.tax_code,
+		change_type = EXCLUDED.change_type,
+		date_start = EXCLUDED.date_start,
+		date_end = EXCLUDED.date_end,
+		national = EXCLUDED.national;
+	`
+
+	deleteQuery := `
+	DELETE FROM tax_code
+	WHERE sid = $1;
+	`
+
+	batch := &pgx.Batch{}
+
+	for i, taxCode := range taxCodes {
+		switch taxCode.ChangeType {
+		case "U": // Insert or update
+			batch.Queue(insertQuery, taxCode.SID, taxCode.TaxCode, taxCode.ChangeType, taxCode.DateStart, taxCode.DateEnd, taxCode.National)
+
+			// Queue child descriptions
+			if err := taxCode.TaxCodeDescriptions.QueueBatch(ctx, batch, taxCode.SID); err != nil {
+				return fmt.Errorf("failed to queue descriptions for TaxCode SID %d: %w", taxCode.SID, err)
+			}
+
+		case "D": // Delete
+			batch.Queue(deleteQuery, taxCode.SID)
+
+		default:
+			return fmt.Errorf("unknown ChangeType: %s for TaxCode SID: %d", taxCode.ChangeType, taxCode.SID)
+		}
+
+		if (i+1)%batchSize == 0 || i == len(taxCodes)-1 {
+			if err := db.SendBatch(ctx, batch).Close(); err != nil {
+				return fmt.Errorf("failed to execute batch: %w", err)
+			}
+			batch = &pgx.Batch{}
+		}
+	}
+
+	return nil
+}
+
+type TaxCodeDescriptions []TaxCodeDescription
+
+type TaxCodeDescription struct {
+	ParentSID   int    // added type
+	SID         int    `xml:"SID,attr"`
+	Description string `xml:"description,attr"`
+	LanguageID  string `xml:"languageId,attr"`
+}
+
+func (descriptions TaxCodeDescriptions) QueueBatch(ctx context.Context, batch *pgx.Batch, parentSID int) error {
+	insertQuery := `
+	INSERT INTO tax_code_description (parent_sid, sid, description, language_id)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (parent_sid, language_id) DO UPDATE
+	SET sid = EXCLUDED.sid,
+		description = EXCLUDED.description;
+	`
+
+	// Descriptions missing from an update have been removed from the tax code
+	deleteQuery := `
+	DELETE FROM tax_code_description
+	WHERE parent_sid = $1
+		AND language_id <> ALL($2::TEXT[]);
+	`
+
+	languageIDs := make([]string, 0, len(descriptions))
+	for _, desc := range descriptions {
+		desc.ParentSID = parentSID
+		languageIDs = append(languageIDs, desc.LanguageID)
+		batch.Queue(insertQuery, desc.ParentSID, desc.SID, desc.Description, desc.LanguageID)
+	}
+	batch.Queue(deleteQuery, parentSID, languageIDs)
+
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type Export struct {
@@ -21,49 +22,57 @@ type Parameters struct {
 	QueryDateStart *string `xml:"queryDateStart"`
 }
 
+// DB is the part of *pgx.Conn and pgx.Tx used to write filedist items,
+// so items can be inserted either directly or as part of a transaction.
+type DB interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
 type FileDistItem interface {
-	BatchInsert(context.Context, *pgx.Conn, int) error
+	BatchInsert(context.Context, DB, int) error
 }
 
 type Items struct {
-	AdditionalCodes              AdditionalCodes              `xml:"additionalCode"`
-	Certificates                 Certificates                 `xml:"certificate"`
-	CodeTypes                    CodeTypes                    `xml:"codeType"`
-	DeclarableGoodsNomenclatures DeclarableGoodsNomenclatures `xml:"declarableGoodsNomenclature"`
-	DutyExpressions              DutyExpressions              `xml:"dutyExpression"`
-	ExportRefundNomenclatures    ExportRefundNomenclatures    `xml:"exportRefundNomenclature"`
-	Footnotes                    Footnotes                    `xml:"footnote"`
-	GeographicalAreas            GeographicalAreas            `xml:"geographicalArea"`
-	GoodsNomenclatureGroups      GoodsNomenclatureGroups      `xml:"goodsNomenclatureGroup"`
-	GoodsNomenclatures           GoodsNomenclatures           `xml:"goodsNomenclature"`
-	LookupTables                 LookupTables                 `xml:"lookupTable"`
-	MeasureActions               MeasureActions               `xml:"measureAction"`
-	MeasureConditionCodes        MeasureConditionCodes        `xml:"measureConditionCode"`
-	MeasureTypes                 MeasureTypes                 `xml:"measureType"`
-	Measures                     Measures                     `xml:"measure"`
-	MeasurementUnitQualifiers    MeasurementUnitQualifiers    `xml:"measurementUnitQualifier"`
-	MeasurementUnits             MeasurementUnits             `xml:"measurementUnit"`
-	Measurements                 Measurements                 `xml:"measurement"`
-	MeursingAdditionalCodes      MeursingAdditionalCodes      `xml:"meursingAdditionalCode"`
-	MeursingHeadings             MeursingHeadings             `xml:"meursingHeading"`
-	MeursingSubheadings          MeursingSubheadings          `xml:"meursingSubheading"`
-	MeursingTablePlans           MeursingTablePlans           `xml:"meursingTablePlan"`
-	MonetaryExchangePeriods      MonetaryExchangePeriods      `xml:"monetaryExchangePeriod"`
-	PreferenceCode               PreferenceCodes              `xml:"preferenceCode"`
-	QuotaDefinition              QuotaDefinitions             `xml:"quotaDefinition"`
-	// QuotaBalanceEvent               []QuotaBalanceEvent             `xml:"quotaBalanceEvent"`
-	// QuotaUnblockingEvent            []QuotaUnblockingEvent          `xml:"quotaUnblockingEvent"`
-	// QuotaCriticalEvent              []QuotaCriticalEvent            `xml:"quotaCriticalEvent"`
-	// QuotaExhaustionEvent            []QuotaExhaustionEvent          `xml:"quotaExhaustionEvent"`
-	// QuotaReopeningEvent             []QuotaReopeningEvent           `xml:"quotaReopeningEvent"`
-	// QuotaUnsuspensionEvent          []QuotaUnsuspensionEvent        `xml:"quotaUnsuspensionEvent"`
-	// QuotaOrderNumber                []QuotaOrderNumber              `xml:"quotaOrderNumber"`
+	AdditionalCodes                 AdditionalCodes                 `xml:"additionalCode"`
+	Certificates                    Certificates                    `xml:"certificate"`
+	CodeTypes                       CodeTypes                       `xml:"codeType"`
+	DeclarableGoodsNomenclatures    DeclarableGoodsNomenclatures    `xml:"declarableGoodsNomenclature"`
+	DutyExpressions                 DutyExpressions                 `xml:"dutyExpression"`
+	ExportRefundNomenclatures       ExportRefundNomenclatures       `xml:"exportRefundNomenclature"`
+	Footnotes                       Footnotes                       `xml:"footnote"`
+	GeographicalAreas               GeographicalAreas               `xml:"geographicalArea"`
+	GoodsNomenclatureGroups         GoodsNomenclatureGroups         `xml:"goodsNomenclatureGroup"`
+	GoodsNomenclatures              GoodsNomenclatures              `xml:"goodsNomenclature"`
+	LookupTables                    LookupTables                    `xml:"lookupTable"`
+	MeasureActions                  MeasureActions                  `xml:"measureAction"`
+	MeasureConditionCodes           MeasureConditionCodes           `xml:"measureConditionCode"`
+	MeasureTypes                    MeasureTypes                    `xml:"measureType"`
+	Measures                        Measures                        `xml:"measure"`
+	MeasurementUnitQualifiers       MeasurementUnitQualifiers       `xml:"measurementUnitQualifier"`
+	MeasurementUnits                MeasurementUnits                `xml:"measurementUnit"`
+	Measurements                    Measurements                    `xml:"measurement"`
+	MeursingAdditionalCodes         MeursingAdditionalCodes         `xml:"meursingAdditionalCode"`
+	MeursingHeadings                MeursingHeadings                `xml:"meursingHeading"`
+	MeursingSubheadings             MeursingSubheadings             `xml:"meursingSubheading"`
+	MeursingTablePlans              MeursingTablePlans              `xml:"meursingTablePlan"`
+	MonetaryExchangePeriods         MonetaryExchangePeriods         `xml:"monetaryExchangePeriod"`
+	PreferenceCode                  PreferenceCodes                 `xml:"preferenceCode"`
+	QuotaDefinition                 QuotaDefinitions                `xml:"quotaDefinition"`
+	QuotaBalanceEvents              QuotaBalanceEvents              `xml:"quotaBalanceEvent"`
+	QuotaUnblockingEvents           QuotaUnblockingEvents           `xml:"quotaUnblockingEvent"`
+	QuotaCriticalEvents             QuotaCriticalEvents             `xml:"quotaCriticalEvent"`
+	QuotaExhaustionEvents           QuotaExhaustionEvents           `xml:"quotaExhaustionEvent"`
+	QuotaReopeningEvents            QuotaReopeningEvents            `xml:"quotaReopeningEvent"`
+	QuotaUnsuspensionEvents         QuotaUnsuspensionEvents         `xml:"quotaUnsuspensionEvent"`
+	QuotaOrderNumbers               QuotaOrderNumbers               `xml:"quotaOrderNumber"`
 	BaseRegulation                  BaseRegulations                 `xml:"baseRegulation"`
 	ModificationRegulation          ModificationRegulations         `xml:"modificationRegulation"`
 	FullTemporaryStopRegulation     FullTemporaryStopRegulations    `xml:"fullTemporaryStopRegulation"`
-	TaxCode                         []TaxCode                       `xml:"taxCode"`
+	TaxCodes                        TaxCodes                        `xml:"taxCode"`
 	UnquotedMonetaryExchangePeriods UnquotedMonetaryExchangePeriods `xml:"unquotedMonetaryExchangePeriod"`
-	// Record                          []Record                        `xml:"record"`
+	Records                         Records                         `xml:"record"` // filled while streaming, see NewRecord
 }
 
 // FileDistTime is a custom type that wraps time.Time for XML and database use.
@@ -130,26 +139,10 @@ func (ct FileDistTimeStamp) Value() *time.Time {
 		log.Fatalf("Failed to load location: %v", err)
 	}
 
-	t, err := time.Parse(layout, string(ct))
+	t, err := time.ParseInLocation(layout, string(ct), location)
 	if err != nil {
 		log.Fatalf("Failed to parse time: %v", err)
 	}
 
-	t = t.In(location)
 	return &t
 }
-
-type TaxCode struct {
-	ChangeType         string               `xml:"changeType,attr"`
-	DateStart          FileDistTime         `xml:"dateStart,attr"`
-	National           int                  `xml:"national,attr"`
-	SID                int                  `xml:"SID,attr"`
-	TaxCode            string               `xml:"taxCode,attr"`
-	TaxCodeDescription []TaxCodeDescription `xml:"taxCodeDescription"`
-}
-
-type TaxCodeDescription struct {
-	Description string `xml:"description,attr"`
-	LanguageID  string `xml:"languageId,attr"`
-	SID         int    `xml:"SID,attr"`
-}
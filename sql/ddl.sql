@@ -230,6 +230,8 @@ CREATE TABLE IF NOT EXISTS measure_condition_component (
 	PRIMARY KEY (parent_sid, duty_expression_id),
 	FOREIGN key (parent_sid) REFERENCES measure_condition (sid) ON DELETE cascade
 );
+-- Position of the component among the components of its condition, the order the duty expression is written in
+ALTER TABLE measure_condition_component ADD COLUMN IF NOT EXISTS sequence_number INT;
 CREATE TABLE IF NOT EXISTS measure_footnote_association (
 	parent_sid INT,
 	footnote_id VARCHAR(255),
@@ -249,6 +251,8 @@ CREATE TABLE IF NOT EXISTS measure_component (
 	PRIMARY KEY (parent_sid, duty_expression_id),
 	FOREIGN key (parent_sid) REFERENCES measure (sid) ON DELETE cascade
 );
+-- Position of the component among the components of its measure, the order the duty expression is written in
+ALTER TABLE measure_component ADD COLUMN IF NOT EXISTS sequence_number INT;
 CREATE TABLE IF NOT EXISTS measure_excluded_geographical_area (
 	parent_sid INT,
 	geographical_area_id VARCHAR(255),
@@ -289,7 +293,7 @@ CREATE TABLE IF NOT EXISTS measure_type_description (
 	language_id VARCHAR(255),
 	national INT,
 	PRIMARY KEY (parent_measure_type, language_id),
-	FOREIGN key (parent_measure_type) REFERENCES measure_type (measure_type)
+	FOREIGN key (parent_measure_type) REFERENCES measure_type (measure_type) ON DELETE CASCADE
 );
 -- Goods Nomenclature Groups
 CREATE TABLE IF NOT EXISTS goods_nomenclature_group (
@@ -322,7 +326,7 @@ CREATE TABLE IF NOT EXISTS goods_nomenclature_group_description (
 	) REFERENCES goods_nomenclature_group (
 		goods_nomenclature_group_id,
 		goods_nomenclature_group_type
-	)
+	) ON DELETE CASCADE
 );
 -- Monetary Exchange Periods
 CREATE TABLE IF NOT EXISTS monetary_exchange_period (
@@ -377,7 +381,7 @@ CREATE TABLE IF NOT EXISTS footnote_description_period (
 	date_end TIMESTAMP,
 	date_start TIMESTAMP,
 	national INT,
-	FOREIGN key (parent_footnote_id, parent_footnote_type) REFERENCES footnote (footnote_id, footnote_type)
+	FOREIGN key (parent_footnote_id, parent_footnote_type) REFERENCES footnote (footnote_id, footnote_type) ON DELETE CASCADE
 );
 CREATE TABLE IF NOT EXISTS footnote_description (
 	parent_sid INT,
@@ -460,7 +464,7 @@ CREATE TABLE IF NOT EXISTS measure_action_description (
 	language_id VARCHAR(255),
 	national INT,
 	PRIMARY KEY (parent_action_code, language_id),
-	FOREIGN key (parent_action_code) REFERENCES measure_action (action_code)
+	FOREIGN key (parent_action_code) REFERENCES measure_action (action_code) ON DELETE CASCADE
 );
 -- Measure Condition Code
 CREATE TABLE IF NOT EXISTS measure_condition_code (
@@ -477,7 +481,7 @@ CREATE TABLE IF NOT EXISTS measure_condition_code_description (
 	language_id VARCHAR(255),
 	national INT,
 	PRIMARY KEY (parent_condition_code, language_id),
-	FOREIGN key (parent_condition_code) REFERENCES measure_condition_code (condition_code)
+	FOREIGN key (parent_condition_code) REFERENCES measure_condition_code (condition_code) ON DELETE CASCADE
 );
 -- Code Type
 CREATE TABLE IF NOT EXISTS code_type (
@@ -496,7 +500,7 @@ CREATE TABLE IF NOT EXISTS code_type_description (
 	language_id VARCHAR(255),
 	national INT,
 	PRIMARY KEY (parent_id, language_id),
-	FOREIGN key (parent_id) REFERENCES code_type (id)
+	FOREIGN key (parent_id) REFERENCES code_type (id) ON DELETE CASCADE
 );
 -- Duty Expression
 CREATE TABLE IF NOT EXISTS duty_expression (
@@ -515,7 +519,7 @@ CREATE TABLE IF NOT EXISTS duty_expression_description (
 	language_id VARCHAR(255),
 	national INT,
 	PRIMARY KEY (parent_duty_expression_id, language_id),
-	FOREIGN key (parent_duty_expression_id) REFERENCES duty_expression (duty_expression_id)
+	FOREIGN key (parent_duty_expression_id) REFERENCES duty_expression (duty_expression_id) ON DELETE CASCADE
 );
 -- Lookup Table
 CREATE TABLE IF NOT EXISTS lookup_table (
@@ -574,7 +578,7 @@ CREATE TABLE IF NOT EXISTS measurement_unit_qualifier_description (
 		parent_measurement_unit_qualifier_code,
 		language_id
 	),
-	FOREIGN key (parent_measurement_unit_qualifier_code) REFERENCES measurement_unit_qualifier (measurement_unit_qualifier_code)
+	FOREIGN key (parent_measurement_unit_qualifier_code) REFERENCES measurement_unit_qualifier (measurement_unit_qualifier_code) ON DELETE CASCADE
 );
 -- Meursing Additional Code
 CREATE TABLE IF NOT EXISTS meursing_additional_code (
@@ -699,6 +703,7 @@ CREATE TABLE IF NOT EXISTS quota_definition (
 	national INT
 );
 CREATE INDEX IF NOT EXISTS idx_quota_definition_sid_quota_order_number ON quota_definition (sid_quota_order_number);
+CREATE INDEX IF NOT EXISTS idx_quota_definition_quota_order_number ON quota_definition (quota_order_number);
 CREATE TABLE IF NOT EXISTS quota_suspension_period (
 	sid INT PRIMARY KEY,
 	parent_sid INT NOT NULL,
@@ -730,6 +735,93 @@ CREATE TABLE IF NOT EXISTS quota_association (
 );
 CREATE INDEX IF NOT EXISTS idx_quota_association_sid_sub_quota ON quota_association (sid_sub_quota);
 CREATE INDEX IF NOT EXISTS idx_quota_association_relation_type ON quota_association (relation_type);
+CREATE TABLE IF NOT EXISTS quota_order_number (
+	sid INT PRIMARY KEY,
+	quota_order_number VARCHAR(6),
+	change_type TEXT,
+	date_start DATE,
+	date_end DATE,
+	national INT
+);
+CREATE INDEX IF NOT EXISTS idx_quota_order_number ON quota_order_number (quota_order_number);
+CREATE TABLE IF NOT EXISTS quota_order_number_origin (
+	sid INT PRIMARY KEY,
+	parent_sid INT NOT NULL,
+	geographical_area_id TEXT,
+	sid_geographical_area INT,
+	date_start DATE,
+	date_end DATE,
+	national INT,
+	FOREIGN KEY (parent_sid) REFERENCES quota_order_number (sid) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_quota_order_number_origin_parent_sid ON quota_order_number_origin (parent_sid);
+CREATE TABLE IF NOT EXISTS quota_order_number_origin_exclusion (
+	parent_sid INT,
+	sid_geographical_area INT,
+	geographical_area_id TEXT,
+	national INT,
+	PRIMARY KEY (parent_sid, sid_geographical_area),
+	FOREIGN KEY (parent_sid) REFERENCES quota_order_number_origin (sid) ON DELETE CASCADE
+);
+-- Quota events
+CREATE TABLE IF NOT EXISTS quota_balance_event (
+	sid_quota_definition INT NOT NULL,
+	occurrence_timestamp TIMESTAMPTZ NOT NULL,
+	end_occurrence_timestamp TIMESTAMPTZ,
+	imported_amount FLOAT,
+	last_import_date DATE,
+	new_balance FLOAT,
+	old_balance FLOAT,
+	change_type TEXT,
+	national INT,
+	PRIMARY KEY (sid_quota_definition, occurrence_timestamp)
+);
+CREATE TABLE IF NOT EXISTS quota_critical_event (
+	sid_quota_definition INT NOT NULL,
+	occurrence_timestamp TIMESTAMPTZ NOT NULL,
+	end_occurrence_timestamp TIMESTAMPTZ,
+	critical_date DATE,
+	quota_critical_state_code TEXT,
+	change_type TEXT,
+	national INT,
+	PRIMARY KEY (sid_quota_definition, occurrence_timestamp)
+);
+CREATE TABLE IF NOT EXISTS quota_exhaustion_event (
+	sid_quota_definition INT NOT NULL,
+	occurrence_timestamp TIMESTAMPTZ NOT NULL,
+	end_occurrence_timestamp TIMESTAMPTZ,
+	exhaustion_date DATE,
+	change_type TEXT,
+	national INT,
+	PRIMARY KEY (sid_quota_definition, occurrence_timestamp)
+);
+CREATE TABLE IF NOT EXISTS quota_reopening_event (
+	sid_quota_definition INT NOT NULL,
+	occurrence_timestamp TIMESTAMPTZ NOT NULL,
+	end_occurrence_timestamp TIMESTAMPTZ,
+	reopening_date DATE,
+	change_type TEXT,
+	national INT,
+	PRIMARY KEY (sid_quota_definition, occurrence_timestamp)
+);
+CREATE TABLE IF NOT EXISTS quota_unblocking_event (
+	sid_quota_definition INT NOT NULL,
+	occurrence_timestamp TIMESTAMPTZ NOT NULL,
+	end_occurrence_timestamp TIMESTAMPTZ,
+	unblocking_date DATE,
+	change_type TEXT,
+	national INT,
+	PRIMARY KEY (sid_quota_definition, occurrence_timestamp)
+);
+CREATE TABLE IF NOT EXISTS quota_unsuspension_event (
+	sid_quota_definition INT NOT NULL,
+	occurrence_timestamp TIMESTAMPTZ NOT NULL,
+	end_occurrence_timestamp TIMESTAMPTZ,
+	unsuspension_date DATE,
+	change_type TEXT,
+	national INT,
+	PRIMARY KEY (sid_quota_definition, occurrence_timestamp)
+);
 -- Base Regulation
 CREATE TABLE IF NOT EXISTS base_regulation (
 	regulation_id VARCHAR(255) PRIMARY KEY,
@@ -801,12 +893,206 @@ CREATE TABLE IF NOT EXISTS full_temporary_stop_regulation_action (
 		stopped_regulation_id,
 		stopped_regulation_role_type
 	),
-	FOREIGN key (fts_regulation_id) REFERENCES full_temporary_stop_regulation (fts_regulation_id)
+	FOREIGN key (fts_regulation_id) REFERENCES full_temporary_stop_regulation (fts_regulation_id) ON DELETE CASCADE
+);
+-- Tax Code
+CREATE TABLE IF NOT EXISTS tax_code (
+	sid INT PRIMARY KEY,
+	tax_code VARCHAR(255),
+	change_type VARCHAR(255),
+	date_end TIMESTAMP,
+	date_start TIMESTAMP,
+	national INT
+);
+CREATE INDEX if NOT EXISTS idx_tax_code_tax_code ON tax_code (tax_code);
+CREATE TABLE IF NOT EXISTS tax_code_description (
+	parent_sid INT,
+	sid INT,
+	description TEXT,
+	language_id VARCHAR(255),
+	PRIMARY KEY (parent_sid, language_id),
+	FOREIGN key (parent_sid) REFERENCES tax_code (sid) ON DELETE CASCADE
 );
 -- Inserted files to validate if a file should be processed or not. 
 CREATE TABLE IF NOT EXISTS inserted_files (
 	file_name VARCHAR(255) PRIMARY KEY,
 	date_inserted date,
 	time_taken TIME,
-	file_size FLOAT
-);
\ No newline at end of file
+	file_size FLOAT,
+	-- sha256 of the file as downloaded and of its decrypted xml
+	checksum VARCHAR(64),
+	xml_checksum VARCHAR(64)
+);
+-- Checksums were added after the table was first created
+ALTER TABLE inserted_files ADD COLUMN IF NOT EXISTS checksum VARCHAR(64);
+ALTER TABLE inserted_files ADD COLUMN IF NOT EXISTS xml_checksum VARCHAR(64);
+-- Record envelopes of dif files, kept for auditing which changes were applied from which file
+CREATE TABLE IF NOT EXISTS dif_record (
+	file_name VARCHAR(255),
+	position INT,
+	element VARCHAR(255),
+	change_type VARCHAR(255),
+	attributes JSONB,
+	PRIMARY KEY (file_name, position)
+);
+-- History of database maintenance runs and the files imported by them
+CREATE TABLE IF NOT EXISTS import_run (
+	id SERIAL PRIMARY KEY,
+	started_at TIMESTAMPTZ NOT NULL,
+	finished_at TIMESTAMPTZ,
+	status VARCHAR(255) NOT NULL,
+	error TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_import_run_started_at ON import_run (started_at);
+CREATE TABLE IF NOT EXISTS import_file (
+	id SERIAL PRIMARY KEY,
+	run_id INT NOT NULL,
+	directory VARCHAR(255) NOT NULL,
+	file_name VARCHAR(255) NOT NULL,
+	started_at TIMESTAMPTZ NOT NULL,
+	finished_at TIMESTAMPTZ,
+	status VARCHAR(255) NOT NULL,
+	error TEXT,
+	file_size BIGINT,
+	checksum VARCHAR(64),
+	-- Number of upserted and deleted items per element name, e.g. {"measure": {"upserted": 10, "deleted": 2}}
+	item_counts JSONB,
+	FOREIGN key (run_id) REFERENCES import_run (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_import_file_run_id ON import_file (run_id);
+-- Latest refresh of each materialized view in ddl_views.sql
+CREATE TABLE IF NOT EXISTS materialized_view_refresh (
+	view_name VARCHAR(255) PRIMARY KEY,
+	refreshed_at TIMESTAMPTZ,
+	duration INTERVAL
+);
+-- Files rejected by signature verification. They are never imported.
+CREATE TABLE IF NOT EXISTS quarantined_files (
+	file_name VARCHAR(255) PRIMARY KEY,
+	reason TEXT,
+	date_quarantined TIMESTAMP
+);
+-- History of tariff rows changed by dif files, which update rows in place. Every version of a row is kept in
+-- <table>_history together with the period it was in the database, from recorded_from until recorded_to. The current
+-- version has no recorded_to. Rows loaded before the history began, by the initial load or before the history
+-- tables existed, are assumed to have always looked like that and are recorded from -infinity. Rows inserted later,
+-- also those dated back in time, are recorded from when they were inserted.
+-- Versions are copied by column name, so a column added to a table is added to the end of its history table.
+-- record_history is called by row triggers with the key columns of the table as arguments.
+CREATE OR REPLACE FUNCTION record_history() RETURNS trigger AS $$
+DECLARE
+	history_table TEXT := TG_TABLE_NAME || '_history';
+	key_matches TEXT;
+BEGIN
+	-- The initial load of an empty database is recorded at once afterwards by backfill_history
+	IF current_setting('tulltaxan.initial_load', true) = 'on' THEN
+		RETURN NULL;
+	END IF;
+
+	IF TG_OP <> 'INSERT' THEN
+		SELECT string_agg(format('%I = ($1).%I', key_column, key_column), ' AND ')
+		INTO key_matches
+		FROM unnest(TG_ARGV) AS key_column;
+
+		EXECUTE format('UPDATE %I.%I SET recorded_to = now() WHERE recorded_to IS NULL AND %s', TG_TABLE_SCHEMA, history_table, key_matches)
+		USING OLD;
+	END IF;
+
+	IF TG_OP <> 'DELETE' THEN
+		EXECUTE format('INSERT INTO %1$I.%2$I SELECT * FROM jsonb_populate_record(NULL::%1$I.%2$I, to_jsonb($1) || jsonb_build_object(''recorded_from'', now()))',
+			TG_TABLE_SCHEMA, history_table)
+		USING NEW;
+	END IF;
+
+	RETURN NULL;
+END $$ LANGUAGE plpgsql;
+-- backfill_history records the rows of a table that have no history yet, such as the rows of the initial load,
+-- from -infinity. It is called with the table and its key columns.
+CREATE OR REPLACE FUNCTION backfill_history(table_name TEXT, VARIADIC key_columns TEXT[]) RETURNS void AS $$
+DECLARE
+	history_table TEXT := table_name || '_history';
+	key_matches TEXT;
+BEGIN
+	SELECT string_agg(format('h.%I = t.%I', key_column, key_column), ' AND ')
+	INTO key_matches
+	FROM unnest(key_columns) AS key_column;
+
+	EXECUTE format('INSERT INTO %1$I SELECT v.* FROM %2$I t, jsonb_populate_record(NULL::%1$I, to_jsonb(t) || ''{"recorded_from": "-infinity"}'') v WHERE NOT EXISTS (SELECT 1 FROM %1$I h WHERE %3$s)',
+		history_table, table_name, key_matches);
+END $$ LANGUAGE plpgsql;
+CREATE TABLE IF NOT EXISTS measure_history (LIKE measure);
+ALTER TABLE measure_history ADD COLUMN IF NOT EXISTS recorded_from TIMESTAMPTZ NOT NULL;
+ALTER TABLE measure_history ADD COLUMN IF NOT EXISTS recorded_to TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_measure_history_sid ON measure_history (sid);
+CREATE INDEX IF NOT EXISTS idx_measure_history_goods_nomenclature_code ON measure_history (goods_nomenclature_code);
+-- Rows imported before the history existed
+SELECT backfill_history('measure', 'sid')
+WHERE NOT EXISTS (SELECT 1 FROM measure_history);
+CREATE OR REPLACE TRIGGER measure_history_insert_delete AFTER INSERT OR DELETE ON measure
+FOR EACH ROW EXECUTE FUNCTION record_history('sid');
+CREATE OR REPLACE TRIGGER measure_history_update AFTER UPDATE ON measure
+FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION record_history('sid');
+CREATE TABLE IF NOT EXISTS measure_component_history (LIKE measure_component);
+ALTER TABLE measure_component_history ADD COLUMN IF NOT EXISTS recorded_from TIMESTAMPTZ NOT NULL;
+ALTER TABLE measure_component_history ADD COLUMN IF NOT EXISTS recorded_to TIMESTAMPTZ;
+-- Columns added to measure_component after its history table was created
+ALTER TABLE measure_component_history ADD COLUMN IF NOT EXISTS sequence_number INT;
+CREATE INDEX IF NOT EXISTS idx_measure_component_history_parent_sid_duty_expression_id ON measure_component_history (parent_sid, duty_expression_id);
+-- Rows imported before the history existed
+SELECT backfill_history('measure_component', 'parent_sid', 'duty_expression_id')
+WHERE NOT EXISTS (SELECT 1 FROM measure_component_history);
+CREATE OR REPLACE TRIGGER measure_component_history_insert_delete AFTER INSERT OR DELETE ON measure_component
+FOR EACH ROW EXECUTE FUNCTION record_history('parent_sid', 'duty_expression_id');
+CREATE OR REPLACE TRIGGER measure_component_history_update AFTER UPDATE ON measure_component
+FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION record_history('parent_sid', 'duty_expression_id');
+CREATE TABLE IF NOT EXISTS measure_condition_history (LIKE measure_condition);
+ALTER TABLE measure_condition_history ADD COLUMN IF NOT EXISTS recorded_from TIMESTAMPTZ NOT NULL;
+ALTER TABLE measure_condition_history ADD COLUMN IF NOT EXISTS recorded_to TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_measure_condition_history_sid ON measure_condition_history (sid);
+-- Rows imported before the history existed
+SELECT backfill_history('measure_condition', 'sid')
+WHERE NOT EXISTS (SELECT 1 FROM measure_condition_history);
+CREATE OR REPLACE TRIGGER measure_condition_history_insert_delete AFTER INSERT OR DELETE ON measure_condition
+FOR EACH ROW EXECUTE FUNCTION record_history('sid');
+CREATE OR REPLACE TRIGGER measure_condition_history_update AFTER UPDATE ON measure_condition
+FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION record_history('sid');
+CREATE TABLE IF NOT EXISTS goods_nomenclature_description_history (LIKE goods_nomenclature_description);
+ALTER TABLE goods_nomenclature_description_history ADD COLUMN IF NOT EXISTS recorded_from TIMESTAMPTZ NOT NULL;
+ALTER TABLE goods_nomenclature_description_history ADD COLUMN IF NOT EXISTS recorded_to TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_goods_nomenclature_description_history_parent_sid_language_id ON goods_nomenclature_description_history (parent_sid, language_id);
+-- Rows imported before the history existed
+SELECT backfill_history('goods_nomenclature_description', 'parent_sid', 'language_id')
+WHERE NOT EXISTS (SELECT 1 FROM goods_nomenclature_description_history);
+CREATE OR REPLACE TRIGGER goods_nomenclature_description_history_insert_delete AFTER INSERT OR DELETE ON goods_nomenclature_description
+FOR EACH ROW EXECUTE FUNCTION record_history('parent_sid', 'language_id');
+CREATE OR REPLACE TRIGGER goods_nomenclature_description_history_update AFTER UPDATE ON goods_nomenclature_description
+FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION record_history('parent_sid', 'language_id');
+-- Changes to existing databases that must only be made once. Each is recorded by name when it has been made.
+CREATE TABLE IF NOT EXISTS schema_migration (
+	name VARCHAR(255) PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL
+);
+-- Databases created before every foreign key cascaded keep their old constraints, since the tables above already exist.
+-- Recreate those constraints with ON DELETE CASCADE so deleting a parent removes all of its children.
+DO $$
+DECLARE fk RECORD;
+BEGIN
+	IF EXISTS (SELECT 1 FROM schema_migration WHERE name = 'cascade_foreign_keys') THEN
+		RETURN;
+	END IF;
+
+	FOR fk IN
+		SELECT conname,
+			conrelid::regclass AS child_table,
+			pg_get_constraintdef(oid) AS definition
+		FROM pg_constraint
+		WHERE contype = 'f'
+			AND confdeltype <> 'c'
+			AND connamespace = current_schema()::regnamespace
+	LOOP
+		EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I', fk.child_table, fk.conname);
+		EXECUTE format('ALTER TABLE %s ADD CONSTRAINT %I %s ON DELETE CASCADE', fk.child_table, fk.conname, fk.definition);
+	END LOOP;
+
+	INSERT INTO schema_migration (name, applied_at) VALUES ('cascade_foreign_keys', now());
+END $$;
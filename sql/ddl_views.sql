@@ -1,60 +1,119 @@
 -------------- VIEWS ----------------
 
+-- The views hold the descriptions of every period, each row with the days it is valid in a validity daterange,
+-- so searches can be made as of any date with validity @> date.
+
+-- validity_range returns the days from date_start to date_end, both inclusive. A missing date leaves the range unbounded
+-- and an end before the start gives an empty range.
+CREATE OR REPLACE FUNCTION validity_range(date_start TIMESTAMP, date_end TIMESTAMP) RETURNS daterange AS $$
+    SELECT CASE
+        WHEN date_end < date_start THEN 'empty'::daterange
+        ELSE daterange(COALESCE(date_start::date, '-infinity'), date_end::date, '[]')
+    END
+$$ LANGUAGE sql IMMUTABLE;
+
 -- HS DESCRIPTION
 DROP MATERIALIZED VIEW IF EXISTS mv_hs_desc CASCADE;
 
 CREATE MATERIALIZED VIEW mv_hs_desc AS
+WITH description_spans AS (
+    -- The days each description is valid, limited to the days its goods nomenclature is valid
+    SELECT 
+        gn.goods_nomenclature_code AS hs_code,
+        gnd.language_id,
+        gnd.description,
+        validity_range(gn.date_start, gn.date_end) * validity_range(gndp.date_start, gndp.date_end) AS validity
+    FROM 
+        goods_nomenclature gn
+    JOIN goods_nomenclature_description_period gndp 
+        ON gn.sid = gndp.parent_sid
+    JOIN goods_nomenclature_description gnd 
+        ON gndp.sid = gnd.parent_sid
+    WHERE 
+        gnd.language_id = 'SV'
+),
+boundaries AS (
+    SELECT hs_code, language_id, lower(validity) AS boundary FROM description_spans WHERE NOT isempty(validity)
+    UNION
+    SELECT hs_code, language_id, upper(validity) FROM description_spans WHERE NOT isempty(validity)
+),
+segments AS (
+    -- Consecutive boundaries split the time line into segments during which the descriptions of a code do not change.
+    -- The last segment is unbounded, it is only kept if a description is valid without end.
+    SELECT 
+        hs_code,
+        language_id,
+        daterange(boundary, LEAD(boundary) OVER (PARTITION BY hs_code, language_id ORDER BY boundary)) AS validity
+    FROM boundaries
+    WHERE boundary IS NOT NULL
+)
 SELECT 
-    gn.goods_nomenclature_code AS hs_code,
-    string_agg(gnd.description, ' ') AS description,
-    to_tsvector('swedish', string_agg(gnd.description, ' ')) AS search_vector,
-    gnd.language_id
+    s.hs_code,
+    string_agg(ds.description, ' ') AS description,
+    to_tsvector('swedish', string_agg(ds.description, ' ')) AS search_vector,
+    s.language_id,
+    s.validity
 FROM 
-    goods_nomenclature gn
-LEFT JOIN goods_nomenclature_description_period gndp 
-    ON gn.sid = gndp.parent_sid
-LEFT JOIN goods_nomenclature_description gnd 
-    ON gndp.sid = gnd.parent_sid
-WHERE 
-    (gn.date_end IS NULL OR gn.date_end > now()) 
-    AND (gndp.date_end IS NULL OR gndp.date_end > now()) 
-    AND gnd.language_id = 'SV'
+    segments s
+JOIN description_spans ds 
+    ON s.hs_code = ds.hs_code
+    AND s.language_id = ds.language_id
+    AND ds.validity @> lower(s.validity)
 GROUP BY 
-    gn.goods_nomenclature_code, gnd.language_id;
+    s.hs_code, s.language_id, s.validity;
 
 CREATE INDEX IF NOT EXISTS idx_mv_hs_desc_search_vector ON mv_hs_desc USING gin (search_vector);
+-- A unique index is required to refresh the view concurrently
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_hs_desc_hs_code_language_validity ON mv_hs_desc (hs_code, language_id, validity);
 
 -- HS LEVEL DESCRIPTIONS
 DROP MATERIALIZED VIEW IF EXISTS mv_hs_level_desc CASCADE;
 
 CREATE MATERIALIZED VIEW mv_hs_level_desc AS
-SELECT 
-    mvd.hs_code AS cn_code,
-    'CN' AS level,
-    mvd.description AS cn_descriptions,
-
-    hs_undernumber.hs_code AS hs_undernumber,
-    'HS Undernumber' AS hs_undernumber_level,
-    hs_undernumber.description AS hs_undernumber_descriptions,
-
-    hs.hs_code AS hs_code,
-    'HS' AS hs_level,
-    hs.description AS hs_descriptions,
-
-    chapter.hs_code AS chapter,
-    'Chapter' AS chapter_level,
-    chapter.description AS chapter_descriptions
-FROM mv_hs_desc mvd
-INNER JOIN declarable_goods_nomenclature dgn
-    ON LEFT(mvd.hs_code, 8) = dgn.goods_nomenclature_code AND RIGHT(mvd.hs_code, 2) = '00'
-LEFT JOIN mv_hs_desc hs_undernumber 
-    ON LEFT(mvd.hs_code, 6) || '0000' = hs_undernumber.hs_code
-LEFT JOIN mv_hs_desc hs 
-    ON LEFT(mvd.hs_code, 4) || '000000' = hs.hs_code
-LEFT JOIN mv_hs_desc chapter 
-    ON LEFT(mvd.hs_code, 2) || '00000000' = chapter.hs_code
+SELECT *
+FROM (
+    SELECT 
+        mvd.hs_code AS cn_code,
+        'CN' AS level,
+        mvd.description AS cn_descriptions,
+
+        hs_undernumber.hs_code AS hs_undernumber,
+        'HS Undernumber' AS hs_undernumber_level,
+        hs_undernumber.description AS hs_undernumber_descriptions,
+
+        hs.hs_code AS hs_code,
+        'HS' AS hs_level,
+        hs.description AS hs_descriptions,
+
+        chapter.hs_code AS chapter,
+        'Chapter' AS chapter_level,
+        chapter.description AS chapter_descriptions,
+
+        -- The days all levels are valid, a missing level does not limit them
+        mvd.validity
+            * validity_range(dgn.date_start, dgn.date_end)
+            * COALESCE(hs_undernumber.validity, '(,)')
+            * COALESCE(hs.validity, '(,)')
+            * COALESCE(chapter.validity, '(,)') AS validity
+    FROM mv_hs_desc mvd
+    INNER JOIN declarable_goods_nomenclature dgn
+        ON LEFT(mvd.hs_code, 8) = dgn.goods_nomenclature_code AND RIGHT(mvd.hs_code, 2) = '00'
+        AND validity_range(dgn.date_start, dgn.date_end) && mvd.validity
+    LEFT JOIN mv_hs_desc hs_undernumber 
+        ON LEFT(mvd.hs_code, 6) || '0000' = hs_undernumber.hs_code
+        AND hs_undernumber.validity && mvd.validity
+    LEFT JOIN mv_hs_desc hs 
+        ON LEFT(mvd.hs_code, 4) || '000000' = hs.hs_code
+        AND hs.validity && mvd.validity
+    LEFT JOIN mv_hs_desc chapter 
+        ON LEFT(mvd.hs_code, 2) || '00000000' = chapter.hs_code
+        AND chapter.validity && mvd.validity
+) levels
+WHERE NOT isempty(validity)
 ;
 
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_hs_level_desc_cn_code_validity ON mv_hs_level_desc (cn_code, validity);
+
 
 -- HS SEARCH
 DROP MATERIALIZED VIEW IF EXISTS mv_goods_nomenclature_search CASCADE;
@@ -76,13 +135,33 @@ SELECT
             COALESCE(hs_undernumber_descriptions, ''), ' ',
             COALESCE(cn_descriptions, '')
         )
-    ) AS search_vector
+    ) AS search_vector,
+    validity
 FROM mv_hs_level_desc;
 
 CREATE INDEX IF NOT EXISTS idx_mv_goods_nomenclature_search_vector ON mv_goods_nomenclature_search USING gin (search_vector);
--- Enable pg_trgm extension
-CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_goods_nomenclature_search_cn_code_validity ON mv_goods_nomenclature_search (cn_code, validity);
+-- Enable pg_trgm extension. It lives in its own schema, so it survives the live schema being replaced by a rebuild.
+-- The extensions schema must be on the search_path.
+CREATE SCHEMA IF NOT EXISTS extensions;
+CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA extensions;
+-- Databases created before the extensions schema existed have pg_trgm in the live schema
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm' AND extnamespace <> 'extensions'::regnamespace) THEN
+        ALTER EXTENSION pg_trgm SET SCHEMA extensions;
+    END IF;
+END $$;
 
 -- Create a trigram index for the descriptions column
 CREATE INDEX IF NOT EXISTS idx_goods_nomenclature_descriptions_trgm
 ON mv_goods_nomenclature_search USING gin (descriptions gin_trgm_ops);
+
+-- The views were just built, record it as their latest refresh
+INSERT INTO materialized_view_refresh (view_name, refreshed_at)
+VALUES ('mv_hs_desc', CURRENT_TIMESTAMP),
+    ('mv_hs_level_desc', CURRENT_TIMESTAMP),
+    ('mv_goods_nomenclature_search', CURRENT_TIMESTAMP)
+ON CONFLICT (view_name) DO UPDATE
+SET refreshed_at = EXCLUDED.refreshed_at,
+    duration = NULL;
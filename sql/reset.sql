@@ -9,6 +9,7 @@ DROP TABLE IF EXISTS certificate_description_period CASCADE;
 DROP TABLE IF EXISTS code_type CASCADE;
 DROP TABLE IF EXISTS code_type_description CASCADE;
 DROP TABLE IF EXISTS declarable_goods_nomenclature CASCADE;
+DROP TABLE IF EXISTS dif_record CASCADE;
 DROP TABLE IF EXISTS duty_expression CASCADE;
 DROP TABLE IF EXISTS duty_expression_description CASCADE;
 DROP TABLE IF EXISTS export_refund_nomenclature CASCADE;
@@ -27,26 +28,33 @@ DROP TABLE IF EXISTS geographical_area_description_period CASCADE;
 DROP TABLE IF EXISTS geographical_area_membership CASCADE;
 DROP TABLE IF EXISTS goods_nomenclature CASCADE;
 DROP TABLE IF EXISTS goods_nomenclature_description CASCADE;
+DROP TABLE IF EXISTS goods_nomenclature_description_history CASCADE;
 DROP TABLE IF EXISTS goods_nomenclature_description_period CASCADE;
 DROP TABLE IF EXISTS goods_nomenclature_footnote_association CASCADE;
 DROP TABLE IF EXISTS goods_nomenclature_group CASCADE;
 DROP TABLE IF EXISTS goods_nomenclature_group_description CASCADE;
 DROP TABLE IF EXISTS goods_nomenclature_group_membership CASCADE;
 DROP TABLE IF EXISTS goods_nomenclature_indent CASCADE;
+DROP TABLE IF EXISTS import_file CASCADE;
+DROP TABLE IF EXISTS import_run CASCADE;
 DROP TABLE IF EXISTS inserted_files CASCADE;
 DROP TABLE IF EXISTS lookup_table CASCADE;
 DROP TABLE IF EXISTS lookup_table_description CASCADE;
 DROP TABLE IF EXISTS lookup_table_item CASCADE;
+DROP TABLE IF EXISTS materialized_view_refresh CASCADE;
 DROP TABLE IF EXISTS measure CASCADE;
 DROP TABLE IF EXISTS measure_action CASCADE;
 DROP TABLE IF EXISTS measure_action_description CASCADE;
 DROP TABLE IF EXISTS measure_component CASCADE;
+DROP TABLE IF EXISTS measure_component_history CASCADE;
 DROP TABLE IF EXISTS measure_condition CASCADE;
 DROP TABLE IF EXISTS measure_condition_code CASCADE;
 DROP TABLE IF EXISTS measure_condition_code_description CASCADE;
 DROP TABLE IF EXISTS measure_condition_component CASCADE;
+DROP TABLE IF EXISTS measure_condition_history CASCADE;
 DROP TABLE IF EXISTS measure_excluded_geographical_area CASCADE;
 DROP TABLE IF EXISTS measure_footnote_association CASCADE;
+DROP TABLE IF EXISTS measure_history CASCADE;
 DROP TABLE IF EXISTS measure_partial_temporary_stop CASCADE;
 DROP TABLE IF EXISTS measure_type CASCADE;
 DROP TABLE IF EXISTS measure_type_description CASCADE;
@@ -67,7 +75,19 @@ DROP TABLE IF EXISTS monetary_exchange_period CASCADE;
 DROP TABLE IF EXISTS monetary_exchange_rate CASCADE;
 DROP TABLE IF EXISTS preference_code CASCADE;
 DROP TABLE IF EXISTS preference_code_description CASCADE;
+DROP TABLE IF EXISTS quarantined_files CASCADE;
 DROP TABLE IF EXISTS quota_association CASCADE;
+DROP TABLE IF EXISTS quota_balance_event CASCADE;
 DROP TABLE IF EXISTS quota_blocking_period CASCADE;
+DROP TABLE IF EXISTS quota_critical_event CASCADE;
 DROP TABLE IF EXISTS quota_definition CASCADE;
-DROP TABLE IF EXISTS quota_suspension_period CASCADE;
\ No newline at end of file
+DROP TABLE IF EXISTS quota_exhaustion_event CASCADE;
+DROP TABLE IF EXISTS quota_order_number CASCADE;
+DROP TABLE IF EXISTS quota_order_number_origin CASCADE;
+DROP TABLE IF EXISTS quota_order_number_origin_exclusion CASCADE;
+DROP TABLE IF EXISTS quota_reopening_event CASCADE;
+DROP TABLE IF EXISTS quota_suspension_period CASCADE;
+DROP TABLE IF EXISTS quota_unblocking_event CASCADE;
+DROP TABLE IF EXISTS quota_unsuspension_event CASCADE;
+DROP TABLE IF EXISTS tax_code CASCADE;
+DROP TABLE IF EXISTS tax_code_description CASCADE;
\ No newline at end of file